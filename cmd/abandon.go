@@ -79,7 +79,9 @@ func (v abandonCommand) Execute(args []string) error {
 			SingleOK: true,
 		},
 	}
-	cmd.O = v.O
+	cmd.O.All = v.O.All
+	cmd.O.Branch = v.O.Branch
+	cmd.O.Force = v.O.Force
 
 	return cmd.Execute(args)
 }
@@ -16,6 +16,7 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
 	"regexp"
@@ -24,6 +25,8 @@ import (
 	"github.com/alibaba/git-repo-go/config"
 	"github.com/alibaba/git-repo-go/workspace"
 	log "github.com/jiangxin/multi-log"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/pflag"
 )
 
 // WorkSpaceCommand implements load of workspace
@@ -168,3 +171,110 @@ func answerIsTrue(answer string) bool {
 	}
 	return false
 }
+
+// stderrIsTerminal indicates whether progress can be shown on stderr.
+func stderrIsTerminal() bool {
+	return isatty.IsTerminal(os.Stderr.Fd()) ||
+		isatty.IsCygwinTerminal(os.Stderr.Fd())
+}
+
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// checkFormat validates value of --format option.
+func checkFormat(format string) error {
+	switch format {
+	case formatText, formatJSON:
+		return nil
+	}
+	return newUserErrorF("unknown format '%s', should be one of: %s, %s",
+		format,
+		formatText,
+		formatJSON)
+}
+
+// printJSON writes data to stdout in JSON format.
+func printJSON(data interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
+
+// Flags annotated as sensitive, such as passwords, are redacted in
+// command line saved in journal of operations.
+const (
+	flagSensitive = "sensitive"
+	redactedValue = "***"
+)
+
+// markSensitive annotates flag as sensitive.
+func markSensitive(flags *pflag.FlagSet, name string) {
+	flags.SetAnnotation(name, flagSensitive, []string{"true"})
+}
+
+func isSensitive(flag *pflag.Flag) bool {
+	return flag != nil && len(flag.Annotations[flagSensitive]) > 0
+}
+
+// redactArgs replaces values of sensitive flags in args, which may be
+// given as "--name value", "--name=value", "-n value" or "-nvalue".
+func redactArgs(flags *pflag.FlagSet, args []string) []string {
+	var (
+		result     = []string{}
+		redactNext bool
+	)
+
+	for i, arg := range args {
+		if redactNext {
+			result = append(result, redactedValue)
+			redactNext = false
+			continue
+		}
+		if arg == "--" {
+			result = append(result, args[i:]...)
+			break
+		}
+		if strings.HasPrefix(arg, "--") {
+			name := strings.SplitN(arg[2:], "=", 2)[0]
+			if isSensitive(flags.Lookup(name)) {
+				if strings.Contains(arg, "=") {
+					arg = "--" + name + "=" + redactedValue
+				} else {
+					redactNext = true
+				}
+			}
+		} else if strings.HasPrefix(arg, "-") {
+			// Grouped shorthands, and the rest is value of the first
+			// shorthand which needs a value.
+			for j := 1; j < len(arg); j++ {
+				flag := flags.ShorthandLookup(arg[j : j+1])
+				if isSensitive(flag) {
+					if j+1 < len(arg) {
+						arg = arg[:j+1] + redactedValue
+					} else {
+						redactNext = true
+					}
+					break
+				}
+				if flag == nil || flag.NoOptDefVal == "" {
+					break
+				}
+			}
+		}
+		result = append(result, arg)
+	}
+	return result
+}
+
+// commandLine returns arguments of current command, which is saved in
+// journal of operations. Values of sensitive flags are redacted.
+func commandLine() string {
+	args := os.Args[1:]
+	flags := rootCmd.Command().PersistentFlags()
+	if c, _, err := rootCmd.Command().Find(args); err == nil {
+		flags = c.Flags()
+	}
+	return strings.Join(redactArgs(flags, args), " ")
+}
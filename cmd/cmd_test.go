@@ -15,3 +15,31 @@ func TestMin(t *testing.T) {
 	m = min(200)
 	assert.Equal(200, int(m))
 }
+
+func TestRedactArgs(t *testing.T) {
+	assert := assert.New(t)
+
+	flags := syncCmd.Command().Flags()
+	for _, c := range []struct {
+		args, expect []string
+	}{
+		{
+			[]string{"sync", "-s", "-u", "user", "-p", "secret"},
+			[]string{"sync", "-s", "-u", "user", "-p", "***"},
+		},
+		{
+			[]string{"sync", "-s", "-psecret", "--manifest-server-password=secret"},
+			[]string{"sync", "-s", "-p***", "--manifest-server-password=***"},
+		},
+		{
+			[]string{"sync", "-s", "--manifest-server-password", "secret", "-sp", "secret"},
+			[]string{"sync", "-s", "--manifest-server-password", "***", "-sp", "***"},
+		},
+		{
+			[]string{"sync", "-u", "user", "--", "-p", "main"},
+			[]string{"sync", "-u", "user", "--", "-p", "main"},
+		},
+	} {
+		assert.Equal(c.expect, redactArgs(flags, c.args))
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/alibaba/git-repo-go/cap"
+	"github.com/alibaba/git-repo-go/path"
+	"github.com/alibaba/git-repo-go/project"
+	log "github.com/jiangxin/multi-log"
+	"github.com/spf13/cobra"
+)
+
+type diffCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		Jobs int
+	}
+}
+
+func (v *diffCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "diff",
+		Short: "Show changes between commit and working tree of projects",
+		Long: `The diff command shows changes between the HEAD commit and the
+working tree of each project. Paths in the diff are prefixed with the
+project path, so the output can be applied from the top of the workspace.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().IntVarP(&v.O.Jobs,
+		"jobs",
+		"j",
+		4,
+		"number of projects to diff simultaneously")
+
+	return v.cmd
+}
+
+func (v diffCommand) Execute(args []string) error {
+	ws := v.RepoWorkSpace()
+
+	if v.O.Jobs < 1 {
+		v.O.Jobs = 1
+	}
+
+	projects, err := ws.GetProjects(nil, args...)
+	if err != nil {
+		return err
+	}
+
+	if len(projects) == 0 {
+		log.Infof("no projects")
+		return nil
+	}
+
+	return v.RunDiff(projects)
+}
+
+// RunDiff runs git diff in projects in parallel, and shows results in
+// the order of projects.
+func (v diffCommand) RunDiff(projects []*project.Project) error {
+	var (
+		jobs       = v.O.Jobs
+		jobTasks   = make(chan int, jobs)
+		jobResults = make(chan int, jobs)
+		results    = make([]*project.CmdExecResult, len(projects))
+		done       = make([]bool, len(projects))
+		failed     = 0
+	)
+
+	worker := func(i int) {
+		log.Debugf("start diff worker #%d", i)
+		for idx := range jobTasks {
+			results[idx] = v.diffProject(projects[idx])
+			jobResults <- idx
+		}
+	}
+
+	for i := 0; i < jobs; i++ {
+		go worker(i)
+	}
+
+	go func() {
+		for i := 0; i < len(projects); i++ {
+			jobTasks <- i
+		}
+		close(jobTasks)
+	}()
+
+	next := 0
+	for i := 0; i < len(projects); i++ {
+		done[<-jobResults] = true
+		for ; next < len(projects) && done[next]; next++ {
+			result := results[next]
+			if result == nil {
+				continue
+			}
+			if result.Error != nil {
+				failed++
+				log.Errorf("fail to diff project '%s': %s",
+					projects[next].Path,
+					result.Stderr())
+				continue
+			}
+			fmt.Print(result.Stdout())
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("fail to diff %d projects", failed)
+	}
+	return nil
+}
+
+func (v diffCommand) diffProject(p *project.Project) *project.CmdExecResult {
+	if !path.Exist(p.WorkDir) {
+		log.Infof("skipping %s/", p.Path)
+		return nil
+	}
+
+	cmdArgs := []string{
+		project.GIT,
+		"diff",
+	}
+	if cap.Isatty() {
+		cmdArgs = append(cmdArgs, "--color")
+	}
+	cmdArgs = append(cmdArgs,
+		"--src-prefix=a/"+p.Path+"/",
+		"--dst-prefix=b/"+p.Path+"/",
+		"HEAD",
+		"--")
+	return p.ExecuteCommand(cmdArgs...)
+}
+
+var diffCmd = diffCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd.Command())
+}
@@ -0,0 +1,247 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/alibaba/git-repo-go/path"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/alibaba/git-repo-go/workspace"
+	"github.com/spf13/cobra"
+)
+
+type diffManifestsCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		Raw bool
+	}
+}
+
+func (v *diffManifestsCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "diffmanifests <manifest1> [<manifest2>]",
+		Short: "Show differences between project revisions of manifests",
+		Long: `Show differences between project revisions of manifests.
+
+When only one manifest is given, compare it with the current manifest of
+the workspace. Manifest files are resolved from the current directory,
+or from the '.repo/manifests' directory.
+
+For projects changed between manifests, list commits between the old and
+new revisions, which must be available in the workspace.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().BoolVar(&v.O.Raw,
+		"raw",
+		false,
+		"display raw diff in a machine-readable format")
+
+	return v.cmd
+}
+
+func (v diffManifestsCommand) loadManifest(name string) (*manifest.Manifest, error) {
+	ws := v.RepoWorkSpace()
+	if path.Exist(name) {
+		name, _ = filepath.Abs(name)
+	}
+	// Local manifests are merged already in manifest saved by
+	// `git repo manifest`.
+	m, err := manifest.LoadFileWithoutLocal(filepath.Join(ws.RootDir, config.DotRepo), name)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, newUserErrorF("cannot find manifest '%s'", name)
+	}
+	return m, nil
+}
+
+func indexManifestProjects(m *manifest.Manifest) map[string]manifest.Project {
+	result := make(map[string]manifest.Project)
+	for _, p := range m.AllProjects() {
+		result[p.Path] = p
+	}
+	return result
+}
+
+func (v diffManifestsCommand) Execute(args []string) error {
+	var (
+		oldManifest, newManifest *manifest.Manifest
+		err                      error
+	)
+
+	ws := v.RepoWorkSpace()
+
+	if len(args) < 1 || len(args) > 2 {
+		return newUserError("diffmanifests needs one or two manifest files")
+	}
+
+	oldManifest, err = v.loadManifest(args[0])
+	if err != nil {
+		return err
+	}
+	if len(args) == 2 {
+		newManifest, err = v.loadManifest(args[1])
+		if err != nil {
+			return err
+		}
+	} else {
+		newManifest = ws.Manifest
+	}
+
+	// Projects in workspace are used to show commit logs.
+	allProjects, err := ws.GetProjects(&workspace.GetProjectsOptions{
+		Groups:    "all",
+		MissingOK: true,
+	})
+	if err != nil {
+		return err
+	}
+	repos := project.IndexByPath(allProjects)
+
+	oldProjects := indexManifestProjects(oldManifest)
+	newProjects := indexManifestProjects(newManifest)
+
+	added := []string{}
+	removed := []string{}
+	changed := []string{}
+	for p := range newProjects {
+		if _, ok := oldProjects[p]; !ok {
+			added = append(added, p)
+		}
+	}
+	for p := range oldProjects {
+		if _, ok := newProjects[p]; !ok {
+			removed = append(removed, p)
+		} else if oldProjects[p].Revision != newProjects[p].Revision {
+			changed = append(changed, p)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+
+	if len(added) > 0 && !v.O.Raw {
+		fmt.Println("added projects :")
+	}
+	for _, p := range added {
+		if v.O.Raw {
+			fmt.Printf("A %s %s\n", p, newProjects[p].Revision)
+		} else {
+			fmt.Printf("  %s at revision %s\n", p, newProjects[p].Revision)
+		}
+	}
+
+	if len(removed) > 0 && !v.O.Raw {
+		fmt.Println("removed projects :")
+	}
+	for _, p := range removed {
+		if v.O.Raw {
+			fmt.Printf("R %s %s\n", p, oldProjects[p].Revision)
+		} else {
+			fmt.Printf("  %s at revision %s\n", p, oldProjects[p].Revision)
+		}
+	}
+
+	if len(changed) > 0 && !v.O.Raw {
+		fmt.Println("changed projects :")
+	}
+	for _, p := range changed {
+		oldRev := oldProjects[p].Revision
+		newRev := newProjects[p].Revision
+		if v.O.Raw {
+			fmt.Printf("C %s %s %s\n", p, oldRev, newRev)
+		} else {
+			fmt.Printf("  %s changed from %s to %s\n", p, oldRev, newRev)
+		}
+
+		logs, err := v.changeLogs(repos[p], oldRev, newRev)
+		if err != nil {
+			if !v.O.Raw {
+				fmt.Printf("    (cannot show commits: %s)\n", err)
+			}
+			continue
+		}
+		for _, l := range logs {
+			if v.O.Raw {
+				fmt.Printf("  %s\n", l)
+			} else {
+				fmt.Printf("    %s\n", l)
+			}
+		}
+	}
+
+	return nil
+}
+
+// changeLogs returns commits between old and new revisions of a project,
+// new commits are prefixed with "+", and commits removed with "-".
+func (v diffManifestsCommand) changeLogs(p *project.Project, oldRev, newRev string) ([]string, error) {
+	var logs []string
+
+	if p == nil || !p.Exists() {
+		return nil, fmt.Errorf("project is not found in workspace")
+	}
+
+	oldID, err := p.ResolveRemoteTracking(oldRev)
+	if err != nil {
+		return nil, err
+	}
+	newID, err := p.ResolveRemoteTracking(newRev)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range []struct {
+		prefix string
+		rng    string
+	}{
+		{"+", oldID + ".." + newID},
+		{"-", newID + ".." + oldID},
+	} {
+		commits, err := p.Revlist("--oneline", item.rng)
+		if err != nil {
+			return nil, err
+		}
+		for _, commit := range commits {
+			logs = append(logs, item.prefix+" "+commit)
+		}
+	}
+	return logs, nil
+}
+
+var diffManifestsCmd = diffManifestsCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: true,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffManifestsCmd.Command())
+}
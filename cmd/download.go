@@ -22,6 +22,7 @@ import (
 	"github.com/alibaba/git-repo-go/config"
 	"github.com/alibaba/git-repo-go/helper"
 	"github.com/alibaba/git-repo-go/project"
+	"github.com/alibaba/git-repo-go/workspace"
 	log "github.com/jiangxin/multi-log"
 	"github.com/spf13/cobra"
 )
@@ -36,14 +37,18 @@ type downloadCommand struct {
 		FFOnly     bool
 		NoCache    bool
 		Remote     string
+		ChangeSet  string
 	}
 }
 
-// projectChange wraps download project and review ID
+// projectChange wraps download project and review ID, or review reference
+// of a change set.
 type projectChange struct {
-	Project  *project.Project
-	ReviewID int
-	PatchID  int
+	Project   *project.Project
+	ReviewID  int
+	PatchID   int
+	Remote    string
+	ReviewRef string
 }
 
 var (
@@ -85,6 +90,10 @@ func (v *downloadCommand) Command() *cobra.Command {
 		"remote",
 		"",
 		"use specific remote to download (use with --single)")
+	v.cmd.Flags().StringVar(&v.O.ChangeSet,
+		"change-set",
+		"",
+		"download code reviews of projects in change set `file` saved by `upload --topic`")
 
 	return v.cmd
 }
@@ -125,6 +134,31 @@ func (v *downloadCommand) parseChanges(args ...string) ([]projectChange, error)
 	return changes, nil
 }
 
+// parseChangeSet returns changes of projects in change set file.
+func (v *downloadCommand) parseChangeSet(filename string) ([]projectChange, error) {
+	var changes []projectChange
+
+	ms, err := workspace.LoadChangeSet(filename)
+	if err != nil {
+		return nil, err
+	}
+	for _, mp := range ms.Projects {
+		projects, err := v.ws.GetProjects(nil, mp.Path)
+		if err != nil {
+			return nil, err
+		}
+		if len(projects) == 0 {
+			return nil, fmt.Errorf("cannot find project '%s' of change set in workspace", mp.Path)
+		}
+		changes = append(changes, projectChange{
+			Project:   projects[0],
+			Remote:    mp.RemoteName,
+			ReviewRef: mp.Revision,
+		})
+	}
+	return changes, nil
+}
+
 func (v *downloadCommand) Execute(args []string) error {
 	ws := v.WorkSpace()
 	err := ws.LoadRemotes(v.O.NoCache)
@@ -150,26 +184,44 @@ func (v *downloadCommand) Execute(args []string) error {
 		return fmt.Errorf("--remote can be only used with --single")
 	}
 
-	if len(args) == 0 {
-		return newUserError("no args")
+	var changes []projectChange
+	if v.O.ChangeSet != "" {
+		if config.IsSingleMode() {
+			return fmt.Errorf("--change-set cannot be used with --single")
+		}
+		if len(args) > 0 {
+			return newUserError("cannot combine --change-set with changes")
+		}
+		changes, err = v.parseChangeSet(v.O.ChangeSet)
+	} else {
+		if len(args) == 0 {
+			return newUserError("no args")
+		}
+		changes, err = v.parseChanges(args...)
 	}
-
-	changes, err := v.parseChanges(args...)
 	if err != nil {
 		return err
 	}
 
 	for _, c := range changes {
-		dl, err := c.Project.DownloadPatchSet(v.O.Remote, c.ReviewID, c.PatchID)
-		if err != nil {
-			return err
-		}
+		var (
+			dl       *project.PatchSet
+			changeID string
+		)
 
-		changeID := ""
-		if c.PatchID == 0 {
-			changeID = fmt.Sprintf("%d", c.ReviewID)
+		if c.ReviewRef != "" {
+			dl, err = c.Project.DownloadReviewRef(c.Remote, c.ReviewRef)
+			changeID = c.ReviewRef
 		} else {
-			changeID = fmt.Sprintf("%d/%d", c.ReviewID, c.PatchID)
+			dl, err = c.Project.DownloadPatchSet(v.O.Remote, c.ReviewID, c.PatchID)
+			if c.PatchID == 0 {
+				changeID = fmt.Sprintf("%d", c.ReviewID)
+			} else {
+				changeID = fmt.Sprintf("%d/%d", c.ReviewID, c.PatchID)
+			}
+		}
+		if err != nil {
+			return err
 		}
 
 		if len(dl.Commits) == 0 && !v.O.Revert {
@@ -28,6 +28,15 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// forallRecord is result of command executed in a project.
+type forallRecord struct {
+	*project.Record
+
+	ExitCode int    `json:"exit_code"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
 type forallCommand struct {
 	WorkSpaceCommand
 
@@ -40,6 +49,7 @@ type forallCommand struct {
 		Command       string
 		Groups        string
 		Jobs          int
+		Format        string
 	}
 }
 
@@ -91,6 +101,10 @@ func (v *forallCommand) Command() *cobra.Command {
 		"j",
 		1,
 		"number of commands to execute simultaneously")
+	v.cmd.Flags().StringVar(&v.O.Format,
+		"format",
+		formatText,
+		"output format: text or json")
 
 	return v.cmd
 }
@@ -119,6 +133,9 @@ func (v forallCommand) Execute(args []string) error {
 	if v.O.Jobs < 1 {
 		v.O.Jobs = 1
 	}
+	if err = checkFormat(v.O.Format); err != nil {
+		return err
+	}
 
 	inverseMode = len(v.O.InverseRegex) > 0
 	if inverseMode && len(v.O.Regex) > 0 {
@@ -217,6 +234,9 @@ func (v forallCommand) RunCommand(projects []*project.Project, cmds []string) er
 	}()
 
 	count := len(projects)
+	if v.O.Format == formatJSON {
+		return v.showJSON(projects, jobResults)
+	}
 	for i := 0; i < count; i++ {
 		result := <-jobResults
 		if result == nil {
@@ -227,6 +247,37 @@ func (v forallCommand) RunCommand(projects []*project.Project, cmds []string) er
 	return nil
 }
 
+// showJSON shows results of all projects in JSON, in the order of projects.
+func (v forallCommand) showJSON(projects []*project.Project, jobResults chan *project.CmdExecResult) error {
+	resultMap := make(map[string]*project.CmdExecResult)
+	for i := 0; i < len(projects); i++ {
+		result := <-jobResults
+		if result == nil {
+			continue
+		}
+		resultMap[result.Project.Path] = result
+	}
+
+	records := []forallRecord{}
+	for _, p := range projects {
+		result, ok := resultMap[p.Path]
+		if !ok {
+			continue
+		}
+		record := forallRecord{
+			Record:   p.Record(),
+			ExitCode: result.ExitCode(),
+			Stdout:   result.Stdout(),
+			Stderr:   string(result.ErrOut),
+		}
+		if result.ExitCode() < 0 {
+			record.Stderr = result.Error.Error()
+		}
+		records = append(records, record)
+	}
+	return printJSON(records)
+}
+
 func (v forallCommand) showResult(result *project.CmdExecResult, i, count int) {
 	stdout := result.Stdout()
 	stderr := result.Stderr()
@@ -280,11 +331,7 @@ func (v forallCommand) executeCommand(p *project.Project, cmds []string) *projec
 		return nil
 	}
 
-	os.Setenv("REPO_PROJECT", p.Name)
-	os.Setenv("REPO_PATH", p.Path)
-	os.Setenv("REPO_REMOTE", p.RemoteName)
-
-	return p.ExecuteCommand(cmds...)
+	return p.ExecuteCommandWithEnv(p.Environ(), cmds...)
 }
 
 var forallCmd = forallCommand{
@@ -0,0 +1,187 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/color"
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/path"
+	"github.com/alibaba/git-repo-go/project"
+	log "github.com/jiangxin/multi-log"
+	"github.com/spf13/cobra"
+)
+
+type infoCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		Overview bool
+	}
+}
+
+func (v *infoCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "info",
+		Short: "Get info on the manifest branch, current branch or unmerged branches",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().BoolVarP(&v.O.Overview,
+		"overview",
+		"o",
+		false,
+		"show overview of all local commits, only projects with local work")
+
+	return v.cmd
+}
+
+func (v infoCommand) Execute(args []string) error {
+	ws := v.RepoWorkSpace()
+
+	projects, err := ws.GetProjects(nil, args...)
+	if err != nil {
+		return err
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].Path < projects[j].Path
+	})
+
+	if v.O.Overview {
+		v.showOverview(projects)
+		return nil
+	}
+
+	v.showManifest()
+	for _, p := range projects {
+		v.showProject(p)
+	}
+	return nil
+}
+
+func (v infoCommand) showManifest() {
+	mp := v.RepoWorkSpace().ManifestProject
+	s := mp.ReadSettings()
+
+	branch := mp.TrackBranch("")
+	if branch == "" {
+		branch = strings.TrimPrefix(s.Revision, config.RefsHeads)
+	}
+	manifestName := s.ManifestName
+	if manifestName == "" {
+		manifestName = config.DefaultXML
+	}
+
+	v.showItem("Manifest URL", s.ManifestURL)
+	v.showItem("Manifest branch", branch)
+	v.showItem("Manifest file", manifestName)
+	v.showItem("Groups", s.Groups)
+	v.showItem("Mirror", fmt.Sprintf("%v", s.Mirror))
+	v.showItem("Archive", fmt.Sprintf("%v", s.Archive))
+	v.showItem("Depth", fmt.Sprintf("%d", s.Depth))
+	v.showItem("Reference", s.Reference)
+	v.showItem("Submodules", fmt.Sprintf("%v", s.Submodules))
+	if m := v.RepoWorkSpace().Manifest; m != nil && m.ContactInfo != nil {
+		v.showItem("Bug URL", m.ContactInfo.BugURL)
+	}
+	color.Dimln(strings.Repeat("-", 78))
+}
+
+func (v infoCommand) showProject(p *project.Project) {
+	v.showItem("Project", p.Name)
+	v.showItem("Mount path", p.Path)
+	v.showItem("Remote", p.RemoteName)
+	v.showItem("Revision", p.Revision)
+
+	if !path.Exist(p.WorkDir) {
+		log.Warnf("project '%s' is not checked out yet", p.Path)
+		color.Dimln(strings.Repeat("-", 78))
+		return
+	}
+
+	head := p.HeadBranch()
+	if head.Name == "" {
+		v.showItem("Current branch", "(detached)")
+	} else {
+		v.showItem("Current branch", head.ShortName())
+	}
+	v.showItem("Upstream", p.LocalTrackBranch(""))
+	if head.Name != "" {
+		commits := p.UnpublishedCommits(head.Name)
+		v.showItem("Local commits", fmt.Sprintf("%d", len(commits)))
+		for _, commit := range commits {
+			fmt.Printf("  %s\n", commit)
+		}
+	}
+	color.Dimln(strings.Repeat("-", 78))
+}
+
+func (v infoCommand) showOverview(projects []*project.Project) {
+	found := false
+	for _, p := range projects {
+		if !path.Exist(p.WorkDir) {
+			continue
+		}
+
+		heads := p.Heads()
+		sort.Slice(heads, func(i, j int) bool {
+			return heads[i].Name < heads[j].Name
+		})
+		for _, head := range heads {
+			commits := p.UnpublishedCommits(head.Name)
+			if len(commits) == 0 {
+				continue
+			}
+			if !found {
+				color.Hilightln("Projects Overview")
+				color.Dimln(strings.Repeat("-", 78))
+				found = true
+			}
+			fmt.Printf("project %-40s branch %s\n", p.Path+"/", head.ShortName())
+			for _, commit := range commits {
+				fmt.Printf("  -%s\n", commit)
+			}
+		}
+	}
+
+	if !found {
+		log.Note("no local commits in projects")
+	}
+}
+
+func (v infoCommand) showItem(name, value string) {
+	color.Hilight(name)
+	fmt.Printf(": %s\n", value)
+}
+
+var infoCmd = infoCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd.Command())
+}
@@ -123,7 +123,7 @@ func (v *initCommand) Command() *cobra.Command {
 		"groups",
 		"g",
 		"default",
-		"restrict manifest projects to ones with specified group(s) [default|all|G1,G2,G3|G4,-G5,-G6]")
+		"restrict manifest projects to ones with specified group(s) [default|all|G1,G2,G3|G4,-G5,-G6|(G7 & !G8) | G9]")
 	v.cmd.Flags().StringVarP(&v.O.Platform,
 		"platform",
 		"p",
@@ -148,8 +148,7 @@ func (v *initCommand) Command() *cobra.Command {
 func (v initCommand) getGroups() string {
 	allPlatforms := []string{"linux", "darwin", "windows"}
 	groups := []string{}
-	for _, g := range strings.Split(v.O.Groups, ",") {
-		g = strings.TrimSpace(g)
+	for _, g := range project.SplitGroups(v.O.Groups) {
 		if g != "" {
 			groups = append(groups, g)
 		}
@@ -268,6 +267,9 @@ func (v initCommand) Execute(args []string) error {
 
 	// v.O.Groups has default value, and use it if setting is empty
 	groupStr := v.getGroups()
+	if _, err := project.ParseGroups(groupStr); err != nil {
+		return err
+	}
 	if ((v.cmd.Flags().Changed("groups") || v.cmd.Flags().Changed("platform")) &&
 		s.Groups != groupStr) ||
 		s.Groups == "" {
@@ -334,6 +336,7 @@ Either delete the .repo folder in this workspace, or initialize in another locat
 		RepoSettings: *s,
 
 		CloneBundle:       !v.O.NoCloneBundle,
+		ShowProgress:      stderrIsTerminal(),
 		CurrentBranchOnly: v.O.CurrentBranchOnly,
 		ForceSync:         false,
 		IsNew:             isNew,
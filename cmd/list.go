@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"regexp"
 	"sort"
+	"strings"
 
 	"github.com/alibaba/git-repo-go/project"
 	"github.com/alibaba/git-repo-go/workspace"
@@ -32,9 +33,13 @@ type listCommand struct {
 	O   struct {
 		Regex    []string
 		Groups   string
+		GroupsOf string
 		FullPath bool
 		NameOnly bool
 		PathOnly bool
+
+		Annotations []string
+		Format      string
 	}
 }
 
@@ -63,6 +68,10 @@ func (v *listCommand) Command() *cobra.Command {
 		"g",
 		"",
 		"Filter the project list based on the groups the project is in")
+	v.cmd.Flags().StringVar(&v.O.GroupsOf,
+		"groups-of",
+		"",
+		"Explain why the project matches or does not match the groups")
 	v.cmd.Flags().BoolVarP(&v.O.FullPath,
 		"fullpath",
 		"f",
@@ -78,6 +87,14 @@ func (v *listCommand) Command() *cobra.Command {
 		"p",
 		false,
 		"Display only the path of the repository")
+	v.cmd.Flags().StringArrayVar(&v.O.Annotations,
+		"annotation",
+		nil,
+		"Filter the project list based on annotation (name=value, or name)")
+	v.cmd.Flags().StringVar(&v.O.Format,
+		"format",
+		formatText,
+		"output format: text or json")
 
 	return v.cmd
 }
@@ -100,6 +117,14 @@ func (v listCommand) Execute(args []string) error {
 		log.Fatal("cannot combine -f and -n")
 	}
 
+	if err = checkFormat(v.O.Format); err != nil {
+		return err
+	}
+
+	if v.O.GroupsOf != "" {
+		return v.explainGroups(v.O.GroupsOf)
+	}
+
 	allProjects, err = ws.GetProjects(&workspace.GetProjectsOptions{
 		Groups: v.O.Groups,
 	})
@@ -130,6 +155,27 @@ func (v listCommand) Execute(args []string) error {
 		}
 	}
 
+	if len(v.O.Annotations) > 0 {
+		matched := []*project.Project{}
+		for _, p := range projects {
+			if v.matchAnnotations(p) {
+				matched = append(matched, p)
+			}
+		}
+		projects = matched
+	}
+
+	if v.O.Format == formatJSON {
+		sort.Slice(projects, func(i, j int) bool {
+			return projects[i].Path < projects[j].Path
+		})
+		records := make([]*project.Record, len(projects))
+		for i, p := range projects {
+			records[i] = p.Record()
+		}
+		return printJSON(records)
+	}
+
 	if len(projects) == 0 {
 		log.Notef("no projects")
 		return nil
@@ -142,7 +188,10 @@ func (v listCommand) Execute(args []string) error {
 		} else if v.O.PathOnly {
 			outputs[i] = fmt.Sprintf("%s\n", v.getPath(project))
 		} else {
-			outputs[i] = fmt.Sprintf("%s : %s\n", v.getPath(project), project.Name)
+			outputs[i] = fmt.Sprintf("%s : %s%s\n",
+				v.getPath(project),
+				project.Name,
+				v.fmtAnnotations(project))
 		}
 	}
 
@@ -154,6 +203,62 @@ func (v listCommand) Execute(args []string) error {
 	return nil
 }
 
+// explainGroups shows how groups of project are matched.
+func (v listCommand) explainGroups(name string) error {
+	ws := v.RepoWorkSpace()
+	groups := ws.EffectiveGroups(v.O.Groups)
+	matcher, err := project.ParseGroups(groups)
+	if err != nil {
+		return err
+	}
+
+	projects, err := ws.GetProjects(&workspace.GetProjectsOptions{
+		Groups:    project.GroupAll,
+		MissingOK: true,
+	}, name)
+	if err != nil {
+		return err
+	}
+
+	for i, p := range projects {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("project %s (%s)\n", p.Path, p.Name)
+		fmt.Printf("match groups: %s\n", groups)
+		for _, line := range matcher.Explain(p.Groups) {
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
+
+// matchAnnotations checks if project matches all annotation filters.
+func (v listCommand) matchAnnotations(p *project.Project) bool {
+	for _, filter := range v.O.Annotations {
+		items := strings.SplitN(filter, "=", 2)
+		value, ok := p.GetAnnotation(items[0])
+		if !ok {
+			return false
+		}
+		if len(items) == 2 && value != items[1] {
+			return false
+		}
+	}
+	return true
+}
+
+func (v listCommand) fmtAnnotations(p *project.Project) string {
+	if len(p.Annotations) == 0 {
+		return ""
+	}
+	items := []string{}
+	for _, a := range p.Annotations {
+		items = append(items, a.Name+"="+a.Value)
+	}
+	return " [" + strings.Join(items, ", ") + "]"
+}
+
 func (v listCommand) getPath(project *project.Project) string {
 	if v.O.FullPath {
 		return project.WorkDir
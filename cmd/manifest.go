@@ -15,11 +15,16 @@
 package cmd
 
 import (
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/alibaba/git-repo-go/config"
 	"github.com/alibaba/git-repo-go/file"
 	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/alibaba/git-repo-go/path"
 	log "github.com/jiangxin/multi-log"
 	"github.com/spf13/cobra"
 )
@@ -32,6 +37,7 @@ type manifestCommand struct {
 		PegRev           bool
 		PegRevNoUpstream bool
 		OutputFile       string
+		Lint             bool
 	}
 }
 
@@ -43,6 +49,13 @@ func (v *manifestCommand) Command() *cobra.Command {
 	v.cmd = &cobra.Command{
 		Use:   "manifest",
 		Short: "Manifest inspection utility",
+		Long: `Manifest inspection utility.
+
+With --lint, check manifest files and report all problems found with
+file names and line numbers.  If no manifest file is given, check the
+manifest and local manifests of the workspace.  Manifests of submanifests
+checked out in the workspace are checked too.  Manifest files can be
+checked outside a workspace, such as in a CI job of manifest repository.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return v.Execute(args)
 		},
@@ -64,21 +77,28 @@ func (v *manifestCommand) Command() *cobra.Command {
 		"o",
 		"-",
 		"File to save the manifest to")
+	v.cmd.Flags().BoolVar(&v.O.Lint,
+		"lint",
+		false,
+		"check manifest files and report problems, which are also shown as warnings by sync")
 
 	return v.cmd
 }
 
 func (v manifestCommand) WriteManifest(writer io.Writer) error {
 	ws := v.RepoWorkSpace()
+	m := ws.Manifest
 
 	if v.O.PegRev {
 		err := ws.FreezeManifest(!v.O.PegRevNoUpstream)
 		if err != nil {
 			return err
 		}
+		// Save frozen projects of submanifests instead of submanifests.
+		m = m.Flatten()
 	}
 
-	data, err := manifest.Marshal(ws.Manifest)
+	data, err := manifest.Marshal(m)
 	if err != nil {
 		return err
 	}
@@ -96,11 +116,64 @@ func (v manifestCommand) WriteManifest(writer io.Writer) error {
 	return nil
 }
 
+// Lint checks manifest files given in args, or manifest of workspace,
+// and shows problems found.
+func (v manifestCommand) Lint(args []string) error {
+	var (
+		problems = []manifest.Problem{}
+		cwd, _   = os.Getwd()
+		repoDir  string
+	)
+
+	// Do not load workspace, which fails on broken manifest.
+	topDir, err := path.FindTopDir("")
+	if err == nil {
+		repoDir = filepath.Join(topDir, config.DotRepo)
+	} else if len(args) == 0 {
+		return err
+	}
+	if len(args) == 0 {
+		ps, err := manifest.Lint(repoDir, "")
+		if err != nil {
+			return err
+		}
+		problems = append(problems, ps...)
+	}
+	// Manifest files given in a workspace are checked with submanifests
+	// checked out in the workspace.
+	for _, arg := range args {
+		file, err := filepath.Abs(arg)
+		if err != nil {
+			return err
+		}
+		ps, err := manifest.Lint(repoDir, file)
+		if err != nil {
+			return newUserErrorF("cannot lint manifest '%s': %s", arg, err)
+		}
+		problems = append(problems, ps...)
+	}
+
+	for _, p := range problems {
+		if rel, err := filepath.Rel(cwd, p.File); err == nil && !strings.HasPrefix(rel, "..") {
+			p.File = rel
+		}
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d problems in manifest", len(problems))
+	}
+	return nil
+}
+
 func (v manifestCommand) Execute(args []string) error {
 	var (
 		writer io.ReadWriteCloser
 	)
 
+	if v.O.Lint {
+		return v.Lint(args)
+	}
+
 	if v.O.OutputFile == "" {
 		log.Fatal("no output file, no operation to perform")
 	} else if v.O.OutputFile == "-" {
@@ -22,6 +22,7 @@ import (
 	"github.com/alibaba/git-repo-go/color"
 	"github.com/alibaba/git-repo-go/config"
 	"github.com/alibaba/git-repo-go/project"
+	"github.com/alibaba/git-repo-go/workspace"
 	log "github.com/jiangxin/multi-log"
 	"github.com/spf13/cobra"
 )
@@ -34,6 +35,7 @@ type pruneCommand struct {
 		All    bool
 		Branch string
 		Force  bool
+		DryRun bool
 	}
 }
 
@@ -45,10 +47,21 @@ func (v *pruneCommand) Command() *cobra.Command {
 	v.cmd = &cobra.Command{
 		Use:   "prune [<project>...]",
 		Short: "Prune (delete) already merged topic branches",
+		Long: `Prune (delete) already merged topic branches.
+
+A branch is merged if all its commits are in its upstream, or each commit
+has an equivalent commit in upstream with the same patch-id or the same
+Change-Id, or all its changes are squashed into one commit in upstream.
+Branches without commits of their own are also pruned. Pruned branches
+are saved in ` + config.RefsBackup + ` namespace.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return v.Execute(args)
 		},
 	}
+	v.cmd.Flags().BoolVar(&v.O.DryRun,
+		"dry-run",
+		false,
+		"show branches to prune and the rules which find them merged, without deleting")
 
 	return v.cmd
 }
@@ -58,6 +71,7 @@ type projectBranch struct {
 
 	Project   *project.Project
 	IsCurrent bool
+	MergedBy  string
 }
 
 type pbByBranch []projectBranch
@@ -87,6 +101,7 @@ func (v pruneCommand) Execute(args []string) error {
 		err     error
 		success = []projectBranch{}
 		failure = []projectBranch{}
+		dryRun  = v.O.DryRun || config.IsDryRun()
 	)
 
 	ws := v.WorkSpace()
@@ -100,30 +115,31 @@ func (v pruneCommand) Execute(args []string) error {
 		return err
 	}
 
+	// Save deleted branches, which can be restored by `git repo undo`.
+	var journal *workspace.Journal
+	if rws, ok := ws.(*workspace.RepoWorkSpace); ok && !dryRun {
+		journal = rws.BeginOperation(commandLine(), projects)
+	}
+
 	if v.O.Branch != "" && !strings.HasPrefix(v.O.Branch, config.RefsHeads) {
 		v.O.Branch = config.RefsHeads + v.O.Branch
 	}
 
 	maxProjectWidth := 0
-	forceDropCmdArgs := []string{project.GIT, "branch", "-D"}
-	normalDropCmdArgs := []string{project.GIT, "branch", "-d"}
-	forceDropCmdArgsInitSize := len(forceDropCmdArgs)
-	normalDropCmdArgsInitSize := len(normalDropCmdArgs)
-
 	for _, p := range projects {
 		w := len(p.Path)
 		if w > maxProjectWidth {
 			maxProjectWidth = w
 		}
 		allHeads := p.Heads()
-		branches := []project.Branch{}
+		candidates := []project.Branch{}
 		cb := p.HeadBranch()
 		if v.O.Branch != "" && v.O.Branch != cb.Name {
 			oid, err := p.ResolveRevision(v.O.Branch)
 			if err != nil {
 				log.Errorf("project %s> fail to resolve %s", p.Path, v.O.Branch)
 			} else {
-				branches = append(branches,
+				candidates = append(candidates,
 					project.Branch{
 						Name: v.O.Branch,
 						Hash: oid,
@@ -132,133 +148,56 @@ func (v pruneCommand) Execute(args []string) error {
 		} else if v.O.All {
 			for _, b := range allHeads {
 				if b.Name != cb.Name {
-					branches = append(branches, b)
+					candidates = append(candidates, b)
 				}
 			}
 		}
-
-		// Check current branch for deletion
+		// Current branch is only deleted if worktree is clean.
 		if cb.Name != "" && (v.O.All || cb.Name == v.O.Branch) {
-			shouldPruneCurrentBranch := false
-			if v.O.Force {
-				shouldPruneCurrentBranch = true
-			} else {
-				// Check if there are no commits ahead for current branch
-				tb := p.LocalTrackBranch(cb.Name)
-				if tb == "" {
-					tb, err = p.ResolveRemoteTracking(cb.Name)
-				} else {
-					tb, err = p.ResolveRevision(tb)
-				}
-				if err != nil {
-					log.Debugf("cannot find tracking branch %s of %s: %s",
-						cb.Name,
-						p.Path,
-						err)
-					if p.IsClean() {
-						shouldPruneCurrentBranch = true
-					}
-				} else {
-					list, err := p.Revlist(cb.Name, "--not", tb)
-					if err != nil {
-						log.Errorf("project %s> revlist failed for HEAD: %s", p.Path, err)
-					} else if len(list) == 0 && p.IsClean() {
-						shouldPruneCurrentBranch = true
-					}
-				}
-			}
-			if shouldPruneCurrentBranch {
-				err := p.DetachHead()
-				if err != nil {
-					shouldPruneCurrentBranch = false
-					log.Errorf("project %s> cannot detach current branch for abandon: %s", p.Path, err)
-				} else {
-					branches = append(branches, cb)
-				}
-			}
-			if !shouldPruneCurrentBranch {
-				failure = append(failure, projectBranch{
-					Branch:    cb,
-					Project:   p,
-					IsCurrent: true,
-				})
-			}
+			candidates = append(candidates, cb)
 		}
-
-		needToClean := false
-		if len(branches) == 0 {
+		if len(candidates) == 0 {
 			log.Debugf("no branch to prune for project %s", p.Name)
-		} else {
-			// run branch -d ...
-			forceDropCmdArgs = forceDropCmdArgs[:forceDropCmdArgsInitSize]
-			normalDropCmdArgs = normalDropCmdArgs[:normalDropCmdArgsInitSize]
-			allDropSuccess := true
+			continue
+		}
 
-			for _, b := range branches {
-				if v.O.Force || p.TrackBranch(b.Name) == "" {
-					forceDropCmdArgs = append(forceDropCmdArgs, b.ShortName())
-				} else {
-					normalDropCmdArgs = append(normalDropCmdArgs, b.ShortName())
-				}
-			}
-			if len(forceDropCmdArgs) > forceDropCmdArgsInitSize {
-				result := p.ExecuteCommand(forceDropCmdArgs...)
-				if result.Error != nil {
-					allDropSuccess = false
-					log.Debugf("project %s> %s", p.Path, result.Stderr())
-				} else {
-					needToClean = true
-				}
-			}
-			if len(normalDropCmdArgs) > normalDropCmdArgsInitSize {
-				result := p.ExecuteCommand(normalDropCmdArgs...)
-				if result.Error != nil {
-					allDropSuccess = false
-					log.Debugf("project %s> %s", p.Path, result.Stderr())
-				} else {
-					needToClean = true
-				}
-			}
-			if allDropSuccess {
-				for _, b := range branches {
-					success = append(success, projectBranch{
-						Branch:    b,
-						Project:   p,
-						IsCurrent: b.Name == cb.Name,
-					})
-				}
-			} else {
-				left := make(map[string]bool)
-				for _, b := range p.Heads() {
-					left[b.Name] = true
-				}
-				for _, b := range branches {
-					if _, ok := left[b.Name]; ok {
-						failure = append(failure, projectBranch{
-							Branch:    b,
-							Project:   p,
-							IsCurrent: b.Name == cb.Name,
-						})
-					} else {
-						needToClean = true
-						success = append(success, projectBranch{
-							Branch:  b,
-							Project: p,
-						})
-					}
-				}
-			}
+		pruned, pending, err := p.PruneMergedBranches(&project.PruneOptions{
+			Branches:  candidates,
+			Force:     v.O.Force,
+			NoCommits: true,
+			DryRun:    dryRun,
+		})
+		if err != nil {
+			log.Errorf("project %s> %s", p.Path, err)
+		}
+		for _, b := range pruned {
+			success = append(success, projectBranch{
+				Branch:    b.Branch,
+				Project:   p,
+				IsCurrent: b.IsCurrent,
+				MergedBy:  b.MergedBy,
+			})
+		}
+		for _, b := range pending {
+			failure = append(failure, projectBranch{
+				Branch:    b.Branch,
+				Project:   p,
+				IsCurrent: b.IsCurrent,
+			})
 		}
+	}
 
-		if needToClean {
-			log.Debugf("clean published cache for %s", p.Path)
-			p.CleanPublishedCache()
+	if journal != nil {
+		if _, err = journal.Finish(); err != nil {
+			log.Warnf("fail to save journal of operation: %s", err)
 		}
 	}
 
 	// Show deleted branch
 	if len(success) > 0 {
-		if v.O.Force {
+		if dryRun {
+			color.Hilightln("Would prune branches (dry run, rules which find them merged)")
+		} else if v.O.Force {
 			color.Hilightln("Abandoned branches")
 		} else {
 			color.Hilightln("Pruned branches (already merged)")
@@ -283,7 +222,15 @@ func (v pruneCommand) Execute(args []string) error {
 			} else {
 				fmt.Printf("%-*s | ", maxBranchWidth, "")
 			}
-			fmt.Printf("%-*s (was %s)\n", maxProjectWidth, b.Project.Path, b.Hash[0:7])
+			if dryRun {
+				rule := b.MergedBy
+				if rule == "" {
+					rule = "abandon"
+				}
+				fmt.Printf("%-*s (was %s, %s)\n", maxProjectWidth, b.Project.Path, b.Hash[0:7], rule)
+			} else {
+				fmt.Printf("%-*s (was %s)\n", maxProjectWidth, b.Project.Path, b.Hash[0:7])
+			}
 		}
 		fmt.Println("")
 	}
@@ -34,6 +34,7 @@ type statusCommand struct {
 	O   struct {
 		Jobs    int
 		Orphans bool
+		Format  string
 	}
 }
 
@@ -59,6 +60,10 @@ func (v *statusCommand) Command() *cobra.Command {
 		"j",
 		2,
 		"number of projects to check simultaneously")
+	v.cmd.Flags().StringVar(&v.O.Format,
+		"format",
+		formatText,
+		"output format: text or json")
 
 	return v.cmd
 }
@@ -74,12 +79,19 @@ func (v statusCommand) Execute(args []string) error {
 	if v.O.Jobs < 1 {
 		v.O.Jobs = 1
 	}
+	if err = checkFormat(v.O.Format); err != nil {
+		return err
+	}
 
 	projects, err = ws.GetProjects(nil, args...)
 	if err != nil {
 		return err
 	}
 
+	if v.O.Format == formatJSON {
+		return v.RunJSON(projects)
+	}
+
 	if len(projects) == 0 {
 		log.Infof("no projects")
 		return nil
@@ -88,6 +100,56 @@ func (v statusCommand) Execute(args []string) error {
 	return v.RunCommand(projects)
 }
 
+// RunJSON shows status of projects in JSON format.
+func (v statusCommand) RunJSON(projects []*project.Project) error {
+	var (
+		jobs       = v.O.Jobs
+		jobTasks   = make(chan int, jobs)
+		jobResults = make(chan int, jobs)
+		records    = make([]*project.Record, len(projects))
+	)
+
+	worker := func(i int) {
+		log.Debugf("start status worker #%d", i)
+		for idx := range jobTasks {
+			records[idx] = v.statusRecord(projects[idx])
+			jobResults <- idx
+		}
+	}
+
+	for i := 0; i < jobs; i++ {
+		go worker(i)
+	}
+
+	go func() {
+		for i := 0; i < len(projects); i++ {
+			jobTasks <- i
+		}
+		close(jobTasks)
+	}()
+
+	for i := 0; i < len(projects); i++ {
+		<-jobResults
+	}
+
+	return printJSON(records)
+}
+
+func (v statusCommand) statusRecord(p *project.Project) *project.Record {
+	record := p.Record()
+	if !path.Exist(p.WorkDir) {
+		record.Error = `missing (run "git repo sync")`
+		return record
+	}
+
+	files, err := p.StatusFiles()
+	record.Files = files
+	if err != nil {
+		record.Error = strings.TrimSpace(err.Error())
+	}
+	return record
+}
+
 func (v statusCommand) RunCommand(projects []*project.Project) error {
 	var (
 		jobs       = v.O.Jobs
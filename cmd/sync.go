@@ -17,12 +17,19 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/alibaba/git-repo-go/cap"
+	"github.com/alibaba/git-repo-go/common"
 	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/manifest"
 	"github.com/alibaba/git-repo-go/project"
 	"github.com/alibaba/git-repo-go/workspace"
 	log "github.com/jiangxin/multi-log"
@@ -60,9 +67,49 @@ type syncCommand struct {
 		Prune                  bool
 		SmartSync              bool
 		SmartTag               string
+		Unshallow              bool
+		Deepen                 int
+		RetryFetches           int
+		FailFast               bool
+		UseSuperproject        bool
+		PruneBranches          bool
+		Format                 string
 	}
 }
 
+// syncFailure is a project failed to sync.
+type syncFailure struct {
+	Project *project.Project
+	Err     error
+}
+
+// Status of project in syncResult.
+const (
+	syncSuccess = "success"
+	syncFailed  = "failure"
+	syncSkipped = "skipped"
+)
+
+// syncResult is result of syncing a project.
+type syncResult struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func newSyncResult(p *project.Project, status string, err error) syncResult {
+	result := syncResult{
+		Name:   p.Name,
+		Path:   p.Path,
+		Status: status,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
 func (v *syncCommand) Command() *cobra.Command {
 	if v.cmd != nil {
 		return v.cmd
@@ -79,7 +126,7 @@ func (v *syncCommand) Command() *cobra.Command {
 		"force-broken",
 		"f",
 		false,
-		"continue sync even if a project fails to sync")
+		"continue sync even if a project fails to sync (default behavior)")
 	v.cmd.Flags().BoolVar(&v.O.ForceSync,
 		"force-sync",
 		false,
@@ -154,8 +201,9 @@ func (v *syncCommand) Command() *cobra.Command {
 		"prune",
 		false,
 		"delete refs that no longer exist on the remote")
-	v.cmd.Flags().BoolVar(&v.O.SmartSync,
+	v.cmd.Flags().BoolVarP(&v.O.SmartSync,
 		"smart-sync",
+		"s",
 		false,
 		"smart sync using manifest from the latest known good build")
 	v.cmd.Flags().StringVarP(&v.O.SmartTag,
@@ -163,6 +211,38 @@ func (v *syncCommand) Command() *cobra.Command {
 		"t",
 		"",
 		"smart sync using manifest from a known tag")
+	v.cmd.Flags().BoolVar(&v.O.Unshallow,
+		"unshallow",
+		false,
+		"convert shallow projects to complete ones")
+	v.cmd.Flags().IntVar(&v.O.Deepen,
+		"deepen",
+		0,
+		"deepen history of shallow projects by specific number of commits")
+	v.cmd.Flags().IntVar(&v.O.RetryFetches,
+		"retry-fetches",
+		0,
+		"number of times to retry fetch of a project, with exponential backoff")
+	v.cmd.Flags().BoolVar(&v.O.FailFast,
+		"fail-fast",
+		false,
+		"stop syncing as soon as a project fails to fetch")
+	v.cmd.Flags().BoolVar(&v.O.UseSuperproject,
+		"use-superproject",
+		false,
+		"use revisions of projects pinned by the superproject of manifest")
+	v.cmd.Flags().BoolVar(&v.O.PruneBranches,
+		"prune-branches",
+		false,
+		"delete local branches already merged into upstream, backups are saved in "+config.RefsBackup+
+			" (branches without commits of their own are kept)")
+	v.cmd.Flags().StringVar(&v.O.Format,
+		"format",
+		formatText,
+		"output format of sync results: text or json")
+	markSensitive(v.cmd.Flags(), "manifest-server-password")
+	v.cmd.Flags().MarkDeprecated("force-broken",
+		"sync continues after failures by default, use --fail-fast to stop on failure")
 
 	return v.cmd
 }
@@ -204,9 +284,53 @@ func (v *syncCommand) maxSyncJobs() int {
 	return nJobs
 }
 
-func (v syncCommand) CallManifestServerRPC() {
-	// TODO: implement `_SmartSyncSetup`
-	log.Panic("not implement CallManifestServerRPC")
+// CallManifestServerRPC gets manifest of a known good build from the
+// manifest server, and saves it in file.
+func (v syncCommand) CallManifestServerRPC(file string) error {
+	var (
+		err      error
+		manifest string
+	)
+
+	rws := v.RepoWorkSpace()
+	if rws.Manifest == nil ||
+		rws.Manifest.Server == nil ||
+		rws.Manifest.Server.URL == "" {
+		return newUserError("cannot smart sync: no manifest server defined in manifest")
+	}
+
+	server := helper.NewManifestServer(rws.Manifest.Server.URL,
+		v.O.ManifestServerUsername,
+		v.O.ManifestServerPassword)
+
+	if v.O.SmartSync {
+		branch := rws.ManifestProject.TrackBranch("")
+		if branch == "" {
+			branch = strings.TrimPrefix(rws.Settings().Revision, config.RefsHeads)
+		}
+		if branch == "" {
+			return newUserError("cannot smart sync: manifest project is not tracking any branch")
+		}
+
+		target := os.Getenv("SYNC_TARGET")
+		if target == "" &&
+			os.Getenv("TARGET_PRODUCT") != "" &&
+			os.Getenv("TARGET_BUILD_VARIANT") != "" {
+			target = os.Getenv("TARGET_PRODUCT") + "-" + os.Getenv("TARGET_BUILD_VARIANT")
+		}
+		manifest, err = server.GetApprovedManifest(branch, target)
+	} else {
+		manifest, err = server.GetManifest(v.O.SmartTag)
+	}
+	if err != nil {
+		return newSystemError(err)
+	}
+
+	err = ioutil.WriteFile(file, []byte(manifest), 0644)
+	if err != nil {
+		return fmt.Errorf("cannot write manifest to %s: %s", file, err)
+	}
+	return nil
 }
 
 func (v *syncCommand) updateManifestProject() error {
@@ -279,10 +403,76 @@ func (v *syncCommand) updateManifestProject() error {
 	return nil
 }
 
-func (v syncCommand) NetworkHalf(allProjects []*project.Project) error {
+// updateSubmanifests fetches and checks out manifest projects of
+// submanifests, and reloads workspace to load projects of submanifests.
+// Nested submanifests are found after reloading.
+func (v *syncCommand) updateSubmanifests() error {
 	var (
-		err  error
-		errs []error
+		checked = make(map[string]bool)
+		err     error
+	)
+
+	for {
+		ws := v.RepoWorkSpace()
+		if ws.Manifest == nil {
+			return nil
+		}
+
+		updated := false
+		for i := range ws.Manifest.Submanifests {
+			sm := &ws.Manifest.Submanifests[i]
+			if checked[sm.RelPath()] {
+				continue
+			}
+			checked[sm.RelPath()] = true
+
+			p := project.NewSubmanifestProject(sm, ws.Settings())
+			oldrev, _ := p.ResolveRevision("HEAD")
+			if !v.O.LocalOnly {
+				err = p.SyncNetworkHalf(&project.FetchOptions{
+					RepoSettings: *ws.Settings(),
+
+					Quiet:  config.GetQuiet(),
+					NoTags: true,
+				})
+				if err != nil {
+					return fmt.Errorf("fail to fetch submanifest '%s': %s", sm.RelPath(), err)
+				}
+			}
+			if !p.Repository.Exists() {
+				continue
+			}
+			err = p.SyncLocalHalf(&project.CheckoutOptions{
+				RepoSettings: *ws.Settings(),
+
+				Quiet: config.GetQuiet(),
+			})
+			if err != nil {
+				return fmt.Errorf("fail to checkout submanifest '%s': %s", sm.RelPath(), err)
+			}
+			newrev, _ := p.ResolveRevision("HEAD")
+			if oldrev != newrev {
+				updated = true
+			}
+		}
+
+		if !updated {
+			return nil
+		}
+		v.ReloadRepoWorkSpace()
+		if v.O.ManifestName != "" {
+			v.RepoWorkSpace().Override(v.O.ManifestName)
+		}
+	}
+}
+
+// NetworkHalf fetches projects, and returns projects failed to fetch.
+// Fetch stops as soon as a project fails if --fail-fast is given, and
+// returns immediately.
+func (v syncCommand) NetworkHalf(allProjects []*project.Project) []syncFailure {
+	var (
+		err      error
+		failures []syncFailure
 	)
 
 	jobs := v.O.Jobs
@@ -290,67 +480,106 @@ func (v syncCommand) NetworkHalf(allProjects []*project.Project) error {
 		jobs = 1
 	}
 
-	// TODO 1. Record fetch time, save time and project name to JSON
-	// TODO 2. Sort projects by its fetch time (reverse order).
-
+	// Projects which took longer time to fetch last time are fetched first.
+	fetchTimes := v.RepoWorkSpace().FetchTimes()
 	projectsByName := project.IndexByName(allProjects)
+	names := []string{}
+	for name := range projectsByName {
+		names = append(names, name)
+	}
+	fetchTimes.Sort(names)
+
+	type fetchResult struct {
+		Name     string
+		Duration time.Duration
+		Failures []syncFailure
+	}
+
 	jobTasks := make(chan string, jobs)
-	jobResults := make(chan error, jobs)
+	jobResults := make(chan fetchResult, jobs)
+	abort := make(chan struct{})
+	aborted := false
+	wg := sync.WaitGroup{}
 
 	worker := func(i int) {
 		var (
-			err      error
-			name     string
-			projects []*project.Project
-			p        *project.Project
+			err   error
+			name  string
+			p     *project.Project
+			start time.Time
 		)
 
+		defer wg.Done()
 		log.Debugf("start NetworkHalf worker #%d", i)
 		for name = range jobTasks {
-			projects = projectsByName[name]
-			for _, p = range projects {
+			select {
+			case <-abort:
+				continue
+			default:
+			}
+			result := fetchResult{Name: name}
+			start = time.Now()
+			for _, p = range projectsByName[name] {
 				log.Debugf("worker #%d: sync %s", i, p.Name)
 				err = p.SyncNetworkHalf(&v.FetchOptions)
-				jobResults <- err
+				if err != nil {
+					result.Failures = append(result.Failures,
+						syncFailure{Project: p, Err: err})
+				}
 			}
+			result.Duration = time.Since(start)
+			jobResults <- result
 		}
 	}
 
+	wg.Add(jobs)
 	for i := 0; i < jobs; i++ {
 		go worker(i)
 	}
 
 	go func() {
-		for name := range projectsByName {
-			jobTasks <- name
+		defer close(jobTasks)
+		for _, name := range names {
+			select {
+			case jobTasks <- name:
+			case <-abort:
+				return
+			}
 		}
+	}()
 
-		close(jobTasks)
+	go func() {
+		wg.Wait()
+		close(jobResults)
 	}()
 
-	for i := 0; i < len(projectsByName); i++ {
-		err = <-jobResults
-		if err != nil {
-			errs = append(errs, err)
+	for result := range jobResults {
+		if len(result.Failures) == 0 {
+			fetchTimes.Set(result.Name, result.Duration)
+			continue
+		}
+		failures = append(failures, result.Failures...)
+		if v.O.FailFast && !aborted {
+			aborted = true
+			close(abort)
 		}
 	}
 
-	if len(errs) == 0 {
-		return nil
+	err = fetchTimes.Save()
+	if err != nil {
+		log.Warnf("fail to save fetch times: %s", err)
 	}
 
-	errMsg := ""
-	for _, err = range errs {
-		errMsg += err.Error() + "\n"
-	}
-	return errors.New(errMsg)
+	return failures
 }
 
-func (v syncCommand) LocalHalf(allProjects []*project.Project) error {
+// LocalHalf checks out projects, and returns result of each project.
+// Projects are checked out after their parent projects, and projects
+// inside a failed project are skipped. Projects in fetchFailures are
+// failed to fetch and will not be checked out.
+func (v syncCommand) LocalHalf(allProjects []*project.Project, fetchFailures []syncFailure) []syncResult {
 	var (
-		err  error
-		errs []error
-		wg   sync.WaitGroup
+		results []syncResult
 	)
 
 	jobs := v.O.Jobs
@@ -358,44 +587,41 @@ func (v syncCommand) LocalHalf(allProjects []*project.Project) error {
 		jobs = 1
 	}
 
-	jobTasks := make(chan *project.Tree, jobs)
-
 	checkoutOptions := project.CheckoutOptions{
 		Quiet:      config.GetQuiet(),
 		DetachHead: v.O.DetachHead,
 	}
 
-	wg.Add(len(allProjects))
+	fetchErrors := make(map[*project.Project]error)
+	for _, f := range fetchFailures {
+		fetchErrors[f.Project] = f.Err
+	}
+
+	type checkoutResult struct {
+		Tree *project.Tree
+		Err  error
+	}
+
+	tree := project.ProjectsTree(allProjects)
+	total := countTreeProjects(tree)
+
+	// Channels are big enough, and sending will never block.
+	jobTasks := make(chan *project.Tree, total)
+	jobResults := make(chan checkoutResult, total)
 
 	worker := func(i int) {
 		var (
-			err  error
-			tree *project.Tree
-			p    *project.Project
+			err error
+			p   *project.Project
 		)
 
 		log.Debugf("start LocalHalf worker #%d", i)
-		for tree = range jobTasks {
-			p = tree.Project
-			if p != nil {
-				log.Debugf("worker #%d: checkout %s", i, p.Name)
-				err = p.SyncLocalHalf(&checkoutOptions)
-				if err != nil {
-					errs = append(errs, err)
-				}
-			}
-
-			go func(tree project.Tree) {
-				for _, t := range tree.Trees {
-					jobTasks <- t
-				}
-			}(*tree)
-
-			// if p is nil, it's root tree
-			if p != nil {
-				log.Debugf("worker #%d: done %s", i, p.Name)
-				wg.Done()
-			}
+		for t := range jobTasks {
+			p = t.Project
+			log.Debugf("worker #%d: checkout %s", i, p.Name)
+			err = p.SyncLocalHalf(&checkoutOptions)
+			log.Debugf("worker #%d: done %s", i, p.Name)
+			jobResults <- checkoutResult{Tree: t, Err: err}
 		}
 	}
 
@@ -403,21 +629,81 @@ func (v syncCommand) LocalHalf(allProjects []*project.Project) error {
 		go worker(i)
 	}
 
-	tree := project.ProjectsTree(allProjects)
-	jobTasks <- tree
+	// Only this goroutine schedules tasks and collects results.
+	done := 0
+	var schedule func(t *project.Tree)
+	schedule = func(t *project.Tree) {
+		if err, ok := fetchErrors[t.Project]; ok {
+			results = append(results, newSyncResult(t.Project, syncFailed, err))
+			done++
+			done += skipTreeProjects(t, &results)
+			return
+		}
+		jobTasks <- t
+	}
 
-	wg.Wait()
+	for _, t := range tree.Trees {
+		schedule(t)
+	}
+	for done < total {
+		result := <-jobResults
+		done++
+		if result.Err != nil {
+			results = append(results,
+				newSyncResult(result.Tree.Project, syncFailed, result.Err))
+			done += skipTreeProjects(result.Tree, &results)
+			continue
+		}
+		results = append(results,
+			newSyncResult(result.Tree.Project, syncSuccess, nil))
+		for _, t := range result.Tree.Trees {
+			schedule(t)
+		}
+	}
 	close(jobTasks)
 
-	if len(errs) == 0 {
-		return nil
+	return results
+}
+
+// countTreeProjects returns number of projects in tree.
+func countTreeProjects(tree *project.Tree) int {
+	n := 0
+	for _, t := range tree.Trees {
+		n += 1 + countTreeProjects(t)
 	}
+	return n
+}
 
-	errMsg := ""
-	for _, err = range errs {
-		errMsg += err.Error() + "\n"
+// skipTreeProjects marks projects inside tree as skipped, and returns
+// number of projects skipped.
+func skipTreeProjects(tree *project.Tree, results *[]syncResult) int {
+	n := 0
+	for _, t := range tree.Trees {
+		*results = append(*results, newSyncResult(t.Project,
+			syncSkipped,
+			fmt.Errorf("parent project '%s' is not synced", tree.Project.Path)))
+		n += 1 + skipTreeProjects(t, results)
+	}
+	return n
+}
+
+// lintManifest shows problems of manifest as warnings before sync, which
+// may fail later in the middle of sync.
+func (v syncCommand) lintManifest(ws *workspace.RepoWorkSpace) {
+	problems, err := manifest.Lint(ws.AdminDir(), "")
+	if err != nil {
+		log.Debugf("fail to lint manifest: %s", err)
+		return
+	}
+	for _, p := range problems {
+		if rel, err := filepath.Rel(ws.RootDir, p.File); err == nil {
+			p.File = rel
+		}
+		log.Warnf("manifest: %s", p)
+	}
+	if len(problems) > 0 {
+		log.Notef("run 'git repo manifest --lint' to check manifest")
 	}
-	return errors.New(errMsg)
 }
 
 func (v syncCommand) Execute(args []string) error {
@@ -438,6 +724,21 @@ func (v syncCommand) Execute(args []string) error {
 	if v.O.NetworkOnly && v.O.LocalOnly {
 		return newUserError("cannot combine -n and -l")
 	}
+	if v.O.Unshallow && v.O.Deepen > 0 {
+		return newUserError("cannot combine --unshallow and --deepen")
+	}
+	if v.O.NetworkOnly && v.O.PruneBranches {
+		return newUserError("cannot combine -n and --prune-branches")
+	}
+	if err = checkFormat(v.O.Format); err != nil {
+		return err
+	}
+	if v.O.RetryFetches < 0 {
+		return newUserError("--retry-fetches must not be negative")
+	}
+	if v.O.LocalOnly && (v.O.Unshallow || v.O.Deepen > 0) {
+		return newUserError("cannot combine -l with --unshallow or --deepen")
+	}
 	if v.O.ManifestName != "" && v.O.SmartSync {
 		return newUserError("cannot combine -m and -s")
 	}
@@ -462,18 +763,32 @@ func (v syncCommand) Execute(args []string) error {
 
 		Quiet:             config.GetQuiet(),
 		CloneBundle:       !v.O.NoCloneBundle,
+		ShowProgress:      v.O.Jobs == 1 && stderrIsTerminal(),
 		CurrentBranchOnly: v.O.CurrentBranchOnly,
 		ForceSync:         v.O.ForceSync,
 		NoTags:            v.O.NoTags,
 		OptimizedFetch:    v.O.OptimizedFetch,
 		Prune:             v.O.Prune,
+		Unshallow:         v.O.Unshallow,
+		Deepen:            v.O.Deepen,
+		RetryFetches:      v.O.RetryFetches,
 	}
 
 	smartSyncManifestName := "smart_sync_override.xml"
 	smartSyncManifestPath := filepath.Join(rws.ManifestProject.WorkDir, smartSyncManifestName)
 
 	if v.O.SmartSync || v.O.SmartTag != "" {
-		v.CallManifestServerRPC()
+		err = v.CallManifestServerRPC(smartSyncManifestPath)
+		if err != nil {
+			return err
+		}
+		// Sync against the override manifest, and keep using it
+		// after the manifest project is updated.
+		v.O.ManifestName = smartSyncManifestName
+		err = rws.Override(v.O.ManifestName)
+		if err != nil {
+			return err
+		}
 	} else {
 		if _, err = os.Stat(smartSyncManifestPath); err == nil {
 			err = os.Remove(smartSyncManifestPath)
@@ -488,8 +803,14 @@ func (v syncCommand) Execute(args []string) error {
 		return err
 	}
 
+	err = v.updateSubmanifests()
+	if err != nil {
+		return err
+	}
+
 	// Use reloaded WorkSpace after calling `updateManifestProject()`.
 	rws = v.RepoWorkSpace()
+	v.lintManifest(rws)
 
 	allProjects, err := rws.GetProjects(&workspace.GetProjectsOptions{
 		Groups:       rws.Settings().Groups,
@@ -497,17 +818,35 @@ func (v syncCommand) Execute(args []string) error {
 		SubmodulesOK: v.O.FetchSubmodules,
 	}, args...)
 
-	if !v.O.LocalOnly {
-		err = v.NetworkHalf(allProjects)
+	if v.O.UseSuperproject {
+		err = v.useSuperproject(allProjects)
 		if err != nil {
 			return err
 		}
 	}
 
+	// Branches ahead of upstream before fetch have commits of their own,
+	// and are pruned if they are merged into the new upstream.
+	ownCommits := make(map[string]map[string]bool)
+	if v.O.PruneBranches {
+		for _, p := range allProjects {
+			ownCommits[p.Path] = p.BranchesWithOwnCommits()
+		}
+	}
+
+	failures := []syncFailure{}
+	if !v.O.LocalOnly {
+		failures = v.NetworkHalf(allProjects)
+		if len(failures) > 0 && v.O.FailFast {
+			v.showSyncResults(fetchResults(allProjects, failures, syncSkipped))
+			return fmt.Errorf("%d projects failed to fetch, sync aborted", len(failures))
+		}
+	}
+
 	if v.O.NetworkOnly ||
 		rws.ManifestProject.MirrorEnabled() ||
 		rws.ManifestProject.ArchiveEnabled() {
-		return nil
+		return v.showSyncResults(fetchResults(allProjects, failures, syncSuccess))
 	}
 
 	// Call ssh_info API to detect types of remote servers
@@ -523,9 +862,25 @@ func (v syncCommand) Execute(args []string) error {
 		log.Fatal(err)
 	}
 
-	err = v.LocalHalf(allProjects)
+	// Save refs moved or deleted by checkout and prune, which can be
+	// restored by `git repo undo`.
+	journal := rws.BeginOperation(commandLine(), allProjects)
+
+	// Projects fetched successfully are still checked out.
+	results := v.LocalHalf(allProjects, failures)
+
+	if v.O.PruneBranches {
+		v.pruneBranches(allProjects, results, ownCommits)
+	}
+
+	if _, err = journal.Finish(); err != nil {
+		log.Warnf("fail to save journal of operation: %s", err)
+	}
+
+	// Remove stale files created by copyfile and linkfile.
+	staleFiles, err := rws.UpdateCopyLinkFiles(v.O.FetchSubmodules)
 	if err != nil {
-		return err
+		log.Error(err)
 	}
 
 	// If there's a notice that's supposed to print at the end of the sync,
@@ -540,12 +895,203 @@ func (v syncCommand) Execute(args []string) error {
 		for _, p := range remains {
 			fmt.Fprintf(os.Stderr, " * %s\n", p)
 		}
+	}
+
+	// Warn user there are stale files modified by user.
+	if len(staleFiles) > 0 {
+		log.Warn("The following stale files of copyfile or linkfile are modified and not removed:\n")
+		for _, f := range staleFiles {
+			fmt.Fprintf(os.Stderr, " * %s\n", f)
+		}
+	}
+
+	err = v.showSyncResults(results)
+	if err != nil {
+		return err
+	}
+	if len(remains) > 0 {
 		return fmt.Errorf("%d obsolete projects in your workdir need to be removed", len(remains))
 	}
+	return nil
+}
+
+// pruneBranches deletes local branches merged into upstream for projects
+// synced successfully, and shows branches pruned. ownCommits holds branches
+// of each project which were ahead of upstream before fetch.
+func (v syncCommand) pruneBranches(allProjects []*project.Project, results []syncResult, ownCommits map[string]map[string]bool) {
+	type prunedBranch struct {
+		project.MergedBranch
 
+		Path string
+	}
+
+	synced := make(map[string]bool)
+	for _, r := range results {
+		if r.Status == syncSuccess {
+			synced[r.Path] = true
+		}
+	}
+
+	pruned := []prunedBranch{}
+	for _, p := range allProjects {
+		if !synced[p.Path] {
+			continue
+		}
+		branches, _, err := p.PruneMergedBranches(&project.PruneOptions{
+			OwnCommits: ownCommits[p.Path],
+		})
+		if err != nil {
+			log.Errorf("%s%s", p.Prompt(), err)
+		}
+		for _, b := range branches {
+			pruned = append(pruned, prunedBranch{MergedBranch: b, Path: p.Path})
+		}
+	}
+	if len(pruned) == 0 || v.O.Format == formatJSON {
+		return
+	}
+
+	sort.SliceStable(pruned, func(i, j int) bool {
+		if pruned[i].Path == pruned[j].Path {
+			return pruned[i].Name < pruned[j].Name
+		}
+		return pruned[i].Path < pruned[j].Path
+	})
+	maxWidth := 20
+	for _, b := range pruned {
+		if len(b.Path) > maxWidth {
+			maxWidth = len(b.Path)
+		}
+	}
+	fmt.Println("Pruned branches (already merged, backups saved in " + config.RefsBackup + ")")
+	fmt.Println(strings.Repeat("-", 78))
+	for _, b := range pruned {
+		fmt.Printf("%-*s | %s (was %s, %s)\n",
+			maxWidth,
+			b.Path,
+			b.ShortName(),
+			b.Hash[:7],
+			b.MergedBy)
+	}
+	fmt.Println("")
+}
+
+// useSuperproject fetches a single commit of the superproject defined in
+// manifest, and pins revisions of projects to its gitlinks, so that all
+// projects are synced to a consistent state.
+func (v syncCommand) useSuperproject(allProjects []*project.Project) error {
+	rws := v.RepoWorkSpace()
+	sp, err := project.NewSuperproject(rws.Manifest, rws.Settings())
+	if err != nil {
+		return err
+	}
+	if sp == nil {
+		return newUserError("no superproject defined in manifest")
+	}
+	if !v.O.LocalOnly {
+		err = sp.Fetch(config.GetQuiet())
+		if err != nil {
+			return err
+		}
+	}
+	revisions, err := sp.Revisions()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range allProjects {
+		rev, ok := revisions[p.Path]
+		if !ok {
+			log.Warnf("%snot found in superproject, use revision '%s'",
+				p.Prompt(),
+				p.Revision)
+			continue
+		}
+		// Keep tracking branch for local branches.
+		if p.Upstream == "" && !common.IsImmutable(p.Revision) {
+			p.Upstream = p.Revision
+		}
+		p.Revision = rev
+	}
 	return nil
 }
 
+// fetchResults returns results of projects after fetch, projects not
+// failed to fetch are marked as status.
+func fetchResults(allProjects []*project.Project, failures []syncFailure, status string) []syncResult {
+	fetchErrors := make(map[*project.Project]error)
+	for _, f := range failures {
+		fetchErrors[f.Project] = f.Err
+	}
+
+	results := []syncResult{}
+	for _, p := range allProjects {
+		if err, ok := fetchErrors[p]; ok {
+			results = append(results, newSyncResult(p, syncFailed, err))
+		} else {
+			results = append(results, newSyncResult(p, status, nil))
+		}
+	}
+	return results
+}
+
+// showSyncResults shows results of projects in JSON, or shows a summary
+// of projects not synced in text, and returns errors of failed projects.
+func (v syncCommand) showSyncResults(results []syncResult) error {
+	var (
+		failed  int
+		skipped int
+	)
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Path < results[j].Path
+	})
+	maxWidth := 20
+	for _, r := range results {
+		switch r.Status {
+		case syncFailed:
+			failed++
+		case syncSkipped:
+			skipped++
+		default:
+			continue
+		}
+		if len(r.Path) > maxWidth {
+			maxWidth = len(r.Path)
+		}
+	}
+
+	if v.O.Format == formatJSON {
+		err := printJSON(results)
+		if err != nil {
+			return err
+		}
+	} else if failed+skipped > 0 {
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintf(os.Stderr, "Failed to sync the following projects:\n")
+		fmt.Fprintln(os.Stderr, strings.Repeat("-", 78))
+		for _, r := range results {
+			if r.Status == syncSuccess {
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "%-*s | %-7s | %s\n",
+				maxWidth, r.Path, r.Status, r.Error)
+		}
+		fmt.Fprintln(os.Stderr, "")
+	}
+
+	if failed == 0 {
+		return nil
+	}
+	errMsg := ""
+	for _, r := range results {
+		if r.Status == syncFailed {
+			errMsg += r.Error + "\n"
+		}
+	}
+	return errors.New(errMsg)
+}
+
 var syncCmd = syncCommand{
 	WorkSpaceCommand: WorkSpaceCommand{
 		MirrorOK: true,
@@ -0,0 +1,139 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/alibaba/git-repo-go/workspace"
+	"github.com/spf13/cobra"
+)
+
+type undoCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		List  bool
+		Force bool
+	}
+}
+
+func (v *undoCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "undo [<op-id>]",
+		Short: "Restore branches changed by sync, prune or abandon",
+		Long: `Restore branches and HEAD of projects changed by "git repo sync",
+"git repo prune" and "git repo abandon", which are saved in the journal
+of operations in ".repo/journal/".
+
+Undo the latest operation which is not undone yet, or the operation
+given by <op-id>. Use --list to show operations in journal.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return errors.New("too many arguments")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().BoolVarP(&v.O.List,
+		"list",
+		"l",
+		false,
+		"list operations in journal")
+	v.cmd.Flags().BoolVarP(&v.O.Force,
+		"force",
+		"f",
+		false,
+		"restore refs even if they are changed after the operation")
+
+	return v.cmd
+}
+
+func (v undoCommand) showOperations(ops []*workspace.Operation) {
+	for _, op := range ops {
+		status := ""
+		if op.Undone {
+			status = " (undone)"
+		}
+		refs := 0
+		for _, p := range op.Projects {
+			refs += len(p.Refs)
+		}
+		fmt.Printf("%4d  %s  %s: %d refs in %d projects%s\n",
+			op.ID,
+			op.Time.Local().Format("2006-01-02 15:04:05"),
+			op.Command,
+			refs,
+			len(op.Projects),
+			status)
+	}
+}
+
+func (v undoCommand) Execute(args []string) error {
+	var (
+		id  int
+		err error
+	)
+
+	ws := v.RepoWorkSpace()
+	if v.O.List {
+		v.showOperations(ws.Operations())
+		return nil
+	}
+
+	if len(args) > 0 {
+		id, err = strconv.Atoi(args[0])
+		if err != nil || id <= 0 {
+			return fmt.Errorf("invalid operation id '%s'", args[0])
+		}
+	}
+
+	op, err := ws.UndoOperation(id, v.O.Force)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Undo operation %d: %s\n", op.ID, op.Command)
+	for _, p := range op.Projects {
+		for _, ref := range p.Refs {
+			old := ref.Old
+			if old == "" {
+				old = "(deleted)"
+			}
+			fmt.Printf("project %s> %s: %s\n", p.Path, ref.Ref, old)
+		}
+	}
+	return nil
+}
+
+var undoCmd = undoCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd.Command())
+}
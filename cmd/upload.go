@@ -15,20 +15,26 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/alibaba/git-repo-go/common"
 	"github.com/alibaba/git-repo-go/config"
 	"github.com/alibaba/git-repo-go/editor"
 	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/manifest"
 	"github.com/alibaba/git-repo-go/path"
 	"github.com/alibaba/git-repo-go/project"
+	"github.com/alibaba/git-repo-go/workspace"
 	log "github.com/jiangxin/multi-log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -53,23 +59,28 @@ type uploadOptions struct {
 	Branch         string
 	BypassHooks    bool
 	Cc             []string
+	ChangeSet      bool
 	CodeReview     config.CodeReview
 	CurrentBranch  bool
 	Description    string
 	DestBranch     string
 	Draft          bool
 	Issue          string
+	Jobs           int
 	MockGitPush    bool
+	MockGitPushOut string
 	MockEditScript string
 	NoCache        bool
 	NoCertChecks   bool
 	NoEdit         bool
 	NoEmails       bool
+	Plan           string
 	Private        bool
 	PushOptions    []string
 	Reviewers      []string
 	Remote         string
 	Title          string
+	Topic          string
 	WIP            bool
 }
 
@@ -299,7 +310,8 @@ func (v *uploadCommand) Command() *cobra.Command {
 		"change",
 		"c",
 		"",
-		"ID of the specific code review to change")
+		"ID of the specific code review to change, defaults to the review "+
+			"uploaded from the branch last time (single repository mode only)")
 	v.cmd.Flags().BoolVar(&v.O.CurrentBranch,
 		"cbr",
 		false,
@@ -318,6 +330,15 @@ func (v *uploadCommand) Command() *cobra.Command {
 		"p",
 		false,
 		"If specified, upload as a private change")
+	v.cmd.Flags().BoolVar(&v.O.ChangeSet,
+		"change-set",
+		false,
+		"Upload branches of all projects as a change set with a generated topic, "+
+			"which differs between runs (use --topic to pin it)")
+	v.cmd.Flags().StringVar(&v.O.Topic,
+		"topic",
+		"",
+		"Upload branches of all projects as a change set with this topic")
 	v.cmd.Flags().StringVar(&v.O.Title,
 		"title",
 		"",
@@ -356,7 +377,7 @@ func (v *uploadCommand) Command() *cobra.Command {
 	v.cmd.Flags().BoolVar(&v.O.BypassHooks,
 		"no-verify",
 		false,
-		"Do not run the upload hook")
+		"Do not run the upload hook and upload checks")
 	v.cmd.Flags().BoolVar(&v.O.AllowAllHooks,
 		"verify",
 		false,
@@ -365,6 +386,15 @@ func (v *uploadCommand) Command() *cobra.Command {
 		"no-cache",
 		false,
 		"Ignore ssh-info cache, and recheck ssh-info API")
+	v.cmd.Flags().IntVarP(&v.O.Jobs,
+		"jobs",
+		"j",
+		1,
+		"number of branches to upload simultaneously")
+	v.cmd.Flags().StringVar(&v.O.Plan,
+		"plan",
+		"",
+		"show git push commands in given format (json) without uploading")
 
 	v.cmd.Flags().BoolVar(&v.O.NoEdit,
 		"no-edit",
@@ -374,6 +404,10 @@ func (v *uploadCommand) Command() *cobra.Command {
 		"mock-git-push",
 		false,
 		"Mock git-push for test")
+	v.cmd.Flags().StringVar(&v.O.MockGitPushOut,
+		"mock-git-push-output",
+		"",
+		"file of server messages for mock git-push, for test")
 	v.cmd.Flags().StringVar(&v.O.MockEditScript,
 		"mock-edit-script",
 		"",
@@ -381,6 +415,7 @@ func (v *uploadCommand) Command() *cobra.Command {
 
 	v.cmd.Flags().MarkHidden("auto-topic")
 	v.cmd.Flags().MarkHidden("mock-git-push")
+	v.cmd.Flags().MarkHidden("mock-git-push-output")
 	v.cmd.Flags().MarkHidden("mock-edit-script")
 
 	return v.cmd
@@ -408,6 +443,22 @@ func (v *uploadCommand) getDestBranch(branch *project.ReviewableBranch) (string,
 	return destBranch, nil
 }
 
+// UploadPlanOfAll shows upload plans of all branches ready for upload,
+// without confirmation.
+func (v uploadCommand) UploadPlanOfAll(branchesMap map[string][]project.ReviewableBranch) error {
+	branches := []project.ReviewableBranch{}
+	for key := range branchesMap {
+		branches = append(branches, branchesMap[key]...)
+	}
+	sort.Slice(branches, func(i, j int) bool {
+		if branches[i].Project.Path != branches[j].Project.Path {
+			return branches[i].Project.Path < branches[j].Project.Path
+		}
+		return branches[i].Branch.Name < branches[j].Branch.Name
+	})
+	return v.UploadAndReport(branches)
+}
+
 func (v uploadCommand) UploadForReviewWithConfirm(branchesMap map[string][]project.ReviewableBranch) error {
 	var (
 		answer   bool
@@ -813,6 +864,301 @@ func (v uploadCommand) fmtUploadOptionsScript(optionsFile string, published bool
 	return script
 }
 
+// runPreUploadHook runs pre-upload hook defined in `<repo-hooks>` of
+// manifest on branches to upload. Hook script is trusted only after the
+// user approves it, or --verify is given.
+func (v *uploadCommand) runPreUploadHook(branches []project.ReviewableBranch) error {
+	if v.O.BypassHooks || v.O.Plan != "" || config.IsSingleMode() {
+		return nil
+	}
+
+	rws := v.RepoWorkSpace()
+	if rws.Manifest == nil ||
+		rws.Manifest.RepoHooks == nil ||
+		rws.Manifest.RepoHooks.InProject == "" {
+		return nil
+	}
+	ps := rws.GetProjectsWithName(rws.Manifest.RepoHooks.InProject)
+	if len(ps) == 0 || !ps[0].Exists() {
+		log.Warnf("hooks project '%s' is not synced, skip running hooks",
+			rws.Manifest.RepoHooks.InProject)
+		return nil
+	}
+	hook := project.NewRepoHook(project.HookTypePreUpload,
+		ps[0],
+		rws.Manifest.RepoHooks.EnabledList)
+	if hook == nil {
+		return nil
+	}
+
+	if !v.O.AllowAllHooks && !hook.IsApproved() {
+		fmt.Printf("Repository hook script:\n")
+		fmt.Printf("  %s\n", hook.Script)
+		if hook.HasApproval() {
+			fmt.Printf("This script will run before upload, and it has changed since it was last approved.\n")
+		} else {
+			fmt.Printf("This script will run before upload, and it has not been approved yet.\n")
+		}
+		input := userInput("Do you want to allow this script to run (yes/always/NO)? ", "NO")
+		if strings.ToLower(strings.TrimSpace(input)) == "always" {
+			err := hook.Approve()
+			if err != nil {
+				log.Warnf("fail to save approval of hook: %s", err)
+			}
+		} else if !answerIsTrue(input) {
+			return newUserErrorF("%s hook is not approved, upload aborted (use --no-verify to skip hooks)",
+				hook.Type)
+		}
+	}
+
+	items := []project.RepoHookItem{}
+	for _, branch := range branches {
+		item := project.RepoHookItem{
+			Name:    branch.Project.Name,
+			WorkDir: branch.Project.WorkDir,
+			Head:    branch.Branch.Hash,
+		}
+		if branch.CodeReview.Empty() {
+			item.Base = branch.RemoteTrack.Track.Hash
+		} else {
+			item.Base, _ = branch.Project.ResolveRevision(branch.CodeReview.Ref)
+		}
+		items = append(items, item)
+	}
+
+	err := hook.Run(items)
+	if err != nil {
+		return newUserErrorF("%s, upload aborted (use --no-verify to skip hooks)", err)
+	}
+	return nil
+}
+
+// runUploadChecks checks commits of branches before upload, such as
+// missing Change-Id and wrong email, and aborts if any problem is found.
+// Checks are configured by "review.<url>.*" git config variables.
+func (v *uploadCommand) runUploadChecks(branches []project.ReviewableBranch) error {
+	if v.O.BypassHooks || v.O.Plan != "" {
+		return nil
+	}
+
+	total := 0
+	for _, branch := range branches {
+		problems, err := branch.CheckCommits()
+		if err != nil {
+			log.Warnf("%sfail to check commits of branch %s: %s",
+				branch.Project.Prompt(),
+				branch.Branch.Name,
+				err)
+			continue
+		}
+		if len(problems) == 0 {
+			continue
+		}
+		log.Errorf("project %s/ (branch %s) fails to pass upload checks:",
+			branch.Project.Path,
+			branch.Branch.Name)
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "    %s\n", problem)
+		}
+		total += len(problems)
+	}
+	if total > 0 {
+		return fmt.Errorf("found %d problems in commits, upload aborted (use --no-verify to skip checks)",
+			total)
+	}
+	return nil
+}
+
+// reportReviews saves code reviews created or updated by upload in git
+// config of branches, and shows links of them.
+func (v *uploadCommand) reportReviews(tasks []uploadTask) {
+	for _, task := range tasks {
+		branch := task.branch
+		if !branch.Uploaded || branch.Review == nil {
+			continue
+		}
+		// Save config one by one, for tasks may share the same project.
+		err := branch.SaveReview()
+		if err != nil {
+			log.Warnf("%sfail to save review of branch %s: %s",
+				branch.Project.Prompt(),
+				branch.Branch.Name,
+				err)
+		}
+		link := branch.Review.URL
+		if branch.Review.ID != "" {
+			link = strings.TrimSpace("#" + branch.Review.ID + " " + link)
+		}
+		fmt.Fprintf(os.Stderr,
+			"[REVIEW] %-15s %-15s %s\n",
+			branch.Project.Path+"/",
+			branch.Branch.Name,
+			link)
+	}
+}
+
+// showUploadPlans shows git push commands of branches in JSON format
+// instead of pushing.
+func (v *uploadCommand) showUploadPlans(tasks []uploadTask) error {
+	plans := []*project.UploadPlan{}
+	failed := 0
+	for _, task := range tasks {
+		plan, err := task.branch.UploadPlan(&task.options)
+		if err != nil {
+			log.Errorf("%sfail to resolve upload plan of branch %s: %s",
+				task.branch.Project.Prompt(),
+				task.branch.Branch.Name,
+				err)
+			failed++
+			continue
+		}
+		plans = append(plans, plan)
+	}
+	data, err := json.MarshalIndent(plans, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	if failed > 0 {
+		return fmt.Errorf("fail to resolve upload plan of %d of %d branches",
+			failed,
+			len(tasks))
+	}
+	return nil
+}
+
+// UploadError is returned by UploadAndReport if some branches fail to
+// upload, and holds result of all branches.
+type UploadError struct {
+	Branches []project.ReviewableBranch
+}
+
+// Failed returns branches which fail to upload, or are uploaded but
+// cannot be saved in change set.
+func (v UploadError) Failed() []project.ReviewableBranch {
+	result := []project.ReviewableBranch{}
+	for _, branch := range v.Branches {
+		if branch.Error != nil {
+			result = append(result, branch)
+		}
+	}
+	return result
+}
+
+func (v UploadError) Error() string {
+	return fmt.Sprintf("fail to upload %d of %d branches",
+		len(v.Failed()),
+		len(v.Branches))
+}
+
+// uploadTask is a branch confirmed for upload, and its upload options.
+type uploadTask struct {
+	branch  *project.ReviewableBranch
+	options config.UploadOptions
+}
+
+// runUploadTasks pushes branches for review. If more than one job is
+// given, pushes run simultaneously, and output of git push is shown
+// together for each project when it is done.
+func (v *uploadCommand) runUploadTasks(tasks []uploadTask) {
+	upload := func(task uploadTask, out io.Writer) {
+		err := task.branch.UploadForReviewWithOutput(&task.options, out)
+		if err != nil {
+			task.branch.Uploaded = false
+			task.branch.Error = err
+		} else {
+			task.branch.Uploaded = true
+		}
+	}
+
+	jobs := min(min(v.O.Jobs, config.MaxJobs), len(tasks))
+	if jobs <= 1 {
+		for _, task := range tasks {
+			upload(task, nil)
+		}
+		return
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		taskCh = make(chan uploadTask, len(tasks))
+	)
+
+	for _, task := range tasks {
+		taskCh <- task
+	}
+	close(taskCh)
+
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range taskCh {
+				buf := bytes.Buffer{}
+				upload(task, &buf)
+
+				mu.Lock()
+				fmt.Fprintf(os.Stderr, "project %s/ (branch %s):\n",
+					task.branch.Project.Path,
+					task.branch.Branch.ShortName())
+				os.Stderr.Write(buf.Bytes())
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// saveChangeSet records references of code reviews uploaded with the same
+// topic, which can be downloaded by `git repo download --change-set`.
+func (v *uploadCommand) saveChangeSet(tasks []uploadTask) {
+	if config.IsSingleMode() {
+		return
+	}
+	projects := []manifest.Project{}
+	for _, task := range tasks {
+		branch := task.branch
+		if !branch.Uploaded {
+			continue
+		}
+		reviewRef, err := branch.ReviewRef()
+		if err != nil {
+			// Branch is pushed, but reviewers cannot download it
+			// with the change set, report it as failed.
+			branch.Error = fmt.Errorf("not saved in change set '%s': %s",
+				v.O.Topic,
+				err)
+			continue
+		}
+		destBranch := task.options.DestBranch
+		if destBranch == "" {
+			destBranch = branch.DestBranch
+		}
+		projects = append(projects, manifest.Project{
+			Name:       branch.Project.Name,
+			Path:       branch.Project.Path,
+			RemoteName: branch.Remote.Name,
+			Revision:   reviewRef,
+			DestBranch: destBranch,
+		})
+	}
+	if len(projects) == 0 {
+		return
+	}
+	filename, err := v.RepoWorkSpace().SaveChangeSet(v.O.Topic, projects)
+	if err != nil {
+		log.Errorf("fail to save change set '%s': %s", v.O.Topic, err)
+		return
+	}
+	log.Notef("change set '%s' of %d projects is saved in '%s'",
+		v.O.Topic,
+		len(projects),
+		filename)
+}
+
+// UploadAndReport uploads branches for review, and returns UploadError if
+// any branch fails to upload.
 func (v *uploadCommand) UploadAndReport(branches []project.ReviewableBranch) error {
 	var (
 		origPeople = [][]string{{}, {}}
@@ -821,6 +1167,25 @@ func (v *uploadCommand) UploadAndReport(branches []project.ReviewableBranch) err
 		destBranch string
 	)
 
+	err = v.runPreUploadHook(branches)
+	if err != nil {
+		return err
+	}
+
+	mockGitPushOutput := ""
+	if v.O.MockGitPushOut != "" {
+		data, err := ioutil.ReadFile(v.O.MockGitPushOut)
+		if err != nil {
+			return err
+		}
+		mockGitPushOutput = string(data)
+	}
+
+	err = v.runUploadChecks(branches)
+	if err != nil {
+		return err
+	}
+
 	if len(v.O.Reviewers) > 0 {
 		for _, reviewer := range strings.Split(
 			strings.Join(v.O.Reviewers, ","),
@@ -842,7 +1207,7 @@ func (v *uploadCommand) UploadAndReport(branches []project.ReviewableBranch) err
 		}
 	}
 
-	haveErrors := false
+	tasks := []uploadTask{}
 	for i := range branches {
 		// Will update branch.Error in this loop.
 		branch := &(branches[i])
@@ -860,7 +1225,7 @@ func (v *uploadCommand) UploadAndReport(branches []project.ReviewableBranch) err
 		people[1] = append(people[1], origPeople[1]...)
 		branch.AppendReviewers(people)
 		cfg := theProject.ConfigWithDefault()
-		if !theProject.IsClean() {
+		if !theProject.IsClean() && v.O.Plan == "" {
 			key := fmt.Sprintf("review.%s.autoupload", remote.Review)
 			if !cfg.HasKey(key) {
 				fmt.Printf("Uncommitted changes in " + theProject.Name)
@@ -926,6 +1291,7 @@ func (v *uploadCommand) UploadAndReport(branches []project.ReviewableBranch) err
 			Issue:        v.O.Issue,
 			LocalBranch:  branch.Branch.Name,
 			MockGitPush:  v.O.MockGitPush,
+			MockPushOut:  mockGitPushOutput,
 			NoCertChecks: v.O.NoCertChecks || config.NoCertChecks(),
 			NoEmails:     v.O.NoEmails,
 			OldOid:       oldOid,
@@ -933,39 +1299,55 @@ func (v *uploadCommand) UploadAndReport(branches []project.ReviewableBranch) err
 			Private:      v.O.Private,
 			PushOptions:  v.O.PushOptions,
 			Title:        v.O.Title,
+			Topic:        v.O.Topic,
 			WIP:          v.O.WIP,
 		}
 
-		err = branch.UploadForReview(&o)
-		if err != nil {
-			branch.Uploaded = false
-			branch.Error = err
-			haveErrors = true
-		} else {
-			branch.Uploaded = true
-		}
+		tasks = append(tasks, uploadTask{
+			branch:  branch,
+			options: o,
+		})
+	}
+
+	if v.O.Plan != "" {
+		return v.showUploadPlans(tasks)
+	}
+
+	// Run git push after all confirmations are collected.
+	v.runUploadTasks(tasks)
+
+	if v.O.Topic != "" {
+		v.saveChangeSet(tasks)
 	}
 
 	fmt.Fprintln(os.Stderr, "")
 	fmt.Fprintln(os.Stderr, "----------------------------------------------------------------------")
+	v.reportReviews(tasks)
+	// Branches skipped by user are only reported if other branches fail.
+	haveErrors := false
+	for _, task := range tasks {
+		if task.branch.Error != nil {
+			haveErrors = true
+			break
+		}
+	}
 	if haveErrors {
-		for _, branch := range branches {
-			if !branch.Uploaded && branch.Error != nil {
-				format := ""
-				if len(branch.Error.Error()) <= 30 {
-					format = " (%s)"
-				} else {
-					format = "\n       (%s)"
-				}
-				fmt.Fprintf(os.Stderr,
-					"[FAILED] %-15s %-15s"+format+"\n",
-					branch.Project.Path+"/",
-					branch.Branch.Name,
-					branch.Error.Error())
+		uploadErr := UploadError{Branches: branches}
+		for _, branch := range uploadErr.Failed() {
+			format := ""
+			if len(branch.Error.Error()) <= 30 {
+				format = " (%s)"
+			} else {
+				format = "\n       (%s)"
 			}
+			fmt.Fprintf(os.Stderr,
+				"[FAILED] %-15s %-15s"+format+"\n",
+				branch.Project.Path+"/",
+				branch.Branch.Name,
+				branch.Error.Error())
 		}
 		fmt.Fprintln(os.Stderr, "")
-		os.Exit(1)
+		return uploadErr
 	}
 	return nil
 }
@@ -980,6 +1362,22 @@ func (v uploadCommand) Execute(args []string) error {
 	if v.O.Remote != "" && !config.IsSingleMode() {
 		return fmt.Errorf("--remote can be only used with --single")
 	}
+	if v.O.Topic != "" {
+		if err = workspace.CheckTopic(v.O.Topic); err != nil {
+			return err
+		}
+	}
+	if (v.O.Topic != "" || v.O.ChangeSet) && v.O.CodeReview.ID != "" {
+		return newUserError("cannot combine --topic or --change-set with --change")
+	}
+	if v.O.Plan != "" && v.O.Plan != "json" {
+		return newUserErrorF("unsupported format '%s' for --plan, only json is supported", v.O.Plan)
+	}
+	// Generated topic changes in every run, and plan would not match
+	// the real upload.
+	if v.O.Plan != "" && v.O.ChangeSet && v.O.Topic == "" {
+		return newUserError("--plan with --change-set needs --topic to pin the topic")
+	}
 
 	allProjects, err := ws.GetProjects(nil, args...)
 	if err != nil {
@@ -1046,7 +1444,27 @@ func (v uploadCommand) Execute(args []string) error {
 				)
 			}
 
-			if v.O.CodeReview.ID != "" {
+			// Update code review created by last upload of the branch if
+			// "review.<url>.updateSavedReview" is set, except Gerrit, which
+			// finds changes to update by Change-Id.
+			if v.O.CodeReview.ID == "" &&
+				v.O.Topic == "" &&
+				!v.O.ChangeSet &&
+				remote.GetType() != helper.ProtoTypeGerrit &&
+				p.ConfigWithDefault().GetBool(
+					fmt.Sprintf("review.%s.updateSavedReview", remote.Review),
+					false) {
+				v.O.CodeReview = p.SavedCodeReview(head)
+				if !v.O.CodeReview.Empty() {
+					log.Notef("will update code review #%s uploaded from branch '%s' "+
+						"(unset branch.%s.review-id to create a new one)",
+						v.O.CodeReview.ID,
+						head,
+						head)
+				}
+			}
+
+			if v.O.CodeReview.ID != "" && v.O.CodeReview.Ref == "" {
 				v.O.CodeReview.Ref, err = remote.GetDownloadRef(v.O.CodeReview.ID, "")
 				if err != nil {
 					return fmt.Errorf("fail to get local ref for code review #%s: %s",
@@ -1129,6 +1547,22 @@ func (v uploadCommand) Execute(args []string) error {
 		return nil
 	}
 
+	if v.O.Topic == "" && v.O.ChangeSet {
+		names := []string{}
+		for _, branches := range tasks {
+			for _, branch := range branches {
+				names = append(names, branch.Branch.Name)
+			}
+		}
+		sort.Strings(names)
+		v.O.Topic = workspace.NewTopic(names)
+	}
+
+	// Nothing is pushed for plan, and published refs are left untouched.
+	if v.O.Plan != "" {
+		return v.UploadPlanOfAll(tasks)
+	}
+
 	if v.O.NoEdit || editor.Editor() == "" {
 		err = v.UploadForReviewWithConfirm(tasks)
 	} else {
@@ -1,9 +1,19 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/alibaba/git-repo-go/project"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -125,3 +135,143 @@ true
 		},
 	)
 }
+
+func TestUploadError(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		branches = []project.ReviewableBranch{
+			{
+				Branch:   project.Branch{Name: "topic1"},
+				Uploaded: true,
+			},
+			{
+				Branch: project.Branch{Name: "topic2"},
+				Error:  errors.New("upload failed: exit status 1"),
+			},
+			{
+				Branch: project.Branch{Name: "topic3"},
+				Error:  errors.New("User aborted"),
+			},
+			{
+				Branch: project.Branch{Name: "topic4"},
+			},
+		}
+	)
+
+	var err error = UploadError{Branches: branches}
+	assert.Equal("fail to upload 2 of 4 branches", err.Error())
+
+	uploadErr, ok := err.(UploadError)
+	assert.True(ok)
+	failed := []string{}
+	for _, branch := range uploadErr.Failed() {
+		failed = append(failed, branch.Branch.Name)
+	}
+	assert.Equal([]string{"topic2", "topic3"}, failed)
+}
+
+// filePushHelper pushes to file:// URL, and git push writes to both stdout
+// and stderr.
+type filePushHelper struct {
+	helper.DefaultProtoHelper
+
+	pushURL string
+}
+
+func (v filePushHelper) GetType() string {
+	return "file"
+}
+
+func (v filePushHelper) GetSSHInfo() *helper.SSHInfo {
+	return &helper.SSHInfo{PushURL: v.pushURL}
+}
+
+func (v filePushHelper) GetGitPushCommand(o *config.UploadOptions) (*helper.GitPushCommand, error) {
+	return &helper.GitPushCommand{
+		Cmd: "git",
+		Args: []string{
+			"push",
+			"--porcelain",
+			"--verbose",
+			o.RemoteURL,
+			o.LocalBranch + ":refs/for/" + o.DestBranch,
+		},
+	}, nil
+}
+
+// TestRunUploadTasksJobs runs git push simultaneously, and should be run
+// with -race to check output of pushes.
+func TestRunUploadTasksJobs(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	git := func(dir string, args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=A", "GIT_AUTHOR_EMAIL=a@example.com",
+			"GIT_COMMITTER_NAME=C", "GIT_COMMITTER_EMAIL=c@example.com")
+		out, err := cmd.Output()
+		if err != nil {
+			panic(fmt.Sprintf("fail to run git %s: %s", args[0], err))
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	remoteDir := filepath.Join(tmpdir, "remote")
+	topDir := filepath.Join(tmpdir, "work")
+	remote := project.NewRemote(
+		&manifest.Remote{Name: "origin", Fetch: ".."},
+		filePushHelper{pushURL: "file://" + remoteDir},
+	)
+	tasks := []uploadTask{}
+	for i := 1; i <= 3; i++ {
+		name := fmt.Sprintf("project%d", i)
+		git(tmpdir, "init", "-q", "--bare", filepath.Join(remoteDir, name+".git"))
+
+		p := project.NewProject(&manifest.Project{
+			Name:           name,
+			Path:           name,
+			RemoteName:     "origin",
+			ManifestRemote: &manifest.Remote{Name: "origin", Fetch: ".."},
+		}, &project.RepoSettings{
+			TopDir:      topDir,
+			ManifestURL: "file://" + tmpdir + "/manifests",
+		}, nil)
+		assert.Nil(p.GitInit())
+		assert.Nil(os.MkdirAll(p.WorkDir, 0755))
+		assert.Nil(os.Symlink(p.GitDir, filepath.Join(p.WorkDir, ".git")))
+		tree := git(p.WorkDir, "mktree")
+		commit := git(p.WorkDir, "commit-tree", "-m", "new commit", tree)
+		git(p.WorkDir, "update-ref", "refs/heads/my/topic", commit)
+
+		tasks = append(tasks, uploadTask{
+			branch: &project.ReviewableBranch{
+				Project:    p,
+				Branch:     project.Branch{Name: "refs/heads/my/topic", Hash: commit},
+				DestBranch: "master",
+				Remote:     remote,
+			},
+			options: config.UploadOptions{
+				LocalBranch: "refs/heads/my/topic",
+			},
+		})
+	}
+
+	cmd := uploadCommand{}
+	cmd.O.Jobs = 3
+	cmd.runUploadTasks(tasks)
+	for _, task := range tasks {
+		if assert.Nil(task.branch.Error, task.branch.Project.Name) {
+			assert.True(task.branch.Uploaded)
+			assert.Equal(task.branch.Branch.Hash,
+				git(filepath.Join(remoteDir, task.branch.Project.Name+".git"),
+					"rev-parse", "refs/for/master"))
+		}
+	}
+}
@@ -10,8 +10,6 @@ import (
 
 // URLJoin appends fetch path (in remote element) and project name to manifest url.
 func URLJoin(u string, paths ...string) (string, error) {
-	var err error
-
 	// remove last part of url
 	if len(u) > 0 && u[len(u)-1] == '/' {
 		u = u[0 : len(u)-1]
@@ -20,6 +18,12 @@ func URLJoin(u string, paths ...string) (string, error) {
 	if i > 0 {
 		u = u[0:i]
 	}
+	return URLAppend(u, paths...)
+}
+
+// URLAppend appends paths to url, and the last part of url is kept.
+func URLAppend(u string, paths ...string) (string, error) {
+	var err error
 
 	for _, p := range paths {
 		u, err = joinTwoURL(u, p)
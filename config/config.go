@@ -69,12 +69,14 @@ const (
 	ManifestXML      = "manifest.xml"
 	LocalManifestXML = "local_manifest.xml"
 	LocalManifests   = "local_manifests"
+	Submanifests     = "submanifests"
 	ProjectObjects   = "project-objects"
 	Projects         = "projects"
 
 	RefsHeads   = "refs/heads/"
 	RefsTags    = "refs/tags/"
 	RefsPub     = "refs/published/"
+	RefsBackup  = "refs/repo-backup/"
 	Refs        = "refs/"
 	RefsRemotes = "refs/remotes/"
 
@@ -10,6 +10,7 @@ type UploadOptions struct {
 	Issue        string
 	LocalBranch  string // Local branch with commits, will push to remote.
 	MockGitPush  bool
+	MockPushOut  string // Mock messages of server for git push.
 	NoCertChecks bool
 	NoEmails     bool
 	OldOid       string
@@ -19,5 +20,6 @@ type UploadOptions struct {
 	RemoteName   string
 	RemoteURL    string
 	Title        string
+	Topic        string // Topic of a change set across projects.
 	WIP          bool
 }
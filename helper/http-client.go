@@ -0,0 +1,40 @@
+package helper
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	log "github.com/jiangxin/multi-log"
+)
+
+// NewHTTPClient creates http client with timeout, which respects
+// config.NoCertChecks() and http proxy settings of git config.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	tr := &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   timeout,
+			KeepAlive: timeout,
+		}).DialContext,
+		TLSClientConfig:       &tls.Config{InsecureSkipVerify: config.NoCertChecks()},
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		ExpectContinueTimeout: 1 * time.Second,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       timeout,
+		DisableCompression:    true,
+		Proxy:                 http.ProxyFromEnvironment,
+	}
+
+	// http.proxy overrides env $HTTP_PROXY, $HTTPS_PROXY and $NO_PROXY (or the lowercase versions thereof).
+	proxyURL, err := GetProxyFromGitConfig()
+	if err != nil {
+		log.Debugf("fail to get proxy from git config: %s", err)
+	} else {
+		tr.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	return &http.Client{Transport: tr}
+}
@@ -0,0 +1,214 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	log "github.com/jiangxin/multi-log"
+)
+
+// ManifestServer is a XML-RPC client of the manifest server defined in
+// the `<manifest-server>` element of manifest, which is used by smart sync.
+type ManifestServer struct {
+	URL      string
+	Username string
+	Password string
+}
+
+// NewManifestServer creates ManifestServer for url. Username and password
+// are used for basic authentication, and if they are empty, will use
+// credential embedded in url.
+func NewManifestServer(url, username, password string) *ManifestServer {
+	return &ManifestServer{
+		URL:      url,
+		Username: username,
+		Password: password,
+	}
+}
+
+// GetApprovedManifest returns manifest of the latest known good build of
+// branch. Target (such as "<product>-<variant>") is optional.
+func (v ManifestServer) GetApprovedManifest(branch, target string) (string, error) {
+	params := []string{branch}
+	if target != "" {
+		params = append(params, target)
+	}
+	return v.call("GetApprovedManifest", params...)
+}
+
+// GetManifest returns manifest of the build with the specific tag.
+func (v ManifestServer) GetManifest(tag string) (string, error) {
+	return v.call("GetManifest", tag)
+}
+
+type xmlrpcParam struct {
+	Value string `xml:"value>string"`
+}
+
+type xmlrpcMethodCall struct {
+	XMLName    xml.Name      `xml:"methodCall"`
+	MethodName string        `xml:"methodName"`
+	Params     []xmlrpcParam `xml:"params>param"`
+}
+
+type xmlrpcMember struct {
+	Name  string      `xml:"name"`
+	Value xmlrpcValue `xml:"value"`
+}
+
+type xmlrpcValue struct {
+	Text    string         `xml:",chardata"`
+	String  *string        `xml:"string"`
+	Boolean *string        `xml:"boolean"`
+	Int     *string        `xml:"int"`
+	I4      *string        `xml:"i4"`
+	Array   []xmlrpcValue  `xml:"array>data>value"`
+	Struct  []xmlrpcMember `xml:"struct>member"`
+}
+
+type xmlrpcMethodResponse struct {
+	XMLName xml.Name      `xml:"methodResponse"`
+	Params  []xmlrpcValue `xml:"params>param>value"`
+	Fault   *xmlrpcValue  `xml:"fault>value"`
+}
+
+// str returns value of string type. A value without a type element
+// is also a string.
+func (v xmlrpcValue) str() string {
+	switch {
+	case v.String != nil:
+		return *v.String
+	case v.Int != nil:
+		return *v.Int
+	case v.I4 != nil:
+		return *v.I4
+	case v.Boolean != nil:
+		return *v.Boolean
+	}
+	return v.Text
+}
+
+func (v xmlrpcValue) member(name string) *xmlrpcValue {
+	for _, m := range v.Struct {
+		if m.Name == name {
+			return &m.Value
+		}
+	}
+	return nil
+}
+
+func (v ManifestServer) endpoint() (string, string, string, error) {
+	u, err := url.Parse(v.URL)
+	if err != nil {
+		return "", "", "", fmt.Errorf("bad manifest server url '%s': %s", v.URL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", "", "", fmt.Errorf("unsupported protocol of manifest server: %s", v.URL)
+	}
+	username, password := v.Username, v.Password
+	if username == "" && u.User != nil {
+		username = u.User.Username()
+		password, _ = u.User.Password()
+	}
+	u.User = nil
+	return u.String(), username, password, nil
+}
+
+func (v ManifestServer) call(method string, params ...string) (string, error) {
+	endpoint, username, password, err := v.endpoint()
+	if err != nil {
+		return "", err
+	}
+
+	call := xmlrpcMethodCall{MethodName: method}
+	for _, p := range params {
+		call.Params = append(call.Params, xmlrpcParam{Value: p})
+	}
+	body, err := xml.Marshal(&call)
+	if err != nil {
+		return "", err
+	}
+	body = append([]byte(xml.Header), body...)
+
+	log.Debugf("call %s(%s) of manifest server: %s",
+		method,
+		strings.Join(params, ", "),
+		endpoint)
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("bad manifest server request to '%s': %s", endpoint, err)
+	}
+	req.Header.Set("Content-Type", "text/xml")
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := getHTTPClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fail to connect to manifest server '%s': %s", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("%d: bad response of manifest server '%s'",
+			resp.StatusCode,
+			endpoint)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return parseManifestServerResponse(data)
+}
+
+// parseManifestServerResponse parses XML-RPC response of manifest server,
+// which is an array of a boolean flag and a string, such as:
+// [true, "<manifest>...</manifest>"], or [false, "error message"].
+func parseManifestServerResponse(data []byte) (string, error) {
+	var resp xmlrpcMethodResponse
+
+	err := xml.Unmarshal(data, &resp)
+	if err != nil {
+		return "", fmt.Errorf("bad XML-RPC response: %s", err)
+	}
+	if resp.Fault != nil {
+		msg := ""
+		if m := resp.Fault.member("faultString"); m != nil {
+			msg = m.str()
+		}
+		if m := resp.Fault.member("faultCode"); m != nil {
+			msg = fmt.Sprintf("%s (code: %s)", msg, m.str())
+		}
+		return "", fmt.Errorf("manifest server fault: %s", strings.TrimSpace(msg))
+	}
+	if len(resp.Params) != 1 || len(resp.Params[0].Array) != 2 {
+		return "", errors.New("bad XML-RPC response: expect an array of [success, manifest]")
+	}
+
+	success := strings.TrimSpace(resp.Params[0].Array[0].str())
+	value := resp.Params[0].Array[1].str()
+	if success != "1" && success != "true" {
+		return "", fmt.Errorf("manifest server RPC call failed: %s", value)
+	}
+	return value, nil
+}
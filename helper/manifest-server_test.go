@@ -0,0 +1,140 @@
+package helper
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testManifestContent = `<?xml version="1.0" encoding="UTF-8"?>
+<manifest>
+  <remote name="aone" fetch=".." />
+  <default remote="aone" revision="master" />
+  <project name="main" path="main" revision="1234567" />
+</manifest>
+`
+
+func newTestManifestServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			call struct {
+				MethodName string   `xml:"methodName"`
+				Params     []string `xml:"params>param>value>string"`
+			}
+			ok    = true
+			value string
+		)
+
+		username, password, _ := r.BasicAuth()
+		if username != "" && (username != "user" || password != "secret") {
+			w.WriteHeader(401)
+			return
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		if err := xml.Unmarshal(data, &call); err != nil {
+			w.WriteHeader(400)
+			return
+		}
+
+		switch call.MethodName {
+		case "GetApprovedManifest":
+			if call.Params[0] != "master" {
+				ok = false
+				value = "no approved build for " + call.Params[0]
+			} else {
+				value = testManifestContent
+			}
+			if len(call.Params) > 1 {
+				value = strings.Replace(value, "1234567", call.Params[1], -1)
+			}
+		case "GetManifest":
+			value = strings.Replace(testManifestContent, "1234567", call.Params[0], -1)
+		default:
+			fmt.Fprintf(w, `<?xml version="1.0"?>
+<methodResponse><fault><value><struct>
+<member><name>faultCode</name><value><int>1</int></value></member>
+<member><name>faultString</name><value><string>unknown method %s</string></value></member>
+</struct></value></fault></methodResponse>`, call.MethodName)
+			return
+		}
+
+		okValue := 0
+		if ok {
+			okValue = 1
+		}
+		fmt.Fprintf(w, `<?xml version="1.0"?>
+<methodResponse><params><param><value><array><data>
+<value><boolean>%d</boolean></value>
+<value><string>%s</string></value>
+</data></array></value></param></params></methodResponse>`,
+			okValue,
+			strings.NewReplacer("<", "&lt;", ">", "&gt;").Replace(value))
+	}))
+}
+
+func TestManifestServerGetApprovedManifest(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := newTestManifestServer(t)
+	defer ts.Close()
+
+	server := NewManifestServer(ts.URL, "", "")
+	m, err := server.GetApprovedManifest("master", "")
+	assert.Nil(err)
+	assert.Equal(testManifestContent, m)
+
+	m, err = server.GetApprovedManifest("master", "product-eng")
+	assert.Nil(err)
+	assert.Contains(m, `revision="product-eng"`)
+
+	_, err = server.GetApprovedManifest("next", "")
+	assert.Equal("manifest server RPC call failed: no approved build for next", err.Error())
+}
+
+func TestManifestServerGetManifest(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := newTestManifestServer(t)
+	defer ts.Close()
+
+	server := NewManifestServer(ts.URL, "user", "secret")
+	m, err := server.GetManifest("v1.0")
+	assert.Nil(err)
+	assert.Contains(m, `revision="v1.0"`)
+
+	server = NewManifestServer(ts.URL, "user", "bad")
+	_, err = server.GetManifest("v1.0")
+	assert.NotNil(err)
+	assert.True(strings.HasPrefix(err.Error(), "401: "))
+
+	// Credential embedded in URL
+	server = NewManifestServer(strings.Replace(ts.URL, "://", "://user:secret@", 1), "", "")
+	_, err = server.GetManifest("v1.0")
+	assert.Nil(err)
+}
+
+func TestParseManifestServerResponse(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := parseManifestServerResponse([]byte(`<methodResponse><fault><value><struct>
+<member><name>faultCode</name><value><int>4</int></value></member>
+<member><name>faultString</name><value>Too many parameters</value></member>
+</struct></value></fault></methodResponse>`))
+	assert.Equal("manifest server fault: Too many parameters (code: 4)", err.Error())
+
+	m, err := parseManifestServerResponse([]byte(`<methodResponse><params><param><value><array><data>
+<value><boolean>1</boolean></value><value>&lt;manifest/&gt;</value>
+</data></array></value></param></params></methodResponse>`))
+	assert.Nil(err)
+	assert.Equal("<manifest/>", m)
+
+	_, err = parseManifestServerResponse([]byte(`<methodResponse><params><param><value>
+<string>bad</string></value></param></params></methodResponse>`))
+	assert.NotNil(err)
+}
@@ -122,10 +122,15 @@ func (v AGitProtoHelper) GetGitPushCommand(o *config.UploadOptions) (*GitPushCom
 			destBranch = strings.TrimPrefix(destBranch, config.RefsHeads)
 		}
 
+		// Reviews of a change set share the same topic as session name.
+		session := localBranch
+		if o.Topic != "" {
+			session = o.Topic
+		}
 		refSpec += fmt.Sprintf(":refs/%s/%s/%s",
 			uploadType,
 			destBranch,
-			localBranch)
+			session)
 	}
 
 	if gitCanPushOptions {
@@ -208,3 +213,9 @@ func (v AGitProtoHelper) GetDownloadRef(id, patch string) (string, error) {
 	}
 	return v.sshInfo.GetReviewRef(id, patch)
 }
+
+// ParseReviewInfo parses messages of AGit-Flow server in output of git
+// push, and returns code review.
+func (v AGitProtoHelper) ParseReviewInfo(output string) *ReviewInfo {
+	return parseReviewInfo(output)
+}
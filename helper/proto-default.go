@@ -50,3 +50,8 @@ func (v DefaultProtoHelper) GetGitPushCommand(o *config.UploadOptions) (*GitPush
 func (v DefaultProtoHelper) GetDownloadRef(cr, patch string) (string, error) {
 	return "", errors.New("not implement")
 }
+
+// ParseReviewInfo parses output of git push, and returns code review.
+func (v DefaultProtoHelper) ParseReviewInfo(output string) *ReviewInfo {
+	return parseReviewInfo(output)
+}
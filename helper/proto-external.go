@@ -118,3 +118,8 @@ func (v ExternalProtoHelper) GetDownloadRef(cr, patch string) (string, error) {
 	}
 	return strings.TrimSpace(string(out)), err
 }
+
+// ParseReviewInfo parses output of git push, and returns code review.
+func (v ExternalProtoHelper) ParseReviewInfo(output string) *ReviewInfo {
+	return parseReviewInfo(output)
+}
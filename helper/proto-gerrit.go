@@ -106,7 +106,8 @@ func (v GerritProtoHelper) GetGitPushCommand(o *config.UploadOptions) (*GitPushC
 		uploadType,
 		destBranch)
 
-	if o.AutoTopic && localBranch != "" {
+	// Topic set by option replaces topic of local branch name.
+	if o.AutoTopic && localBranch != "" && o.Topic == "" {
 		refSpec = refSpec + "/" + localBranch
 	}
 
@@ -130,6 +131,9 @@ func (v GerritProtoHelper) GetGitPushCommand(o *config.UploadOptions) (*GitPushC
 	if o.WIP {
 		opts = append(opts, "wip")
 	}
+	if o.Topic != "" {
+		opts = append(opts, "topic="+o.Topic)
+	}
 	if len(opts) > 0 {
 		refSpec = refSpec + "%" + strings.Join(opts, ",")
 	}
@@ -167,3 +171,9 @@ func (v GerritProtoHelper) GetDownloadRef(cr, patch string) (string, error) {
 	}
 	return v.sshInfo.GetReviewRef(cr, patch)
 }
+
+// ParseReviewInfo parses messages of Gerrit in output of git push, and
+// returns change of the branch.
+func (v GerritProtoHelper) ParseReviewInfo(output string) *ReviewInfo {
+	return parseGerritReviewInfo(output)
+}
@@ -42,6 +42,7 @@ type ProtoHelper interface {
 	GetSSHInfo() *SSHInfo
 	GetGitPushCommand(*config.UploadOptions) (*GitPushCommand, error)
 	GetDownloadRef(string, string) (string, error)
+	ParseReviewInfo(string) *ReviewInfo
 }
 
 // NewProtoHelper returns proto helper for specific proto type.
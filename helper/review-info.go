@@ -0,0 +1,136 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helper
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	reReviewURL   = regexp.MustCompile(`https?://[^\s|<>"]+`)
+	reURLID       = regexp.MustCompile(`/([0-9]+)/?$`)
+	reAGitBoxLine = regexp.MustCompile(`^\+-+\+$`)
+	reAGitBoxRow  = regexp.MustCompile(`^\|(.*)\|$`)
+	reAGitReview  = regexp.MustCompile(`^Merge request #([0-9]+) was (?:created|updated|created or updated)\.?$`)
+)
+
+// ReviewInfo is code review created or updated by git push, which is
+// parsed from messages of server.
+type ReviewInfo struct {
+	ID  string `json:"id,omitempty"`
+	URL string `json:"url,omitempty"`
+}
+
+// remoteMessages returns messages sent by server in output of git push,
+// which have prefix "remote:".
+func remoteMessages(output string) []string {
+	result := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if !strings.HasPrefix(line, "remote:") {
+			continue
+		}
+		result = append(result, strings.TrimSpace(strings.TrimPrefix(line, "remote:")))
+	}
+	return result
+}
+
+// reviewIDFromURL returns the last number in path of URL, such as
+// "https://example.com/c/project/+/123".
+func reviewIDFromURL(url string) string {
+	m := reURLID.FindStringSubmatch(url)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// parseReviewInfo reads code review in the box of messages of AGit-Flow
+// server, such as:
+//
+//	remote: +------------------------------------------------------+
+//	remote: | Merge request #123 was created or updated.           |
+//	remote: | View merge request at URL:                           |
+//	remote: | https://example.com/project/merge_requests/123       |
+//	remote: +------------------------------------------------------+
+//
+// Messages out of the box (such as banners of server) are ignored, and a
+// box without the "Merge request #<id>" line is not a code review.
+func parseReviewInfo(output string) *ReviewInfo {
+	var (
+		info  ReviewInfo
+		inBox bool
+	)
+
+	for _, line := range remoteMessages(output) {
+		if reAGitBoxLine.MatchString(line) {
+			if inBox && info.ID != "" {
+				return &info
+			}
+			inBox = !inBox
+			info = ReviewInfo{}
+			continue
+		}
+		if !inBox {
+			continue
+		}
+		m := reAGitBoxRow.FindStringSubmatch(line)
+		if m == nil {
+			inBox = false
+			continue
+		}
+		row := strings.TrimSpace(m[1])
+		if m := reAGitReview.FindStringSubmatch(row); m != nil {
+			info.ID = m[1]
+		} else if info.ID != "" && info.URL == "" && reReviewURL.FindString(row) == row {
+			info.URL = row
+		}
+	}
+	return nil
+}
+
+// parseGerritReviewInfo reads changes in "New Changes:" or "Updated
+// Changes:" block in messages of Gerrit, and returns the last change,
+// which is the change of the top commit of the branch:
+//
+//	remote: New Changes:
+//	remote:   https://example.com/c/project/+/123 subject
+func parseGerritReviewInfo(output string) *ReviewInfo {
+	var (
+		info    *ReviewInfo
+		inBlock bool
+	)
+
+	for _, line := range remoteMessages(output) {
+		if line == "New Changes:" || line == "Updated Changes:" {
+			inBlock = true
+			continue
+		}
+		if !inBlock {
+			continue
+		}
+		url := reReviewURL.FindString(line)
+		if url == "" || !strings.HasPrefix(line, url) {
+			inBlock = false
+			continue
+		}
+		info = &ReviewInfo{
+			ID:  reviewIDFromURL(url),
+			URL: url,
+		}
+	}
+	return info
+}
@@ -0,0 +1,99 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReviewInfo(t *testing.T) {
+	assert := assert.New(t)
+
+	output := `Enumerating objects: 3, done.
+remote: +------------------------------------------------------------------------+
+remote: | Merge request #123 was created or updated.                             |
+remote: | View merge request at URL:                                             |
+remote: | https://example.com/jiangxin/main/merge_requests/123                   |
+remote: +------------------------------------------------------------------------+
+To ssh://example.com/jiangxin/main.git
+ * [new branch]      my/topic -> refs/for/master/my/topic
+`
+	info := parseReviewInfo(output)
+	if assert.NotNil(info) {
+		assert.Equal("123", info.ID)
+		assert.Equal("https://example.com/jiangxin/main/merge_requests/123", info.URL)
+	}
+
+	output = "remote: +-------------------------------------------------+\r\n" +
+		"remote: | Merge request #456 was created.                 |\r\n" +
+		"remote: | https://example.com/review/456/                 |\r\n" +
+		"remote: +-------------------------------------------------+\r\n"
+	info = parseReviewInfo(output)
+	if assert.NotNil(info) {
+		assert.Equal("456", info.ID)
+		assert.Equal("https://example.com/review/456/", info.URL)
+	}
+
+	// Banners of server are not code reviews.
+	output = `remote: +--------------------------------------------------+
+remote: | Welcome, see release notes at:                   |
+remote: | https://example.com/news/2024                    |
+remote: +--------------------------------------------------+
+remote: Review: https://example.com/review/789
+remote: Merge request #77: https://example.com/jiangxin/main/merge_requests/77
+remote: +--------------------------------------------------+
+remote: | Merge request #88 was updated.                   |
+remote: | https://example.com/jiangxin/main/merge_requests |
+remote: +--------------------------------------------------+
+`
+	info = parseReviewInfo(output)
+	if assert.NotNil(info) {
+		assert.Equal("88", info.ID)
+		assert.Equal("https://example.com/jiangxin/main/merge_requests", info.URL)
+	}
+
+	output = `remote: +--------------------------------------------------+
+remote: | Please fix issue #99 before merge.               |
+remote: | https://example.com/issues/99                    |
+remote: +--------------------------------------------------+
+To ssh://example.com/jiangxin/main.git
+ * [new branch]      my/topic -> refs/for/master/my/topic
+`
+	assert.Nil(parseReviewInfo(output))
+}
+
+func TestParseGerritReviewInfo(t *testing.T) {
+	assert := assert.New(t)
+
+	output := `remote: Processing changes: new: 2, done
+remote:
+remote: New Changes:
+remote:   https://example.com/c/main/+/1001 topic: first commit
+remote:   https://example.com/c/main/+/1002 topic: second commit [WIP]
+remote:
+To ssh://example.com:29418/main.git
+ * [new branch]      my/topic -> refs/for/master
+`
+	info := parseGerritReviewInfo(output)
+	if assert.NotNil(info) {
+		assert.Equal("1002", info.ID)
+		assert.Equal("https://example.com/c/main/+/1002", info.URL)
+	}
+
+	output = `remote: Processing changes: updated: 1, done
+remote:
+remote: Updated Changes:
+remote:   https://example.com/12345 topic: first commit
+remote:
+`
+	info = parseGerritReviewInfo(output)
+	if assert.NotNil(info) {
+		assert.Equal("12345", info.ID)
+		assert.Equal("https://example.com/12345", info.URL)
+	}
+
+	output = `remote: Processing changes: refs: 1, done
+remote: https://example.com/c/main/+/1001 is not a change of this push
+`
+	assert.Nil(parseGerritReviewInfo(output))
+}
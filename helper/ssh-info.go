@@ -4,11 +4,9 @@ import (
 	"bufio"
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net"
 	"net/http"
 	"os"
 	"os/exec"
@@ -397,32 +395,7 @@ func getHTTPClient() *http.Client {
 		return httpClient
 	}
 
-	skipSSLVerify := config.NoCertChecks()
-
-	tr := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   remoteCallTimeout * time.Second,
-			KeepAlive: remoteCallTimeout * time.Second,
-		}).DialContext,
-		TLSClientConfig:       &tls.Config{InsecureSkipVerify: skipSSLVerify},
-		TLSHandshakeTimeout:   remoteCallTimeout * time.Second,
-		ResponseHeaderTimeout: remoteCallTimeout * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-		MaxIdleConns:          10,
-		IdleConnTimeout:       remoteCallTimeout * time.Second,
-		DisableCompression:    true,
-		Proxy:                 http.ProxyFromEnvironment,
-	}
-
-	// http.proxy overrides env $HTTP_PROXY, $HTTPS_PROXY and $NO_PROXY (or the lowercase versions thereof).
-	proxyURL, err := GetProxyFromGitConfig()
-	if err != nil {
-		log.Debugf("fail to get proxy from git config: %s", err)
-	} else {
-		tr.Proxy = http.ProxyURL(proxyURL)
-	}
-
-	httpClient = &http.Client{Transport: tr}
+	httpClient = NewHTTPClient(remoteCallTimeout * time.Second)
 
 	// Mock ssh_info API
 	if config.GetMockSSHInfoResponse() != "" || config.GetMockSSHInfoStatus() != 0 {
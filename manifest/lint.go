@@ -0,0 +1,236 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+)
+
+// Problem is a problem found in manifest file.
+type Problem struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (v Problem) String() string {
+	if v.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", v.File, v.Line, v.Message)
+	}
+	return fmt.Sprintf("%s: %s", v.File, v.Message)
+}
+
+// Error implements error interface, so that problem breaks loading of
+// manifest is returned as error.
+func (v Problem) Error() string {
+	return v.String()
+}
+
+// Known elements, indexed by name of parent element.
+var knownElements = map[string][]string{
+	"manifest": {
+		"notice",
+		"remote",
+		"default",
+		"manifest-server",
+		"remove-project",
+		"project",
+		"extend-project",
+		"repo-hooks",
+		"superproject",
+		"contactinfo",
+		"submanifest",
+		"include",
+	},
+	"project": {
+		"annotation",
+		"project",
+		"copyfile",
+		"linkfile",
+	},
+}
+
+// badPath returns why name is not a relative path inside top directory,
+// or returns empty string for a valid path.
+func badPath(name string) string {
+	if name == "" {
+		return "is empty"
+	}
+	name = filepath.ToSlash(name)
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") || strings.HasPrefix(name, "~") {
+		return "is absolute"
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "contains '..'"
+		}
+		if strings.EqualFold(part, ".git") || strings.EqualFold(part, config.DotRepo) {
+			return fmt.Sprintf("points into '%s'", part)
+		}
+	}
+	return ""
+}
+
+// checkElements checks attributes of elements in manifest file m, which
+// are not checked when loading manifest.
+func (v *loader) checkElements(m *Manifest) {
+	var walk func(projects []Project, parent *Project, prefix string)
+
+	for i, r := range m.Remotes {
+		if r.Name != "" && r.Fetch == "" {
+			v.warn(m.where(fmt.Sprintf("remote[%d]", i)),
+				"remote '%s' has no fetch attribute", r.Name)
+		}
+	}
+
+	// Paths and names of nested projects are joined with their parents.
+	walk = func(projects []Project, parent *Project, prefix string) {
+		for i, p := range projects {
+			key := fmt.Sprintf("%sproject[%d]", prefix, i)
+			pos := m.where(key)
+			if parent != nil {
+				if parent.Path != "" {
+					p.Path = filepath.ToSlash(filepath.Join(parent.Path, p.Path))
+				}
+				if parent.Name != "" && p.Name != "" {
+					p.Name = filepath.ToSlash(filepath.Join(parent.Name, p.Name))
+				}
+			}
+			walk(p.Projects, &p, key+"/")
+			// Project without name is reported by merge.
+			if p.Name == "" {
+				continue
+			}
+
+			what := fmt.Sprintf("project '%s'", p.Name)
+			// Path is optional, and name is used if not supplied.
+			projectPath := p.Path
+			if projectPath == "" {
+				projectPath = p.Name
+			}
+			if reason := badPath(projectPath); reason != "" {
+				v.warn(pos, "path '%s' of %s %s", projectPath, what, reason)
+			}
+			if p.CloneDepth != "" {
+				// Same as Repository.ShallowDepth, 0 means full clone.
+				if depth, err := strconv.Atoi(p.CloneDepth); err != nil || depth < 0 {
+					v.warn(pos, "invalid clone-depth '%s' for %s", p.CloneDepth, what)
+				}
+			}
+			for j, a := range p.Annotations {
+				if a.Name == "" {
+					v.warn(m.where(fmt.Sprintf("%s/annotation[%d]", key, j)),
+						"annotation of %s has no name", what)
+				}
+			}
+			for j, c := range p.CopyFiles {
+				pos := m.where(fmt.Sprintf("%s/copyfile[%d]", key, j))
+				if reason := badPath(c.Src); reason != "" {
+					v.warn(pos, "src '%s' of copyfile in %s %s", c.Src, what, reason)
+				}
+				if reason := badPath(c.Dest); reason != "" {
+					v.warn(pos, "dest '%s' of copyfile in %s %s", c.Dest, what, reason)
+				}
+			}
+			for j, c := range p.LinkFiles {
+				pos := m.where(fmt.Sprintf("%s/linkfile[%d]", key, j))
+				if reason := badPath(c.Src); reason != "" {
+					v.warn(pos, "src '%s' of linkfile in %s %s", c.Src, what, reason)
+				}
+				if reason := badPath(c.Dest); reason != "" {
+					v.warn(pos, "dest '%s' of linkfile in %s %s", c.Dest, what, reason)
+				}
+			}
+		}
+	}
+	walk(m.Projects, nil, "")
+
+	if m.Superproject != nil && m.Superproject.Name == "" {
+		v.warn(m.where("superproject[0]"), "superproject element has no name")
+	}
+	if m.ContactInfo != nil && m.ContactInfo.BugURL == "" {
+		v.warn(m.where("contactinfo[0]"), "contactinfo element has no bugurl")
+	}
+	for i, sm := range m.Submanifests {
+		pos := m.where(fmt.Sprintf("submanifest[%d]", i))
+		if sm.Name == "" {
+			v.warn(pos, "submanifest element has no name")
+		} else if reason := badPath(sm.RelPath()); reason != "" {
+			v.warn(pos, "path '%s' of submanifest '%s' %s", sm.RelPath(), sm.Name, reason)
+		}
+	}
+}
+
+// Lint loads manifest file the same way as Load and LoadFile, and returns
+// all problems found, instead of stopping at the first one. If file is
+// empty, the manifest file used in repoDir is checked with local manifests.
+// Submanifests checked out in repoDir are checked too, and if repoDir is
+// empty, only the file and manifests it includes are checked.
+func Lint(repoDir, file string) ([]Problem, error) {
+	var (
+		err       error
+		index     = make(map[string]int)
+		l         = loader{lint: true}
+		withLocal = file == ""
+	)
+
+	if file == "" {
+		file, err = manifestFile(repoDir)
+		if err != nil {
+			return nil, err
+		}
+	} else if !filepath.IsAbs(file) && repoDir != "" {
+		file = filepath.Join(repoDir, config.Manifests, file)
+	}
+	if _, err = os.Stat(file); err != nil {
+		return nil, err
+	}
+
+	m, _ := l.loadFile(repoDir, file, withLocal)
+	if m != nil {
+		l.allProjects(m)
+		if m.Default != nil && m.Default.RemoteName != "" && m.GetRemote(m.Default.RemoteName) == nil {
+			l.warn(m.defined["default"], "unknown remote '%s' in default element",
+				m.Default.RemoteName)
+		}
+		if m.Superproject != nil && m.Superproject.Name != "" {
+			pos := m.defined["superproject"]
+			if name := m.Superproject.Remote; name != "" {
+				if m.GetRemote(name) == nil {
+					l.warn(pos, "unknown remote '%s' for superproject '%s'", name, m.Superproject.Name)
+				}
+			} else if m.Default == nil || m.Default.RemoteName == "" {
+				l.warn(pos, "no remote for superproject '%s'", m.Superproject.Name)
+			}
+		}
+		// Remotes of submanifests are checked when they are loaded.
+		if repoDir == "" {
+			for _, sm := range m.Submanifests {
+				if m.GetRemote(sm.Remote) == nil {
+					l.warn(m.defined["submanifest:"+sm.RelPath()],
+						"unknown remote '%s' for submanifest '%s'", sm.Remote, sm.Name)
+				}
+			}
+		}
+	}
+
+	// Sort problems by order of files and line numbers.
+	for _, p := range l.problems {
+		if _, ok := index[p.File]; !ok {
+			index[p.File] = len(index)
+		}
+	}
+	sort.SliceStable(l.problems, func(i, j int) bool {
+		pi, pj := l.problems[i], l.problems[j]
+		if index[pi.File] != index[pj.File] {
+			return index[pi.File] < index[pj.File]
+		}
+		return pi.Line < pj.Line
+	})
+	return l.problems, nil
+}
@@ -0,0 +1,225 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/jiangxin/multi-log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(dir string) {
+		os.RemoveAll(dir)
+	}(tmpdir)
+
+	repoDir := filepath.Join(tmpdir, ".repo")
+	writeFile := func(name, data string) {
+		name = filepath.Join(repoDir, name)
+		assert.Nil(os.MkdirAll(filepath.Dir(name), 0755))
+		assert.Nil(ioutil.WriteFile(name, []byte(data), 0644))
+	}
+
+	writeFile("manifests/default.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<manifest>
+  <remote name="origin" fetch=".." />
+  <default remote="origin" revision="master" />
+  <project name="main" path="main">
+    <copyfile src="VERSION" dest="VERSION" />
+    <linkfile src="Makefile" dest="Makefile" />
+    <project name="module" path="module" />
+  </project>
+  <include name="extra.xml" />
+</manifest>`)
+	writeFile("manifests/extra.xml", `<manifest>
+  <project name="tools" clone-depth="0" />
+</manifest>`)
+
+	problems, err := Lint(repoDir, "")
+	assert.Nil(err)
+	assert.Equal(0, len(problems))
+
+	// Removed project is defined again in another local manifest.
+	writeFile("local_manifests/local.xml", `<manifest>
+  <remove-project name="tools" />
+</manifest>`)
+	writeFile("local_manifests/tools.xml", `<manifest>
+  <project name="tools" path="tools" revision="develop" />
+</manifest>`)
+	problems, err = Lint(repoDir, "")
+	assert.Nil(err)
+	assert.Equal(0, len(problems))
+	assert.Nil(os.Remove(filepath.Join(repoDir, "local_manifests", "tools.xml")))
+
+	// Project is removed and defined again in the same file, which
+	// cannot be loaded.
+	writeFile("local_manifests/local.xml", `<manifest>
+  <remove-project name="tools" />
+  <project name="tools" path="tools" revision="develop" />
+</manifest>`)
+	problems, err = Lint(repoDir, "")
+	assert.Nil(err)
+	assert.Equal(1, len(problems))
+	assert.Equal(3, problems[0].Line)
+	assert.Equal("duplicate path 'tools' for project 'tools', first defined in "+
+		filepath.Join(repoDir, "manifests", "extra.xml")+":2",
+		problems[0].Message)
+	_, err = Load(repoDir)
+	assert.Equal(problems[0].String(), err.Error())
+
+	writeFile("local_manifests/local.xml", `<manifest>
+  <remote name="origin"
+          fetch="../other" />
+  <project name="app"
+           path="main/module" remote="unknown">
+    <copyfile src="../VERSION" dest="VERSION" />
+    <linkfile src="Makefile" dest="/Makefile" />
+    <annotation value="no name" />
+  </project>
+  <project path="no-name" />
+  <extend-project name="main" groups="app" />
+  <remove-project name="missing" />
+  <repo-hooks in-project="tools" enabled-list="pre-upload" />
+  <superproject name="platform/superproject" remote="gerrit" />
+  <unknown />
+</manifest>`)
+
+	problems, err = Lint(repoDir, "")
+	assert.Nil(err)
+	result := []string{}
+	for _, p := range problems {
+		rel, _ := filepath.Rel(repoDir, p.File)
+		p.File = rel
+		result = append(result, p.String())
+	}
+	assert.Equal([]string{
+		"local_manifests/local.xml:2: duplicate remote 'origin', first defined in " +
+			filepath.Join(repoDir, "manifests", "default.xml") + ":3. " +
+			"If you want to override, set atrribute 'override' true",
+		"local_manifests/local.xml:4: duplicate path 'main/module' for project 'app', first defined in " +
+			filepath.Join(repoDir, "manifests", "default.xml") + ":8",
+		"local_manifests/local.xml:6: src '../VERSION' of copyfile in project 'app' contains '..'",
+		"local_manifests/local.xml:7: dest '/Makefile' of linkfile in project 'app' is absolute",
+		"local_manifests/local.xml:8: annotation of project 'app' has no name",
+		"local_manifests/local.xml:10: project element has no name",
+		"local_manifests/local.xml:12: remove-project names unknown project 'missing'",
+		"local_manifests/local.xml:14: unknown remote 'gerrit' for superproject 'platform/superproject'",
+		"local_manifests/local.xml:15: unknown element <unknown> in <manifest>",
+	}, result)
+
+	// Bad XML.
+	writeFile("local_manifests/local.xml", `<manifest>
+  <project name="app" path="app">
+</manifest>`)
+	problems, err = Lint(repoDir, "")
+	assert.Nil(err)
+	assert.Equal(1, len(problems))
+	assert.Equal(3, problems[0].Line)
+
+	// Bad clone-depth, and bad path from name.
+	writeFile("local_manifests/local.xml", `<manifest>
+  <project name="app" clone-depth="-1" />
+  <project name="../app" />
+</manifest>`)
+	problems, err = Lint(repoDir, "")
+	assert.Nil(err)
+	result = []string{}
+	for _, p := range problems {
+		result = append(result, p.Message)
+	}
+	assert.Equal([]string{
+		"invalid clone-depth '-1' for project 'app'",
+		"path '../app' of project '../app' contains '..'",
+	}, result)
+
+	// Lint file without repoDir.
+	problems, err = Lint("", filepath.Join(repoDir, "manifests", "default.xml"))
+	assert.Nil(err)
+	assert.Equal(0, len(problems))
+	_, err = Lint("", filepath.Join(repoDir, "manifests", "missing.xml"))
+	assert.NotNil(err)
+}
+
+func TestLintWithSubmanifests(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(dir string) {
+		os.RemoveAll(dir)
+	}(tmpdir)
+
+	repoDir := filepath.Join(tmpdir, ".repo")
+	writeFile := func(name, data string) {
+		name = filepath.Join(repoDir, name)
+		assert.Nil(os.MkdirAll(filepath.Dir(name), 0755))
+		assert.Nil(ioutil.WriteFile(name, []byte(data), 0644))
+	}
+
+	writeFile("manifests/default.xml", `<manifest>
+  <remote name="origin" fetch=".." />
+  <default remote="origin" revision="master" />
+  <project name="main" />
+  <submanifest name="team/manifests" path="team" />
+</manifest>`)
+	writeFile("submanifests/team/manifests/default.xml", `<manifest>
+  <remote name="origin" fetch=".." />
+  <default remote="origin" revision="master" />
+  <project name="app" />
+  <project name="lib" path="../lib" remote="unknown" />
+</manifest>`)
+
+	problems, err := Lint(repoDir, "")
+	assert.Nil(err)
+	result := []string{}
+	for _, p := range problems {
+		rel, _ := filepath.Rel(repoDir, p.File)
+		p.File = rel
+		result = append(result, p.String())
+	}
+	assert.Equal([]string{
+		"submanifests/team/manifests/default.xml:5: path '../lib' of project 'lib' contains '..'",
+		"submanifests/team/manifests/default.xml:5: unknown remote 'unknown' for project 'lib'",
+	}, result)
+
+	// Project of submanifest has the same path of project in manifest,
+	// such as a manifest exported with projects of submanifests.
+	writeFile("submanifests/team/manifests/default.xml", `<manifest>
+  <remote name="origin" fetch=".." />
+  <default remote="origin" revision="master" />
+  <project name="app" />
+</manifest>`)
+	writeFile("manifests/exported.xml", `<manifest>
+  <remote name="origin" fetch=".." />
+  <default remote="origin" revision="master" />
+  <project name="main" />
+  <project name="team/app" path="team/app" />
+  <submanifest name="team/manifests" path="team" />
+</manifest>`)
+	problems, err = Lint(repoDir, "exported.xml")
+	assert.Nil(err)
+	assert.Equal(1, len(problems))
+	assert.Equal(filepath.Join(repoDir, "submanifests", "team", "manifests", "default.xml"),
+		problems[0].File)
+	assert.Equal(4, problems[0].Line)
+	assert.Equal("duplicate path 'team/app' for project 'app' in submanifest 'team', first defined in "+
+		filepath.Join(repoDir, "manifests", "exported.xml")+":5",
+		problems[0].Message)
+	_, err = LoadFile(repoDir, "exported.xml")
+	assert.Equal(problems[0].String(), err.Error())
+
+	// Lint file without repoDir, and submanifests are not loaded.
+	problems, err = Lint("", filepath.Join(repoDir, "manifests", "exported.xml"))
+	assert.Nil(err)
+	assert.Equal(0, len(problems))
+}
@@ -16,14 +16,17 @@
 package manifest
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
 
+	"github.com/alibaba/git-repo-go/common"
 	"github.com/alibaba/git-repo-go/config"
 	"github.com/alibaba/git-repo-go/path"
 	"github.com/jiangxin/goconfig"
@@ -46,8 +49,32 @@ type Manifest struct {
 	RemoveProjects []RemoveProject `xml:"remove-project,omitempty"`
 	ExtendProjects []ExtendProject `xml:"extend-project,omitempty"`
 	RepoHooks      *RepoHooks      `xml:"repo-hooks,omitempty"`
+	Superproject   *Superproject   `xml:"superproject,omitempty"`
+	ContactInfo    *ContactInfo    `xml:"contactinfo,omitempty"`
+	Submanifests   []Submanifest   `xml:"submanifest,omitempty"`
 	Includes       []Include       `xml:"include,omitempty"`
 	SourceFile     string          `xml:"-"`
+
+	// lines are line numbers of elements in SourceFile, indexed by keys
+	// such as "project[0]/copyfile[1]".
+	lines map[string]int
+	// defined are positions of elements merged into manifest, indexed by
+	// keys such as "remote:origin", to show where an element is first
+	// defined.
+	defined map[string]position
+}
+
+// position is where an element is defined in manifest files.
+type position struct {
+	file string
+	line int
+}
+
+func (v position) String() string {
+	if v.line > 0 {
+		return fmt.Sprintf("%s:%d", v.file, v.line)
+	}
+	return v.file
 }
 
 // Remote is for remote XML element.
@@ -104,6 +131,8 @@ type Project struct {
 
 	isMetaProject  bool    `xml:"-"`
 	ManifestRemote *Remote `xml:"-"`
+	// Submanifest is path of submanifest which project is lifted from.
+	Submanifest string `xml:"-"`
 }
 
 // Annotation is for annotation XML element.
@@ -144,17 +173,81 @@ type RepoHooks struct {
 	EnabledList string `xml:"enabled-list,attr,omitempty"`
 }
 
+// Superproject is for superproject XML element.
+type Superproject struct {
+	Name     string `xml:"name,attr,omitempty"`
+	Remote   string `xml:"remote,attr,omitempty"`
+	Revision string `xml:"revision,attr,omitempty"`
+}
+
+// ContactInfo is for contactinfo XML element.
+type ContactInfo struct {
+	BugURL string `xml:"bugurl,attr,omitempty"`
+}
+
+// Submanifest is for submanifest XML element.
+type Submanifest struct {
+	Name         string `xml:"name,attr,omitempty"`
+	Remote       string `xml:"remote,attr,omitempty"`
+	Project      string `xml:"project,attr,omitempty"`
+	ManifestName string `xml:"manifest-name,attr,omitempty"`
+	Revision     string `xml:"revision,attr,omitempty"`
+	Path         string `xml:"path,attr,omitempty"`
+	Groups       string `xml:"groups,attr,omitempty"`
+
+	ManifestRemote *Remote `xml:"-"`
+	// Parent is path of submanifest which nested submanifest is lifted
+	// from.
+	Parent string `xml:"-"`
+}
+
 // Include is for include XML element.
 type Include struct {
 	Name string `xml:"name,attr,omitempty"`
 }
 
+// RelPath returns path of submanifest, which is the prefix of paths of
+// projects in submanifest.
+func (v Submanifest) RelPath() string {
+	if v.Path != "" {
+		return cleanPath(v.Path)
+	}
+	return cleanPath(v.Name)
+}
+
+// ProjectName returns name of manifest project of submanifest.
+func (v Submanifest) ProjectName() string {
+	if v.Project != "" {
+		return cleanPath(v.Project)
+	}
+	return cleanPath(v.Name)
+}
+
+// ManifestFile returns name of manifest file in manifest project of
+// submanifest.
+func (v Submanifest) ManifestFile() string {
+	if v.ManifestName != "" {
+		return v.ManifestName
+	}
+	return config.DefaultXML
+}
+
+// RepoDir returns admin directory of submanifest inside repoDir, where
+// its manifest project is checked out in the "manifests" subdirectory.
+func (v Submanifest) RepoDir(repoDir string) string {
+	return filepath.Join(repoDir, config.Submanifests, v.RelPath())
+}
+
 // AllProjects returns all projects (include current project and all sub-projects)
 // of a project recursively.
 func (v *Project) AllProjects(parent *Project) []Project {
 	var project Project
 
 	projects := []Project{}
+	// Path is optional, and name is used if not supplied.
+	if v.Path == "" {
+		v.Path = strings.TrimSuffix(v.Name, ".git")
+	}
 	if parent != nil {
 		if parent.Path != "" {
 			v.Path = filepath.Join(parent.Path, v.Path)
@@ -251,169 +344,93 @@ func (v *Manifest) allProjects() []Project {
 	return projects
 }
 
-// AllProjects returns all projects and fill missing fields
-func (v *Manifest) AllProjects() []Project {
-	projects := v.allProjects()
-	remotes := make(map[string]*Remote)
-	for i := range v.Remotes {
-		remotes[v.Remotes[i].Name] = &v.Remotes[i]
-	}
-
-	for i := range projects {
-		if projects[i].RemoteName == "" {
-			if v.Default == nil || v.Default.RemoteName == "" {
-				log.Fatalf("no remote defined for for project '%s'",
-					projects[i].Name)
-			}
-			projects[i].RemoteName = v.Default.RemoteName
-		}
-		projects[i].ManifestRemote = remotes[projects[i].RemoteName]
-		if projects[i].ManifestRemote == nil {
-			log.Fatalf("cannot find remote '%s' for project '%s'",
-				projects[i].RemoteName,
-				projects[i].Name)
-		}
-
-		if projects[i].Revision == "" {
-			projects[i].Revision = projects[i].ManifestRemote.Revision
-		}
-
-		if v.Default != nil {
-			if projects[i].Revision == "" {
-				projects[i].Revision = v.Default.Revision
-			}
-			if projects[i].DestBranch == "" {
-				projects[i].DestBranch = v.Default.DestBranch
-			}
-			if projects[i].Upstream == "" {
-				projects[i].Upstream = v.Default.Upstream
-			}
-			if projects[i].SyncC == "" {
-				projects[i].SyncC = v.Default.SyncC
-			}
-			if projects[i].SyncS == "" {
-				projects[i].SyncS = v.Default.SyncS
-			}
-			if projects[i].SyncTags == "" {
-				projects[i].SyncTags = v.Default.SyncTags
-			}
+// projectKeys returns keys of project elements in the same order as
+// allProjects, such as "project[0]/project[1]".
+func (v *Manifest) projectKeys() []string {
+	var walk func(projects []Project, prefix string) []string
+
+	walk = func(projects []Project, prefix string) []string {
+		keys := []string{}
+		for i, p := range projects {
+			key := fmt.Sprintf("%sproject[%d]", prefix, i)
+			keys = append(keys, key)
+			keys = append(keys, walk(p.Projects, key+"/")...)
 		}
+		return keys
+	}
+	return walk(v.Projects, "")
+}
 
-		if projects[i].Revision == "" {
-			log.Fatalf("no revision for project '%s'", projects[i].Name)
-		}
+// AllProjects returns all projects and fill missing fields
+func (v *Manifest) AllProjects() []Project {
+	projects, err := (&loader{}).allProjects(v)
+	if err != nil {
+		log.Fatal(err)
 	}
 	return projects
 }
 
-// Merge implements merging another manifest to self.
-func (v *Manifest) Merge(m *Manifest) error {
-	if m.Notice != "" {
-		if v.Notice == "" {
-			v.Notice = m.Notice
-		} else {
-			return fmt.Errorf("duplicate notice in %s", m.SourceFile)
-		}
+// GetRemote returns remote of the given name, and returns the default
+// remote if name is empty.
+func (v *Manifest) GetRemote(name string) *Remote {
+	if name == "" && v.Default != nil {
+		name = v.Default.RemoteName
 	}
-
-	for _, r1 := range m.Remotes {
-		found := false
-		for idx, r2 := range v.Remotes {
-			if r1.Name == r2.Name {
-				if r1.Override {
-					v.Remotes[idx] = r1
-				} else if !reflect.DeepEqual(r1, r2) {
-					return fmt.Errorf("duplicate remote in %s. If you want to override, set atrribute 'override' true",
-						m.SourceFile)
-				}
-				found = true
-				break
-			}
-		}
-		if !found {
-			v.Remotes = append(v.Remotes, r1)
+	for i := range v.Remotes {
+		if v.Remotes[i].Name == name {
+			return &v.Remotes[i]
 		}
 	}
+	return nil
+}
 
-	if m.Default != nil {
-		if m.Default.Override || v.Default == nil {
-			v.Default = m.Default
-		} else if !reflect.DeepEqual(v.Default, m.Default) {
-			return fmt.Errorf("duplicate default in %s. If you want to override, set atrribute 'override' true", m.SourceFile)
-		}
+// SuperprojectRevision returns revision of superproject, which falls back
+// to revision of its remote and the default revision.
+func (v *Manifest) SuperprojectRevision() string {
+	if v.Superproject == nil {
+		return ""
 	}
-
-	if m.Server != nil {
-		if m.Server.Override || v.Server == nil {
-			v.Server = m.Server
-		} else if !reflect.DeepEqual(v.Server, m.Server) {
-			return fmt.Errorf("duplicate manifest-server in %s. If you want to override, set atrribute 'override' true",
-				m.SourceFile)
-		}
+	if v.Superproject.Revision != "" {
+		return v.Superproject.Revision
 	}
-
-	realPath := make(map[string]bool)
-	for _, p := range v.allProjects() {
-		if realPath[p.Path] {
-			return fmt.Errorf("duplicate path for project '%s' in '%s'",
-				p.Path,
-				v.SourceFile)
-		}
-		realPath[p.Path] = true
+	if r := v.GetRemote(v.Superproject.Remote); r != nil && r.Revision != "" {
+		return r.Revision
 	}
-	for _, p := range m.allProjects() {
-		p.Name = cleanPath(p.Name)
-		p.Path = cleanPath(p.Path)
-		if realPath[p.Path] {
-			return fmt.Errorf("duplicate path for project '%s' in '%s'",
-				p.Path,
-				m.SourceFile)
-		}
-		v.Projects = append(v.Projects, p)
-		realPath[p.Path] = true
+	if v.Default != nil {
+		return v.Default.Revision
 	}
+	return ""
+}
 
-	rmPath := make(map[string]bool)
-	for _, r := range m.RemoveProjects {
-		r.Name = cleanPath(r.Name)
-		rmPath[r.Name] = true
-	}
-	ps := []Project{}
-	for _, p := range v.allProjects() {
-		if rmPath[p.Name] {
-			realPath[p.Path] = false
-		} else {
-			ps = append(ps, p)
-		}
+// where returns position of element in SourceFile.
+func (v *Manifest) where(key string) position {
+	return position{file: v.SourceFile, line: v.lines[key]}
+}
+
+// define records position of element merged into manifest.
+func (v *Manifest) define(key string, pos position) {
+	if v.defined == nil {
+		v.defined = make(map[string]position)
 	}
-	v.Projects = ps
+	v.defined[key] = pos
+}
 
-	extPath := make(map[string]ExtendProject)
-	for _, p := range m.ExtendProjects {
-		p.Name = cleanPath(p.Name)
-		extPath[p.Name] = p
+// definedAt returns where element is first defined, which is shown in
+// problem of element defined again in file.
+func (v *Manifest) definedAt(key, file string) string {
+	pos, ok := v.defined[key]
+	if !ok || pos.file == "" {
+		return ""
 	}
-	for i, p := range v.Projects {
-		if p2, ok := extPath[p.Name]; ok {
-			if p2.Path == p.Path {
-				if p.Groups == "" {
-					v.Projects[i].Groups = p2.Groups
-				} else if p2.Groups != "" {
-					groups := []string{}
-					groups = append(groups, strings.Split(p.Groups, ",")...)
-					groups = append(groups, strings.Split(p2.Groups, ",")...)
-					v.Projects[i].Groups = strings.Join(groups, ",")
-				}
-				if p2.Revision != "" {
-					v.Projects[i].Revision = p2.Revision
-				}
-			}
-		}
+	if pos.file == file && pos.line > 0 {
+		return fmt.Sprintf(", first defined at line %d", pos.line)
 	}
+	return ", first defined in " + pos.String()
+}
 
-	// m.RepoHooks
-
-	return nil
+// Merge implements merging another manifest to self.
+func (v *Manifest) Merge(m *Manifest) error {
+	return (&loader{}).merge(v, m)
 }
 
 // ProjectHandler is an interface to manipulate projects of manifest
@@ -463,55 +480,184 @@ func cleanPath(name string) string {
 	return filepath.Clean(strings.Replace(strings.TrimSuffix(name, ".git"), "\\", "/", -1))
 }
 
-func unmarshalFile(file string) (*Manifest, error) {
-	if _, err := os.Stat(file); err != nil {
-		return nil, err
+// loader loads manifest files. In lint mode, problems are collected and
+// loading goes on to find more problems, otherwise loading stops at the
+// first problem, and problems only reported for lint are ignored.
+type loader struct {
+	lint     bool
+	problems []Problem
+}
+
+// fail reports problem which breaks loading of manifest, and returns it as
+// error if not in lint mode.
+func (v *loader) fail(pos position, format string, args ...interface{}) error {
+	p := Problem{
+		File:    pos.file,
+		Line:    pos.line,
+		Message: fmt.Sprintf(format, args...),
+	}
+	if !v.lint {
+		return p
+	}
+	v.problems = append(v.problems, p)
+	return nil
+}
+
+// warn reports problem which does not break loading of manifest, such as
+// remove-project for unknown project, which is only reported by lint.
+func (v *loader) warn(pos position, format string, args ...interface{}) {
+	if v.lint {
+		v.problems = append(v.problems, Problem{
+			File:    pos.file,
+			Line:    pos.line,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+}
+
+// scan scans elements of XML data, and returns their line numbers. It
+// returns nil if data is not a valid manifest.
+func (v *loader) scan(file string, data []byte) (map[string]int, error) {
+	type frame struct {
+		name   string
+		key    string
+		counts map[string]int
+	}
+
+	lines := make(map[string]int)
+	stack := []*frame{}
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		offset := d.InputOffset()
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			if e, ok := err.(*xml.SyntaxError); ok {
+				return nil, v.fail(position{file: file, line: e.Line}, "%s", e.Msg)
+			}
+			return nil, v.fail(position{file: file}, "%s", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			line := bytes.Count(data[:offset], []byte("\n")) + 1
+			if len(stack) == 0 {
+				if name != "manifest" {
+					return nil, v.fail(position{file: file, line: line},
+						"root element is <%s>, not <manifest>", name)
+				}
+				stack = append(stack, &frame{
+					name:   name,
+					counts: make(map[string]int),
+				})
+				continue
+			}
+
+			parent := stack[len(stack)-1]
+			known := false
+			for _, n := range knownElements[parent.name] {
+				if n == name {
+					known = true
+					break
+				}
+			}
+			if !known {
+				v.warn(position{file: file, line: line},
+					"unknown element <%s> in <%s>", name, parent.name)
+			}
+
+			key := fmt.Sprintf("%s[%d]", name, parent.counts[name])
+			if parent.key != "" {
+				key = parent.key + "/" + key
+			}
+			parent.counts[name]++
+			lines[key] = line
+			stack = append(stack, &frame{
+				name:   name,
+				key:    key,
+				counts: make(map[string]int),
+			})
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
 	}
+	return lines, nil
+}
 
+// unmarshalFile parses manifest file, and records line numbers of its
+// elements. In lint mode, nil is returned for a broken file.
+func (v *loader) unmarshalFile(file string) (*Manifest, error) {
 	buf, err := ioutil.ReadFile(file)
 	if err != nil {
-		return nil, fmt.Errorf("cannot read manifest file '%s': %s", file, err)
+		return nil, v.fail(position{file: file}, "cannot read manifest file: %s", err)
+	}
+	lines, err := v.scan(file, buf)
+	if lines == nil {
+		return nil, err
 	}
 
-	ms, err := Unmarshal(buf)
+	m, err := Unmarshal(buf)
 	if err != nil {
-		return nil, fmt.Errorf("fail to parse manifest file '%s': %s", file, err)
+		return nil, v.fail(position{file: file}, "fail to parse manifest file: %s", err)
 	}
-
-	return ms, nil
+	m.SourceFile = file
+	m.lines = lines
+	if v.lint {
+		v.checkElements(m)
+	}
+	return m, nil
 }
 
-func parseXML(file string, depth int) ([]*Manifest, error) {
+func (v *loader) parseXML(file string, depth int) ([]*Manifest, error) {
 	ms := []*Manifest{}
 
-	m, err := unmarshalFile(file)
+	m, err := v.unmarshalFile(file)
 	if err != nil {
 		return ms, err
 	}
 	if m == nil {
 		return ms, nil
 	}
-	m.SourceFile = file
 	ms = append(ms, m)
 
-	for _, i := range m.Includes {
+	for idx, i := range m.Includes {
+		pos := m.where(fmt.Sprintf("include[%d]", idx))
+		if i.Name == "" {
+			if err = v.fail(pos, "include element has no name"); err != nil {
+				return ms, err
+			}
+			continue
+		}
 		f, err := path.AbsJoin(filepath.Dir(file), i.Name)
 		if err != nil {
-			return ms, err
+			if err = v.fail(pos, "bad include '%s': %s", i.Name, err); err != nil {
+				return ms, err
+			}
+			continue
 		}
 
 		if depth > maxRecursiveDepth {
-			return nil, fmt.Errorf("exceeded maximum include depth (%d) while including\n"+
-				"\t%s\n"+
-				"from"+
-				"\t%s\n"+
-				"This might be due to circular includes",
+			err = v.fail(pos, "exceeded maximum include depth (%d) while including '%s', "+
+				"this might be due to circular includes",
 				maxRecursiveDepth,
-				f,
-				file)
+				i.Name)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if _, err = os.Stat(f); err != nil {
+			if err = v.fail(pos, "cannot find included manifest '%s'", i.Name); err != nil {
+				return ms, err
+			}
+			continue
 		}
 
-		subMs, err := parseXML(f, depth+1)
+		subMs, err := v.parseXML(f, depth+1)
 		if err != nil {
 			return ms, err
 		}
@@ -521,10 +667,10 @@ func parseXML(file string, depth int) ([]*Manifest, error) {
 	return ms, nil
 }
 
-func mergeManifests(ms []*Manifest) (*Manifest, error) {
+func (v *loader) mergeManifests(ms []*Manifest) (*Manifest, error) {
 	manifest := &Manifest{}
 	for _, m := range ms {
-		err := manifest.Merge(m)
+		err := v.merge(manifest, m)
 		if err != nil {
 			return nil, err
 		}
@@ -532,20 +678,314 @@ func mergeManifests(ms []*Manifest) (*Manifest, error) {
 	return manifest, nil
 }
 
-// Load implements load and parse manifest XML file in repoDir.
-func Load(repoDir string) (*Manifest, error) {
-	var (
-		file string
-		err  error
-	)
+// merge merges manifest m into dst. Problems are reported at elements of m,
+// with where the same element is first defined in dst.
+func (v *loader) merge(dst, m *Manifest) error {
+	var err error
+
+	if m.Notice != "" {
+		if dst.Notice == "" {
+			dst.Notice = m.Notice
+			dst.define("notice", m.where("notice[0]"))
+		} else {
+			err = v.fail(m.where("notice[0]"), "duplicate notice%s",
+				dst.definedAt("notice", m.SourceFile))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, r1 := range m.Remotes {
+		pos := m.where(fmt.Sprintf("remote[%d]", i))
+		if r1.Name == "" {
+			if err = v.fail(pos, "remote element has no name"); err != nil {
+				return err
+			}
+			continue
+		}
+		key := "remote:" + r1.Name
+		found := false
+		for idx, r2 := range dst.Remotes {
+			if r1.Name == r2.Name {
+				if r1.Override {
+					dst.Remotes[idx] = r1
+					dst.define(key, pos)
+				} else if !reflect.DeepEqual(r1, r2) {
+					err = v.fail(pos, "duplicate remote '%s'%s. If you want to override, set atrribute 'override' true",
+						r1.Name,
+						dst.definedAt(key, m.SourceFile))
+					if err != nil {
+						return err
+					}
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			dst.Remotes = append(dst.Remotes, r1)
+			dst.define(key, pos)
+		}
+	}
+
+	if m.Default != nil {
+		pos := m.where("default[0]")
+		if m.Default.Override || dst.Default == nil {
+			dst.Default = m.Default
+			dst.define("default", pos)
+		} else if !reflect.DeepEqual(dst.Default, m.Default) {
+			err = v.fail(pos, "duplicate default%s. If you want to override, set atrribute 'override' true",
+				dst.definedAt("default", m.SourceFile))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if m.Server != nil {
+		pos := m.where("manifest-server[0]")
+		if m.Server.Override || dst.Server == nil {
+			dst.Server = m.Server
+			dst.define("manifest-server", pos)
+		} else if !reflect.DeepEqual(dst.Server, m.Server) {
+			err = v.fail(pos, "duplicate manifest-server%s. If you want to override, set atrribute 'override' true",
+				dst.definedAt("manifest-server", m.SourceFile))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if m.Superproject != nil {
+		pos := m.where("superproject[0]")
+		if dst.Superproject == nil {
+			dst.Superproject = m.Superproject
+			dst.define("superproject", pos)
+		} else if !reflect.DeepEqual(dst.Superproject, m.Superproject) {
+			err = v.fail(pos, "duplicate superproject%s",
+				dst.definedAt("superproject", m.SourceFile))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// The last contactinfo wins, so local manifests can override it.
+	if m.ContactInfo != nil {
+		dst.ContactInfo = m.ContactInfo
+	}
+
+	for i, sm := range m.Submanifests {
+		pos := m.where(fmt.Sprintf("submanifest[%d]", i))
+		key := "submanifest:" + sm.RelPath()
+		found := false
+		for _, sm2 := range dst.Submanifests {
+			if sm.RelPath() == sm2.RelPath() {
+				found = true
+				break
+			}
+		}
+		if found {
+			err = v.fail(pos, "duplicate path '%s' for submanifest '%s'%s",
+				sm.RelPath(),
+				sm.Name,
+				dst.definedAt(key, m.SourceFile))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		dst.Submanifests = append(dst.Submanifests, sm)
+		dst.define(key, pos)
+	}
+
+	realPath := make(map[string]bool)
+	for _, p := range dst.allProjects() {
+		realPath[p.Path] = true
+	}
+	keys := m.projectKeys()
+	for i, p := range m.allProjects() {
+		pos := m.where(keys[i])
+		if p.Name == "." {
+			if err = v.fail(pos, "project element has no name"); err != nil {
+				return err
+			}
+			continue
+		}
+		p.Name = cleanPath(p.Name)
+		p.Path = cleanPath(p.Path)
+		key := "project:" + p.Path
+		if realPath[p.Path] {
+			err = v.fail(pos, "duplicate path '%s' for project '%s'%s",
+				p.Path,
+				p.Name,
+				dst.definedAt(key, m.SourceFile))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		dst.Projects = append(dst.Projects, p)
+		dst.define(key, pos)
+		realPath[p.Path] = true
+	}
+
+	rmPath := make(map[string]bool)
+	for i, r := range m.RemoveProjects {
+		pos := m.where(fmt.Sprintf("remove-project[%d]", i))
+		if r.Name == "" {
+			v.warn(pos, "remove-project element has no name")
+			continue
+		}
+		r.Name = cleanPath(r.Name)
+		if !hasProject(dst, r.Name) {
+			v.warn(pos, "remove-project names unknown project '%s'", r.Name)
+		}
+		rmPath[r.Name] = true
+	}
+	ps := []Project{}
+	for _, p := range dst.allProjects() {
+		if !rmPath[p.Name] {
+			ps = append(ps, p)
+		}
+	}
+	dst.Projects = ps
+
+	extPath := make(map[string]ExtendProject)
+	for i, p := range m.ExtendProjects {
+		pos := m.where(fmt.Sprintf("extend-project[%d]", i))
+		if p.Name == "" {
+			v.warn(pos, "extend-project element has no name")
+			continue
+		}
+		p.Name = cleanPath(p.Name)
+		if !hasProject(dst, p.Name) {
+			v.warn(pos, "extend-project names unknown project '%s'", p.Name)
+		}
+		extPath[p.Name] = p
+	}
+	for i, p := range dst.Projects {
+		if p2, ok := extPath[p.Name]; ok {
+			if p2.Path == "" || cleanPath(p2.Path) == p.Path {
+				if p.Groups == "" {
+					dst.Projects[i].Groups = p2.Groups
+				} else if p2.Groups != "" {
+					groups := []string{}
+					groups = append(groups, strings.Split(p.Groups, ",")...)
+					groups = append(groups, strings.Split(p2.Groups, ",")...)
+					dst.Projects[i].Groups = strings.Join(groups, ",")
+				}
+				if p2.Revision != "" {
+					dst.Projects[i].Revision = p2.Revision
+				}
+			}
+		}
+	}
+
+	if m.RepoHooks != nil {
+		pos := m.where("repo-hooks[0]")
+		if m.RepoHooks.InProject == "" {
+			v.warn(pos, "repo-hooks element has no in-project")
+		} else if !hasProject(dst, cleanPath(m.RepoHooks.InProject)) {
+			v.warn(pos, "repo-hooks names unknown project '%s'", m.RepoHooks.InProject)
+		}
+	}
+
+	return nil
+}
 
-	file = filepath.Join(repoDir, config.ManifestXML)
-	if _, err = os.Stat(file); err != nil {
+// hasProject checks whether there is a project of the given name in m.
+func hasProject(m *Manifest, name string) bool {
+	for _, p := range m.Projects {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// allProjects returns all projects of manifest m, and fills missing fields
+// by remote and default.
+func (v *loader) allProjects(m *Manifest) ([]Project, error) {
+	var err error
+
+	projects := []Project{}
+	remotes := make(map[string]*Remote)
+	for i := range m.Remotes {
+		remotes[m.Remotes[i].Name] = &m.Remotes[i]
+	}
+
+	for _, p := range m.allProjects() {
+		pos := m.defined["project:"+p.Path]
+		if p.RemoteName == "" {
+			if m.Default == nil || m.Default.RemoteName == "" {
+				if err = v.fail(pos, "no remote for project '%s'", p.Name); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			p.RemoteName = m.Default.RemoteName
+		}
+		// Projects from submanifests have their own remotes.
+		if p.ManifestRemote == nil {
+			p.ManifestRemote = remotes[p.RemoteName]
+		}
+		if p.ManifestRemote == nil {
+			err = v.fail(pos, "unknown remote '%s' for project '%s'",
+				p.RemoteName,
+				p.Name)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if p.Revision == "" {
+			p.Revision = p.ManifestRemote.Revision
+		}
+
+		if m.Default != nil {
+			if p.Revision == "" {
+				p.Revision = m.Default.Revision
+			}
+			if p.DestBranch == "" {
+				p.DestBranch = m.Default.DestBranch
+			}
+			if p.Upstream == "" {
+				p.Upstream = m.Default.Upstream
+			}
+			if p.SyncC == "" {
+				p.SyncC = m.Default.SyncC
+			}
+			if p.SyncS == "" {
+				p.SyncS = m.Default.SyncS
+			}
+			if p.SyncTags == "" {
+				p.SyncTags = m.Default.SyncTags
+			}
+		}
+
+		if p.Revision == "" {
+			if err = v.fail(pos, "no revision for project '%s'", p.Name); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		projects = append(projects, p)
+	}
+	return projects, nil
+}
+
+// manifestFile returns manifest file used in repoDir.
+func manifestFile(repoDir string) (string, error) {
+	file := filepath.Join(repoDir, config.ManifestXML)
+	if _, err := os.Stat(file); err != nil {
 		defaultXML := ""
 		manifestsDir := filepath.Join(repoDir, config.Manifests)
 		cfg, err := goconfig.Load(manifestsDir)
 		if err != nil && err != goconfig.ErrNotExist {
-			return nil, fmt.Errorf("fail to read config from %s: %s", manifestsDir, err)
+			return "", fmt.Errorf("fail to read config from %s: %s", manifestsDir, err)
 		}
 		if cfg != nil {
 			defaultXML = cfg.Get(config.CfgManifestName)
@@ -555,46 +995,35 @@ func Load(repoDir string) (*Manifest, error) {
 		}
 		file = filepath.Join(manifestsDir, defaultXML)
 		if _, err = os.Stat(file); err != nil {
-			return nil, err
+			return "", err
 		}
 	}
-	return LoadFile(repoDir, file)
+	return file, nil
 }
 
-// LoadFile implements load specific manifest file inside repoDir.
-func LoadFile(repoDir, file string) (*Manifest, error) {
-	var (
-		dir       string
-		err       error
-		manifests = []*Manifest{}
-	)
-
-	if !filepath.IsAbs(file) {
-		file = filepath.Join(repoDir, config.Manifests, file)
-	}
-
-	// Ignore uninitialized repo
-	if _, err := os.Stat(file); err != nil {
-		return nil, nil
-	}
-
-	ms, err := parseXML(file, 1)
+// Load implements load and parse manifest XML file in repoDir.
+func Load(repoDir string) (*Manifest, error) {
+	file, err := manifestFile(repoDir)
 	if err != nil {
 		return nil, err
 	}
-	manifests = append(manifests, ms...)
+	return LoadFile(repoDir, file)
+}
 
-	// load local_manifest.xml (obsolete)
+// localManifestFiles returns local manifest files in repoDir.
+func localManifestFiles(repoDir string) []string {
 	files := []string{}
-	file = filepath.Join(repoDir, config.LocalManifestXML)
-	dir = filepath.Join(repoDir, config.LocalManifests)
-	if _, err = os.Stat(file); err == nil {
+
+	// load local_manifest.xml (obsolete)
+	file := filepath.Join(repoDir, config.LocalManifestXML)
+	dir := filepath.Join(repoDir, config.LocalManifests)
+	if _, err := os.Stat(file); err == nil {
 		log.Warnf("%s is deprecated; put local manifests in `%s` instead", file, dir)
 		files = append(files, file)
 	}
 
 	// load xml files in local_manifests
-	if _, err = os.Stat(dir); err == nil {
+	if _, err := os.Stat(dir); err == nil {
 		filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
@@ -611,16 +1040,217 @@ func LoadFile(repoDir, file string) (*Manifest, error) {
 			return nil
 		})
 	}
+	return files
+}
+
+// LoadFile implements load specific manifest file inside repoDir.
+func LoadFile(repoDir, file string) (*Manifest, error) {
+	return (&loader{}).loadFile(repoDir, file, true)
+}
+
+// LoadFileWithoutLocal loads specific manifest file inside repoDir, and
+// local manifests are not loaded, such as manifest saved by
+// `git repo manifest`, which has local manifests merged already.
+func LoadFileWithoutLocal(repoDir, file string) (*Manifest, error) {
+	return (&loader{}).loadFile(repoDir, file, false)
+}
+
+// loadFile loads manifest file, and submanifests and local manifests in
+// repoDir. Submanifests and local manifests are ignored if repoDir is empty.
+func (v *loader) loadFile(repoDir, file string, withLocal bool) (*Manifest, error) {
+	var (
+		err       error
+		manifests = []*Manifest{}
+	)
+
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(repoDir, config.Manifests, file)
+	}
+
+	// Ignore uninitialized repo
+	if _, err := os.Stat(file); err != nil {
+		return nil, nil
+	}
+
+	ms, err := v.parseXML(file, 1)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := v.mergeManifests(ms)
+	if err != nil {
+		return nil, err
+	}
+	if repoDir == "" {
+		return manifest, nil
+	}
+	// Projects of submanifests are merged before local manifests, so
+	// that they can be removed or extended by local manifests.
+	err = v.loadSubmanifests(manifest, repoDir, "", 1, 0)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, file = range files {
-		ms, err := parseXML(file, 1)
+	if !withLocal {
+		return manifest, nil
+	}
+	for _, file = range localManifestFiles(repoDir) {
+		ms, err := v.parseXML(file, 1)
 		if err != nil {
 			return nil, err
 		}
 		manifests = append(manifests, ms...)
 	}
+	for _, m := range manifests {
+		start := len(manifest.Submanifests)
+		err = v.merge(manifest, m)
+		if err != nil {
+			return nil, err
+		}
+		// Load submanifests added by local manifest, so that their
+		// projects can be extended by the following local manifests.
+		err = v.loadSubmanifests(manifest, repoDir, "", 1, start)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+// loadSubmanifests loads manifests of submanifests (from index start) of m,
+// which are checked out in repoDir, and merges their projects with paths
+// scoped by path of submanifest. Nested submanifests are lifted to m.
+func (v *loader) loadSubmanifests(m *Manifest, repoDir, prefix string, depth, start int) error {
+	if depth > maxRecursiveDepth {
+		return v.fail(position{file: m.SourceFile},
+			"exceeded maximum submanifest depth (%d) in '%s'",
+			maxRecursiveDepth,
+			prefix)
+	}
 
-	return mergeManifests(manifests)
+	realPath := make(map[string]bool)
+	for _, p := range m.allProjects() {
+		realPath[p.Path] = true
+	}
+
+	// Only iterate submanifests of current manifest, not lifted ones.
+	n := len(m.Submanifests)
+	for i := start; i < n; i++ {
+		sm := m.Submanifests[i]
+		remote := m.GetRemote(sm.Remote)
+		if remote == nil {
+			err := v.fail(m.defined["submanifest:"+sm.RelPath()],
+				"unknown remote '%s' for submanifest '%s'",
+				sm.Remote,
+				sm.Name)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		r := *remote
+		sm.ManifestRemote = &r
+		if sm.Revision == "" {
+			sm.Revision = remote.Revision
+		}
+		if sm.Revision == "" && m.Default != nil {
+			sm.Revision = m.Default.Revision
+		}
+		m.Submanifests[i] = sm
+
+		fullPath := filepath.Join(prefix, sm.RelPath())
+		file := filepath.Join(repoDir,
+			config.Submanifests,
+			fullPath,
+			config.Manifests,
+			sm.ManifestFile())
+		if _, err := os.Stat(file); err != nil {
+			log.Debugf("submanifest '%s' is not checked out yet", fullPath)
+			continue
+		}
+		ms, err := v.parseXML(file, 1)
+		if err != nil {
+			return err
+		}
+		child, err := v.mergeManifests(ms)
+		if err != nil {
+			return err
+		}
+		err = v.loadSubmanifests(child, repoDir, fullPath, depth+1, 0)
+		if err != nil {
+			return err
+		}
+		projects, err := v.allProjects(child)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range projects {
+			pos := child.defined["project:"+p.Path]
+			p.Path = cleanPath(filepath.Join(sm.RelPath(), p.Path))
+			key := "project:" + p.Path
+			if realPath[p.Path] {
+				err = v.fail(pos, "duplicate path '%s' for project '%s' in submanifest '%s'%s",
+					p.Path,
+					p.Name,
+					fullPath,
+					m.definedAt(key, pos.file))
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			realPath[p.Path] = true
+			r := *p.ManifestRemote
+			r.Fetch, err = scopedFetch(sm.ManifestRemote.Fetch, sm.ProjectName(), r.Fetch)
+			if err != nil {
+				return err
+			}
+			p.ManifestRemote = &r
+			p.Submanifest = cleanPath(filepath.Join(sm.RelPath(), p.Submanifest))
+			if sm.Groups != "" {
+				if p.Groups == "" {
+					p.Groups = sm.Groups
+				} else {
+					p.Groups += "," + sm.Groups
+				}
+			}
+			m.Projects = append(m.Projects, p)
+			m.define(key, pos)
+		}
+
+		for _, csm := range child.Submanifests {
+			// Problem of submanifest without remote is reported already.
+			if csm.ManifestRemote == nil {
+				continue
+			}
+			pos := child.defined["submanifest:"+csm.RelPath()]
+			csm.Path = cleanPath(filepath.Join(sm.RelPath(), csm.RelPath()))
+			r := *csm.ManifestRemote
+			r.Fetch, err = scopedFetch(sm.ManifestRemote.Fetch, sm.ProjectName(), r.Fetch)
+			if err != nil {
+				return err
+			}
+			csm.ManifestRemote = &r
+			csm.Parent = cleanPath(filepath.Join(sm.RelPath(), csm.Parent))
+			m.Submanifests = append(m.Submanifests, csm)
+			m.define("submanifest:"+csm.RelPath(), pos)
+		}
+	}
+	return nil
+}
+
+// scopedFetch converts fetch of remote in submanifest, which is relative to
+// URL of manifest project of submanifest, to be relative to URL of the
+// outer manifest.
+func scopedFetch(parentFetch, projectName, fetch string) (string, error) {
+	if config.ParseGitURL(fetch) != nil {
+		return fetch, nil
+	}
+	dir := filepath.Dir(projectName)
+	if config.ParseGitURL(parentFetch) != nil {
+		return common.URLAppend(parentFetch, dir, fetch)
+	}
+	return filepath.ToSlash(filepath.Join(parentFetch, dir, fetch)), nil
 }
 
 // Unmarshal implements decoding XML (in buf) to manifest.
@@ -631,9 +1261,64 @@ func Unmarshal(buf []byte) (*Manifest, error) {
 	return &ms, err
 }
 
-// Marshal implements encoding manifest to XML.
+// Marshal implements encoding manifest to XML. Projects and submanifests
+// lifted from submanifests are not written, which are loaded again from
+// submanifests.
 func Marshal(ms *Manifest) ([]byte, error) {
-	return xml.MarshalIndent(ms, "", "  ")
+	m := *ms
+	m.Projects = []Project{}
+	for _, p := range ms.Projects {
+		if p.Submanifest == "" {
+			m.Projects = append(m.Projects, p)
+		}
+	}
+	m.Submanifests = []Submanifest{}
+	for _, sm := range ms.Submanifests {
+		if sm.Parent == "" {
+			m.Submanifests = append(m.Submanifests, sm)
+		}
+	}
+	return xml.MarshalIndent(&m, "", "  ")
+}
+
+// Flatten returns a copy of manifest without submanifests, and projects
+// lifted from submanifests are kept with their own remotes. It is used to
+// save manifest with frozen revisions of all projects, which does not need
+// submanifests to be checked out.
+func (v *Manifest) Flatten() *Manifest {
+	m := *v
+	m.Remotes = append([]Remote{}, v.Remotes...)
+	m.Projects = []Project{}
+	m.Submanifests = nil
+	for _, p := range v.Projects {
+		if p.Submanifest != "" {
+			p.RemoteName = m.addRemote(*p.ManifestRemote, p.Submanifest)
+			p.Submanifest = ""
+		}
+		m.Projects = append(m.Projects, p)
+	}
+	return &m
+}
+
+// addRemote adds remote of project lifted from submanifest, and returns
+// name of the remote. Remote of the same name is reused if they are the
+// same, otherwise remote is renamed after path of submanifest.
+func (v *Manifest) addRemote(r Remote, smPath string) string {
+	name := r.Name
+	for i := 1; ; i++ {
+		r2 := v.GetRemote(r.Name)
+		if r2 == nil {
+			v.Remotes = append(v.Remotes, r)
+			return r.Name
+		}
+		if reflect.DeepEqual(*r2, r) {
+			return r.Name
+		}
+		r.Name = name + "-" + strings.Replace(smPath, "/", "-", -1)
+		if i > 1 {
+			r.Name += fmt.Sprintf("-%d", i)
+		}
+	}
 }
 
 // ManifestsProject is a special instance of Project.
@@ -708,6 +708,215 @@ func TestManifestRevision3(t *testing.T) {
 	assert.True(p.IsSyncTags())
 }
 
+func TestSuperproject(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := []byte(`
+<manifest>
+  <remote name="aone" fetch=".." revision="aone-master" />
+  <remote name="gerrit" fetch="https://gerrit.example.com" />
+  <default remote="gerrit" revision="default-master" />
+  <superproject name="platform/superproject" remote="aone" />
+  <project name="platform/app1" path="app1" />
+</manifest>`)
+
+	m, err := Unmarshal(buf)
+	assert.Nil(err)
+	assert.Equal(&Superproject{
+		Name:   "platform/superproject",
+		Remote: "aone",
+	}, m.Superproject)
+	// Use remote revision.
+	assert.Equal("aone-master", m.SuperprojectRevision())
+	assert.Equal("gerrit", m.GetRemote("").Name)
+	assert.Nil(m.GetRemote("unknown"))
+
+	// Use default remote and default revision.
+	m.Superproject.Remote = ""
+	assert.Equal("default-master", m.SuperprojectRevision())
+	m.Superproject.Revision = "release"
+	assert.Equal("release", m.SuperprojectRevision())
+
+	m2 := &Manifest{
+		Superproject: &Superproject{Name: "platform/other"},
+		SourceFile:   "local.xml",
+	}
+	assert.Equal("local.xml: duplicate superproject", m.Merge(m2).Error())
+	m2.Superproject = m.Superproject
+	assert.Nil(m.Merge(m2))
+}
+
+func TestLoadWithSubmanifests(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(dir string) {
+		os.RemoveAll(dir)
+	}(tmpdir)
+
+	repoDir := filepath.Join(tmpdir, "workdir", ".repo")
+	writeFile := func(name, data string) {
+		name = filepath.Join(repoDir, name)
+		assert.Nil(os.MkdirAll(filepath.Dir(name), 0755))
+		assert.Nil(ioutil.WriteFile(name, []byte(data), 0644))
+	}
+
+	writeFile("manifest.xml", `
+<manifest>
+  <remote name="aone" fetch=".." revision="master"></remote>
+  <default remote="aone"></default>
+  <contactinfo bugurl="https://example.com/bugs"></contactinfo>
+  <project name="platform/main" path="main"></project>
+  <submanifest name="team1" project="teams/team1/manifest" groups="team1"></submanifest>
+  <submanifest name="team2" path="vendor/team2" revision="release"></submanifest>
+</manifest>`)
+	// Manifest of team1 with nested submanifest.
+	writeFile("submanifests/team1/manifests/default.xml", `
+<manifest>
+  <remote name="aone" fetch=".." revision="develop"></remote>
+  <remote name="github" fetch="https://github.com" revision="main"></remote>
+  <default remote="aone"></default>
+  <project name="app" path="app" groups="app"></project>
+  <project name="tools" path="tools" remote="github"></project>
+  <submanifest name="sub" project="sub/manifest"></submanifest>
+</manifest>`)
+	writeFile("submanifests/team1/sub/manifests/default.xml", `
+<manifest>
+  <remote name="origin" fetch="."></remote>
+  <default remote="origin" revision="master"></default>
+  <project name="lib" path="lib"></project>
+</manifest>`)
+	// team2 is not checked out yet.
+
+	m, err := Load(repoDir)
+	assert.Nil(err)
+	assert.Equal("https://example.com/bugs", m.ContactInfo.BugURL)
+
+	result := []string{}
+	for _, p := range m.AllProjects() {
+		result = append(result, fmt.Sprintf("%s:%s:%s:%s:%s",
+			p.Path, p.Name, p.ManifestRemote.Fetch, p.Revision, p.Groups))
+	}
+	assert.Equal([]string{
+		"main:platform/main:..:master:",
+		"team1/app:app:../teams:develop:app,team1",
+		"team1/tools:tools:https://github.com:main:team1",
+		"team1/sub/lib:lib:../teams/sub:master:team1",
+	}, result)
+
+	result = []string{}
+	for _, sm := range m.Submanifests {
+		result = append(result, fmt.Sprintf("%s:%s:%s:%s",
+			sm.RelPath(), sm.ProjectName(), sm.ManifestRemote.Fetch, sm.Revision))
+	}
+	assert.Equal([]string{
+		"team1:teams/team1/manifest:..:master",
+		"vendor/team2:team2:..:release",
+		"team1/sub:sub/manifest:../teams:develop",
+	}, result)
+
+	// Projects and submanifests lifted from submanifests are not saved.
+	data, err := Marshal(m)
+	assert.Nil(err)
+	assert.Equal(`<manifest>
+  <remote name="aone" fetch=".." revision="master"></remote>
+  <default remote="aone"></default>
+  <project name="platform/main" path="main"></project>
+  <contactinfo bugurl="https://example.com/bugs"></contactinfo>
+  <submanifest name="team1" project="teams/team1/manifest" revision="master" groups="team1"></submanifest>
+  <submanifest name="team2" revision="release" path="vendor/team2"></submanifest>
+</manifest>`, string(data))
+
+	// Flatten manifest keeps projects of submanifests with their remotes.
+	data, err = Marshal(m.Flatten())
+	assert.Nil(err)
+	assert.Equal(`<manifest>
+  <remote name="aone" fetch=".." revision="master"></remote>
+  <remote name="aone-team1" fetch="../teams" revision="develop"></remote>
+  <remote name="github" fetch="https://github.com" revision="main"></remote>
+  <remote name="origin" fetch="../teams/sub"></remote>
+  <default remote="aone"></default>
+  <project name="platform/main" path="main"></project>
+  <project name="app" path="team1/app" remote="aone-team1" revision="develop" groups="app,team1"></project>
+  <project name="tools" path="team1/tools" remote="github" revision="main" groups="team1"></project>
+  <project name="lib" path="team1/sub/lib" remote="origin" revision="master" groups="team1"></project>
+  <contactinfo bugurl="https://example.com/bugs"></contactinfo>
+</manifest>`, string(data))
+}
+
+func TestLocalManifestsWithSubmanifests(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(dir string) {
+		os.RemoveAll(dir)
+	}(tmpdir)
+
+	repoDir := filepath.Join(tmpdir, "workdir", ".repo")
+	writeFile := func(name, data string) {
+		name = filepath.Join(repoDir, name)
+		assert.Nil(os.MkdirAll(filepath.Dir(name), 0755))
+		assert.Nil(ioutil.WriteFile(name, []byte(data), 0644))
+	}
+
+	writeFile("manifest.xml", `
+<manifest>
+  <remote name="aone" fetch=".." revision="master"></remote>
+  <default remote="aone"></default>
+  <project name="platform/main" path="main"></project>
+  <submanifest name="team1" project="teams/team1/manifest"></submanifest>
+</manifest>`)
+	writeFile("submanifests/team1/manifests/default.xml", `
+<manifest>
+  <remote name="aone" fetch=".." revision="develop"></remote>
+  <default remote="aone"></default>
+  <project name="app" path="app" groups="app"></project>
+  <project name="tools" path="tools"></project>
+</manifest>`)
+	writeFile("local_manifests/local.xml", `
+<manifest>
+  <remove-project name="tools"></remove-project>
+  <extend-project name="app" path="team1/app" groups="local" revision="feature"></extend-project>
+</manifest>`)
+
+	m, err := Load(repoDir)
+	assert.Nil(err)
+
+	result := []string{}
+	for _, p := range m.AllProjects() {
+		result = append(result, fmt.Sprintf("%s:%s:%s:%s:%s",
+			p.Path, p.Name, p.ManifestRemote.Fetch, p.Revision, p.Groups))
+	}
+	assert.Equal([]string{
+		"main:platform/main:..:master:",
+		"team1/app:app:../teams:feature:app,local",
+	}, result)
+}
+
+func TestScopedFetch(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, c := range []struct {
+		parent, project, fetch, expect string
+	}{
+		{"..", "teams/manifest", "..", ".."},
+		{".", "teams/team1/manifest", ".", "teams/team1"},
+		{"..", "manifest", "https://example.com", "https://example.com"},
+		{"https://example.com/git", "teams/manifest", "..", "https://example.com/git"},
+		{"https://example.com/git", "teams/manifest", ".", "https://example.com/git/teams"},
+	} {
+		fetch, err := scopedFetch(c.parent, c.project, c.fetch)
+		assert.Nil(err)
+		assert.Equal(c.expect, fetch)
+	}
+}
+
 func ExampleMarshal() {
 	m := Manifest{
 		Remotes: []Remote{
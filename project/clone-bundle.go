@@ -0,0 +1,199 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alibaba/git-repo-go/helper"
+	log "github.com/jiangxin/multi-log"
+)
+
+const (
+	cloneBundleFile    = "clone.bundle"
+	cloneBundleTmpFile = "clone.bundle.tmp"
+	cloneBundleTimeout = 30
+)
+
+var (
+	errCloneBundleNotFound = errors.New("clone.bundle not found")
+
+	cloneBundleHTTPClient *http.Client
+)
+
+func getCloneBundleHTTPClient() *http.Client {
+	if cloneBundleHTTPClient == nil {
+		cloneBundleHTTPClient = helper.NewHTTPClient(cloneBundleTimeout * time.Second)
+	}
+	return cloneBundleHTTPClient
+}
+
+// bundleProgress shows progress of downloading clone.bundle, at most once
+// per second.
+type bundleProgress struct {
+	w       io.Writer
+	prompt  string
+	total   int64
+	size    int64
+	updated time.Time
+}
+
+func (v *bundleProgress) Write(p []byte) (int, error) {
+	v.size += int64(len(p))
+	if time.Since(v.updated) >= time.Second {
+		v.show("\r")
+		v.updated = time.Now()
+	}
+	return len(p), nil
+}
+
+func (v *bundleProgress) show(eol string) {
+	if v.total > 0 {
+		fmt.Fprintf(v.w, "%sdownloading clone.bundle: %3d%% (%.2f MiB)%s",
+			v.prompt, v.size*100/v.total, float64(v.size)/(1<<20), eol)
+	} else {
+		fmt.Fprintf(v.w, "%sdownloading clone.bundle: %.2f MiB%s",
+			v.prompt, float64(v.size)/(1<<20), eol)
+	}
+}
+
+// cloneBundleURL returns URL of clone.bundle for http/https remote URL.
+func cloneBundleURL(remoteURL string) string {
+	if !strings.HasPrefix(remoteURL, "http://") &&
+		!strings.HasPrefix(remoteURL, "https://") {
+		return ""
+	}
+	return strings.TrimSuffix(remoteURL, "/") + "/" + cloneBundleFile
+}
+
+// downloadCloneBundle downloads bundle from url and saves to file. Partial
+// downloaded file is resumed if server supports range requests. Progress
+// is shown if progress is not nil.
+func downloadCloneBundle(url, file string, progress *bundleProgress) error {
+	var (
+		offset int64
+		flag   = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	)
+
+	if fi, err := os.Stat(file); err == nil {
+		offset = fi.Size()
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := getCloneBundleHTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusPartialContent:
+		flag = os.O_WRONLY | os.O_APPEND
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Already downloaded.
+		if offset > 0 {
+			return nil
+		}
+		return fmt.Errorf("bad status: %d", resp.StatusCode)
+	case http.StatusNotFound, http.StatusForbidden, http.StatusGone:
+		os.Remove(file)
+		return errCloneBundleNotFound
+	default:
+		return fmt.Errorf("bad status: %d", resp.StatusCode)
+	}
+
+	f, err := os.OpenFile(file, flag, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if progress == nil {
+		_, err = io.Copy(f, resp.Body)
+		return err
+	}
+	if resp.ContentLength > 0 {
+		progress.total = resp.ContentLength
+		if flag&os.O_APPEND != 0 {
+			progress.total += offset
+			progress.size = offset
+		}
+	}
+	progress.updated = time.Now()
+	_, err = io.Copy(io.MultiWriter(f, progress), resp.Body)
+	if err == nil {
+		progress.show("\n")
+	}
+	return err
+}
+
+// applyCloneBundle downloads clone.bundle from http/https remote, and
+// fetches from it for a new repository. Returns false if no bundle is
+// applied, and normal fetch will be used silently.
+func (v Repository) applyCloneBundle(o *FetchOptions) bool {
+	url := cloneBundleURL(v.RemoteURL)
+	if url == "" || !v.isEmpty() {
+		return false
+	}
+
+	tmpFile := filepath.Join(v.RepoDir(), cloneBundleTmpFile)
+	bundleFile := filepath.Join(v.RepoDir(), cloneBundleFile)
+
+	log.Debugf("%sdownloading %s", v.Prompt(), url)
+	var progress *bundleProgress
+	if o.ShowProgress && !o.Quiet {
+		progress = &bundleProgress{w: os.Stderr, prompt: v.Prompt()}
+	}
+	err := downloadCloneBundle(url, tmpFile, progress)
+	if err != nil {
+		log.Debugf("%sfail to download clone.bundle: %s", v.Prompt(), err)
+		return false
+	}
+	err = os.Rename(tmpFile, bundleFile)
+	if err != nil {
+		log.Debugf("%sfail to rename clone.bundle: %s", v.Prompt(), err)
+		return false
+	}
+	defer os.Remove(bundleFile)
+
+	cmd := exec.Command(GIT, "bundle", "verify", bundleFile)
+	cmd.Dir = v.RepoDir()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		log.Debugf("%sbad clone.bundle: %s\n%s", v.Prompt(), err, out)
+		return false
+	}
+
+	cmdArgs := []string{
+		GIT,
+		"fetch",
+	}
+	if o.Quiet {
+		cmdArgs = append(cmdArgs, "--quiet")
+	}
+	cmdArgs = append(cmdArgs, bundleFile)
+	if v.IsBare {
+		cmdArgs = append(cmdArgs, "+refs/heads/*:refs/heads/*")
+	} else {
+		cmdArgs = append(cmdArgs, fmt.Sprintf("+refs/heads/*:refs/remotes/%s/*", v.RemoteName))
+	}
+	cmdArgs = append(cmdArgs, "+refs/tags/*:refs/tags/*")
+	log.Debugf("%sfetching from clone.bundle: %s", v.Prompt(), strings.Join(cmdArgs, " "))
+	err = executeCommandIn(v.RepoDir(), cmdArgs)
+	if err != nil {
+		log.Debugf("%sfail to fetch from clone.bundle: %s", v.Prompt(), err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package project
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloneBundleURL(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("", cloneBundleURL("ssh://example.com/foo.git"))
+	assert.Equal("", cloneBundleURL("/path/of/foo.git"))
+	assert.Equal("https://example.com/foo.git/clone.bundle",
+		cloneBundleURL("https://example.com/foo.git"))
+	assert.Equal("http://example.com/foo/clone.bundle",
+		cloneBundleURL("http://example.com/foo/"))
+}
+
+func TestApplyCloneBundle(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// Bundle of upstream repository "foo" with 3 commits.
+	upstream := filepath.Join(tmpdir, "foo.git")
+	bundle := filepath.Join(tmpdir, "foo.bundle")
+	assert.Nil(exec.Command("git", "init", "-q", upstream).Run())
+	assert.Nil(exec.Command("git", "-C", upstream, "checkout", "-q", "-b", "master").Run())
+	gitCommitIn(t, upstream, 3)
+	assert.Nil(exec.Command("git", "-C", upstream, "bundle", "create", bundle, "--all").Run())
+	bundleData, err := ioutil.ReadFile(bundle)
+	assert.Nil(err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/foo.git/clone.bundle":
+			http.ServeFile(w, r, bundle)
+		case "/bad.git/clone.bundle":
+			w.Write([]byte("bad bundle"))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+
+	newProject := func(name string) *Project {
+		xmlProject := manifest.Project{
+			Name:       name,
+			Path:       name,
+			RemoteName: "origin",
+			Revision:   "master",
+		}
+		xmlProject.ManifestRemote = &manifest.Remote{
+			Name:  "origin",
+			Fetch: "..",
+		}
+		p := NewProject(&xmlProject,
+			&RepoSettings{
+				TopDir:      filepath.Join(tmpdir, "work"),
+				ManifestURL: ts.URL + "/mirror/manifests",
+			}, nil)
+		assert.Nil(p.GitInit())
+		return p
+	}
+
+	// No clone.bundle, fallback silently.
+	p := newProject("not-exist")
+	assert.False(p.applyCloneBundle(&FetchOptions{Quiet: true}))
+	assert.False(p.RevisionIsValid("refs/remotes/origin/master"))
+
+	// Bad clone.bundle.
+	p = newProject("bad")
+	assert.False(p.applyCloneBundle(&FetchOptions{Quiet: true}))
+	assert.False(p.RevisionIsValid("refs/remotes/origin/master"))
+	assert.True(p.isEmpty())
+
+	// Resume partial downloaded clone.bundle.
+	p = newProject("foo")
+	tmpFile := filepath.Join(p.RepoDir(), cloneBundleTmpFile)
+	assert.Nil(ioutil.WriteFile(tmpFile, bundleData[:len(bundleData)/2], 0644))
+	assert.True(p.applyCloneBundle(&FetchOptions{Quiet: true}))
+	assert.True(p.RevisionIsValid("refs/remotes/origin/master"))
+	commits, err := p.Revlist("refs/remotes/origin/master")
+	assert.Nil(err)
+	assert.Equal(3, len(commits))
+	assert.False(p.isEmpty())
+	_, err = os.Stat(tmpFile)
+	assert.True(os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(p.RepoDir(), cloneBundleFile))
+	assert.True(os.IsNotExist(err))
+
+	// Only for new repository.
+	assert.False(p.applyCloneBundle(&FetchOptions{Quiet: true}))
+
+	// Show progress of resumed download.
+	tmpFile = filepath.Join(tmpdir, "progress.bundle")
+	assert.Nil(ioutil.WriteFile(tmpFile, bundleData[:len(bundleData)/2], 0644))
+	out := bytes.Buffer{}
+	assert.Nil(downloadCloneBundle(ts.URL+"/foo.git/clone.bundle", tmpFile,
+		&bundleProgress{w: &out, prompt: "foo: "}))
+	assert.Equal("foo: downloading clone.bundle: 100% (0.00 MiB)\n", out.String())
+	data, err := ioutil.ReadFile(tmpFile)
+	assert.Nil(err)
+	assert.Equal(bundleData, data)
+}
@@ -0,0 +1,72 @@
+package project
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// writtenFiles records checksums of files written by CopyFile and
+// LinkFile, indexed by absolute path of dest.
+var writtenFiles = struct {
+	sync.Mutex
+	checksums map[string]string
+}{
+	checksums: make(map[string]string),
+}
+
+// FileChecksum returns target of symlink, or sha1 of regular file, which
+// is used to find out whether file is modified by user. Returns empty
+// string if file does not exist.
+func FileChecksum(name string) string {
+	fi, err := os.Lstat(name)
+	if err != nil {
+		return ""
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(name)
+		if err != nil {
+			return ""
+		}
+		return "symlink:" + filepath.ToSlash(target)
+	}
+	if !fi.Mode().IsRegular() {
+		return ""
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	h := sha1.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return ""
+	}
+	return "sha1:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// recordWrittenFile saves checksum of dest just written by CopyFile or
+// LinkFile.
+func recordWrittenFile(destAbs string) {
+	checksum := FileChecksum(destAbs)
+	writtenFiles.Lock()
+	defer writtenFiles.Unlock()
+	if checksum == "" {
+		delete(writtenFiles.checksums, destAbs)
+	} else {
+		writtenFiles.checksums[destAbs] = checksum
+	}
+}
+
+// WrittenFileChecksum returns checksum of dest when it was written by
+// CopyFile or LinkFile in this process, or returns empty string if it is
+// not written.
+func WrittenFileChecksum(destAbs string) string {
+	writtenFiles.Lock()
+	defer writtenFiles.Unlock()
+	return writtenFiles.checksums[destAbs]
+}
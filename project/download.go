@@ -51,20 +51,25 @@ func (v Project) DownloadPatchSet(remoteName string, reviewID, patchID int) (*Pa
 		return nil, err
 	}
 
-	commits, err := v.Revlist(reviewRef, "--not", "HEAD")
+	return v.newPatchSet(reviewRef)
+}
+
+// newPatchSet returns PatchSet of reference, with commits not in HEAD.
+func (v Project) newPatchSet(ref string) (*PatchSet, error) {
+	commits, err := v.Revlist(ref, "--not", "HEAD")
 	if err != nil {
 		return nil, err
 	}
 
 	dl := PatchSet{
-		Reference: reviewRef,
+		Reference: ref,
 		Commits:   commits,
 	}
 
 	if len(dl.Commits) > 0 {
 		dl.Commit = dl.Commits[0]
 	} else {
-		commit, err := v.ResolveRevision(reviewRef)
+		commit, err := v.ResolveRevision(ref)
 		if err != nil {
 			return nil, err
 		}
@@ -74,6 +79,27 @@ func (v Project) DownloadPatchSet(remoteName string, reviewID, patchID int) (*Pa
 	return &dl, nil
 }
 
+// DownloadReviewRef fetches code review reference of a change set from
+// remote, and returns the downloaded PatchSet.
+func (v Project) DownloadReviewRef(remoteName, reviewRef string) (*PatchSet, error) {
+	if remoteName == "" {
+		remoteName = v.RemoteName
+	}
+	cmdArgs := []string{
+		GIT,
+		"fetch",
+		remoteName,
+		"+" + reviewRef + ":" + reviewRef,
+		"--",
+	}
+	log.Debugf("%swill execute: %s", v.Prompt(), strings.Join(cmdArgs, " "))
+	err := executeCommandIn(v.WorkDir, cmdArgs)
+	if err != nil {
+		return nil, fmt.Errorf("fail to fetch %s from %s: %s", reviewRef, remoteName, err)
+	}
+	return v.newPatchSet(reviewRef)
+}
+
 // CherryPick runs cherry-pick on commits.
 func (v Project) CherryPick(commits ...string) error {
 	for i := len(commits) - 1; i >= 0; i-- {
@@ -1,6 +1,7 @@
 package project
 
 import (
+	"bytes"
 	"os"
 	"os/exec"
 	"strings"
@@ -12,6 +13,7 @@ import (
 type CmdExecResult struct {
 	Project *Project
 	Out     []byte
+	ErrOut  []byte
 	Error   error
 }
 
@@ -34,6 +36,10 @@ func (v *CmdExecResult) Stderr() string {
 		return ""
 	}
 
+	if len(v.ErrOut) > 0 {
+		return string(v.ErrOut)
+	}
+
 	if exitError, ok := v.Error.(*exec.ExitError); ok {
 		return string(exitError.Stderr)
 	}
@@ -41,6 +47,19 @@ func (v *CmdExecResult) Stderr() string {
 	return v.Error.Error()
 }
 
+// ExitCode returns exit code of command, or -1 if command fails to start.
+func (v CmdExecResult) ExitCode() int {
+	if v.Error == nil {
+		return 0
+	}
+
+	if exitError, ok := v.Error.(*exec.ExitError); ok {
+		return exitError.ExitCode()
+	}
+
+	return -1
+}
+
 // Empty indicates output and error output is empty.
 func (v *CmdExecResult) Empty() bool {
 	return len(v.Stdout()) == 0 && len(v.Stderr()) == 0
@@ -61,6 +80,11 @@ func (v CmdExecResult) Success() bool {
 
 // ExecuteCommand runs command.
 func (v Project) ExecuteCommand(args ...string) *CmdExecResult {
+	return v.ExecuteCommandWithEnv(nil, args...)
+}
+
+// ExecuteCommandWithEnv runs command with extra environments.
+func (v Project) ExecuteCommandWithEnv(env []string, args ...string) *CmdExecResult {
 	result := CmdExecResult{
 		Project: &v,
 	}
@@ -70,8 +94,14 @@ func (v Project) ExecuteCommand(args ...string) *CmdExecResult {
 	} else {
 		cmd.Dir = v.WorkDir
 	}
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 	cmd.Stdin = nil
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
 	result.Out, result.Error = cmd.Output()
+	result.ErrOut = stderr.Bytes()
 	return &result
 }
 
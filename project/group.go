@@ -1,53 +1,404 @@
 package project
 
 import (
+	"fmt"
+	"sort"
 	"strings"
+
+	log "github.com/jiangxin/multi-log"
 )
 
+// GroupAll is the group which all projects belong to.
+const GroupAll = "all"
+
 const (
 	groupDefaultConst    = "default"
-	groupAllConst        = "all"
+	groupAllConst        = GroupAll
 	groupNotDefaultConst = "notdefault"
 )
 
-// MatchGroups checks if project has matched groups.
-func MatchGroups(match, groups string) bool {
-	matchGroups := []string{}
-	for _, g := range strings.Split(match, ",") {
-		matchGroups = append(matchGroups, strings.TrimSpace(g))
+// groupExpr is an expression of groups, such as "(linux & !test) | tools".
+type groupExpr interface {
+	// eval checks whether project with groups matches the expression.
+	eval(groups map[string]bool) bool
+	// explain returns evaluation of expression and its subexpressions.
+	explain(groups map[string]bool, indent string) []string
+	String() string
+}
+
+type groupName string
+
+type groupNot struct {
+	x groupExpr
+}
+
+type groupAnd []groupExpr
+
+type groupOr []groupExpr
+
+func (v groupName) eval(groups map[string]bool) bool {
+	return groups[string(v)]
+}
+
+func (v groupName) explain(groups map[string]bool, indent string) []string {
+	return []string{fmt.Sprintf("%s%s: %t", indent, v, v.eval(groups))}
+}
+
+func (v groupName) String() string {
+	return string(v)
+}
+
+func (v groupNot) eval(groups map[string]bool) bool {
+	return !v.x.eval(groups)
+}
+
+func (v groupNot) explain(groups map[string]bool, indent string) []string {
+	lines := []string{fmt.Sprintf("%s%s: %t", indent, v, v.eval(groups))}
+	if _, ok := v.x.(groupName); ok {
+		return lines
+	}
+	return append(lines, v.x.explain(groups, indent+"  ")...)
+}
+
+func (v groupNot) String() string {
+	if _, ok := v.x.(groupName); ok {
+		return "!" + v.x.String()
+	}
+	return "!(" + v.x.String() + ")"
+}
+
+func (v groupAnd) eval(groups map[string]bool) bool {
+	for _, x := range v {
+		if !x.eval(groups) {
+			return false
+		}
 	}
-	if len(matchGroups) == 0 {
-		matchGroups = append(matchGroups, groupDefaultConst)
+	return true
+}
+
+func (v groupAnd) explain(groups map[string]bool, indent string) []string {
+	lines := []string{fmt.Sprintf("%s%s: %t", indent, v, v.eval(groups))}
+	for _, x := range v {
+		lines = append(lines, x.explain(groups, indent+"  ")...)
 	}
+	return lines
+}
 
-	projectGroups := []string{groupAllConst}
+func (v groupAnd) String() string {
+	items := []string{}
+	for _, x := range v {
+		if _, ok := x.(groupOr); ok {
+			items = append(items, "("+x.String()+")")
+		} else {
+			items = append(items, x.String())
+		}
+	}
+	return strings.Join(items, " & ")
+}
+
+func (v groupOr) eval(groups map[string]bool) bool {
+	for _, x := range v {
+		if x.eval(groups) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v groupOr) explain(groups map[string]bool, indent string) []string {
+	lines := []string{fmt.Sprintf("%s%s: %t", indent, v, v.eval(groups))}
+	for _, x := range v {
+		lines = append(lines, x.explain(groups, indent+"  ")...)
+	}
+	return lines
+}
+
+func (v groupOr) String() string {
+	items := []string{}
+	for _, x := range v {
+		items = append(items, x.String())
+	}
+	return strings.Join(items, " | ")
+}
+
+// groupParser is a recursive descent parser for group expressions:
+//
+//	expr   := and ( '|' and )*
+//	and    := unary ( '&' unary )*
+//	unary  := '!' unary | '(' expr ')' | name
+type groupParser struct {
+	tokens []string
+	pos    int
+}
+
+func isGroupOperator(c rune) bool {
+	return strings.ContainsRune("()&|!,", c)
+}
+
+func tokenizeGroups(s string) []string {
+	tokens := []string{}
+	name := ""
+	for _, c := range s {
+		if c == ' ' || c == '\t' || isGroupOperator(c) {
+			if name != "" {
+				tokens = append(tokens, name)
+				name = ""
+			}
+			if isGroupOperator(c) {
+				tokens = append(tokens, string(c))
+			}
+			continue
+		}
+		name += string(c)
+	}
+	if name != "" {
+		tokens = append(tokens, name)
+	}
+	return tokens
+}
+
+func (v *groupParser) peek() string {
+	if v.pos < len(v.tokens) {
+		return v.tokens[v.pos]
+	}
+	return ""
+}
+
+func (v *groupParser) next() string {
+	token := v.peek()
+	if token != "" {
+		v.pos++
+	}
+	return token
+}
+
+func (v *groupParser) parseExpr() (groupExpr, error) {
+	var result groupOr
+
+	for {
+		x, err := v.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, x)
+		if v.peek() != "|" {
+			break
+		}
+		v.next()
+	}
+	if len(result) == 1 {
+		return result[0], nil
+	}
+	return result, nil
+}
+
+func (v *groupParser) parseAnd() (groupExpr, error) {
+	var result groupAnd
+
+	for {
+		x, err := v.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, x)
+		if v.peek() != "&" {
+			break
+		}
+		v.next()
+	}
+	if len(result) == 1 {
+		return result[0], nil
+	}
+	return result, nil
+}
+
+func (v *groupParser) parseUnary() (groupExpr, error) {
+	token := v.next()
+	switch token {
+	case "":
+		return nil, fmt.Errorf("missing group name at end of expression")
+	case "!":
+		x, err := v.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return groupNot{x}, nil
+	case "(":
+		x, err := v.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if v.next() != ")" {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		return x, nil
+	}
+	if isGroupOperator([]rune(token)[0]) {
+		return nil, fmt.Errorf("unexpected '%s'", token)
+	}
+	return groupName(token), nil
+}
+
+// parseGroupExpr parses expression of groups.
+func parseGroupExpr(s string) (groupExpr, error) {
+	parser := groupParser{tokens: tokenizeGroups(s)}
+	x, err := parser.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.peek(); token != "" {
+		return nil, fmt.Errorf("unexpected '%s'", token)
+	}
+	return x, nil
+}
+
+// SplitGroups splits groups separated by commas, and commas inside
+// parentheses are not separators.
+func SplitGroups(s string) []string {
+	var (
+		result = []string{}
+		depth  = 0
+		start  = 0
+	)
+
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth <= 0 {
+				result = append(result, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(result, strings.TrimSpace(s[start:]))
+}
+
+// groupItem is an item of comma separated groups. Projects which match
+// an inverse item (with "-" prefix) are excluded.
+type groupItem struct {
+	expr    groupExpr
+	inverse bool
+}
+
+func (v groupItem) String() string {
+	if v.inverse {
+		if _, ok := v.expr.(groupName); ok {
+			return "-" + v.expr.String()
+		}
+		return "-(" + v.expr.String() + ")"
+	}
+	return v.expr.String()
+}
+
+// GroupMatcher matches projects against groups, which are separated by
+// commas, such as "default,-test,(linux & !test) | tools".
+//
+// Each item is an expression of groups with operators "&" (and), "|"
+// (or), "!" (not) and parentheses. Items are checked in order, and the
+// last item which matches the project decides: a project is included if
+// the item is a normal one, or excluded if the item has a "-" prefix.
+type GroupMatcher struct {
+	items []groupItem
+}
+
+// ParseGroups parses groups, returns error if there is invalid expression.
+func ParseGroups(match string) (*GroupMatcher, error) {
+	v := GroupMatcher{}
+	for _, item := range SplitGroups(match) {
+		if item == "" {
+			continue
+		}
+		inverse := false
+		if strings.HasPrefix(item, "-") {
+			inverse = true
+			item = item[1:]
+		}
+		x, err := parseGroupExpr(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid groups '%s': %s", match, err)
+		}
+		v.items = append(v.items, groupItem{expr: x, inverse: inverse})
+	}
+	if len(v.items) == 0 {
+		v.items = append(v.items, groupItem{expr: groupName(groupDefaultConst)})
+	}
+	return &v, nil
+}
+
+// projectGroups returns groups of project, include implicit groups.
+func projectGroups(groups string) map[string]bool {
+	result := map[string]bool{groupAllConst: true}
 	hasNotDefault := false
 	for _, g := range strings.Split(groups, ",") {
 		g = strings.TrimSpace(g)
-		projectGroups = append(projectGroups, g)
+		if g == "" {
+			continue
+		}
+		result[g] = true
 		if g == groupNotDefaultConst {
 			hasNotDefault = true
 		}
 	}
 	if !hasNotDefault {
-		projectGroups = append(projectGroups, groupDefaultConst)
+		result[groupDefaultConst] = true
 	}
+	return result
+}
 
+// Match checks if project with groups matches.
+func (v GroupMatcher) Match(groups string) bool {
+	pg := projectGroups(groups)
 	matched := false
-	for _, g := range matchGroups {
-		inverse := false
-		if strings.HasPrefix(g, "-") {
-			inverse = true
-			g = g[1:]
+	for _, item := range v.items {
+		if item.expr.eval(pg) {
+			matched = !item.inverse
 		}
-		for _, pg := range projectGroups {
-			if pg == g {
-				matched = !inverse
-				break
+	}
+	return matched
+}
+
+// Explain shows how project with groups is matched, line by line.
+func (v GroupMatcher) Explain(groups string) []string {
+	pg := projectGroups(groups)
+	names := []string{}
+	for g := range pg {
+		names = append(names, g)
+	}
+	sort.Strings(names)
+	lines := []string{"project groups: " + strings.Join(names, ", ")}
+	matched := false
+	for _, item := range v.items {
+		result := "not matched"
+		if item.expr.eval(pg) {
+			matched = !item.inverse
+			if item.inverse {
+				result = "matched, exclude"
+			} else {
+				result = "matched, include"
 			}
 		}
+		lines = append(lines, fmt.Sprintf("%s => %s", item, result))
+		// Root of expression is shown above, show its subexpressions.
+		lines = append(lines, item.expr.explain(pg, "")[1:]...)
 	}
+	if matched {
+		lines = append(lines, "result: included")
+	} else {
+		lines = append(lines, "result: excluded")
+	}
+	return lines
+}
 
-	return matched
-
+// MatchGroups checks if project has matched groups.
+func MatchGroups(match, groups string) bool {
+	m, err := ParseGroups(match)
+	if err != nil {
+		log.Warn(err)
+		return false
+	}
+	return m.Match(groups)
 }
@@ -57,3 +57,66 @@ func TestMatchGroups(t *testing.T) {
 	groups = "g1,notdefault"
 	assert.False(MatchGroups(match, groups))
 }
+
+func TestMatchGroupExpressions(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(MatchGroups("linux & !test", "linux"))
+	assert.False(MatchGroups("linux & !test", "linux,test"))
+	assert.True(MatchGroups("(linux & !test) | tools", "tools,test"))
+	assert.False(MatchGroups("(linux & !test) | tools", "test"))
+	assert.True(MatchGroups("!(linux|darwin)", "windows"))
+	assert.False(MatchGroups("!(linux|darwin)", "darwin"))
+	assert.True(MatchGroups("a | b & c", "a"))
+	assert.False(MatchGroups("(a | b) & c", "a"))
+	assert.True(MatchGroups("!!a", "a"))
+
+	// Comma separated items, and the last matched item decides.
+	assert.True(MatchGroups("default,(linux & !test)", "linux,notdefault"))
+	assert.False(MatchGroups("all,-(linux & test)", "linux,test"))
+	assert.True(MatchGroups("all,-(linux & test)", "linux"))
+	assert.False(MatchGroups("all,-(a,b)", "b"))
+
+	// Invalid expressions never match.
+	assert.False(MatchGroups("(linux", "linux"))
+	assert.False(MatchGroups("linux &", "linux"))
+	assert.False(MatchGroups("linux test", "linux"))
+}
+
+func TestParseGroups(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{"a", "-b", "(c, d) & e", ""},
+		SplitGroups(" a, -b ,(c, d) & e,"))
+
+	for match, msg := range map[string]string{
+		"(linux":       "invalid groups '(linux': missing ')'",
+		"linux &":      "invalid groups 'linux &': missing group name at end of expression",
+		"linux | )":    "invalid groups 'linux | )': unexpected ')'",
+		"linux test":   "invalid groups 'linux test': unexpected 'test'",
+		"default,-":    "invalid groups 'default,-': missing group name at end of expression",
+		"a & (b | !)":  "invalid groups 'a & (b | !)': unexpected ')'",
+		"a,(b | c))":   "invalid groups 'a,(b | c))': unexpected ')'",
+		"a,&b":         "invalid groups 'a,&b': unexpected '&'",
+		"(a | b) & c(": "invalid groups '(a | b) & c(': unexpected '('",
+	} {
+		_, err := ParseGroups(match)
+		if assert.NotNil(err, "should fail to parse '%s'", match) {
+			assert.Equal(msg, err.Error())
+		}
+	}
+
+	m, err := ParseGroups("default,-test, (linux & !test) | tools")
+	assert.Nil(err)
+	assert.Equal([]string{
+		"project groups: all, notdefault, test, tools",
+		"default => not matched",
+		"-test => matched, exclude",
+		"linux & !test | tools => matched, include",
+		"  linux & !test: false",
+		"    linux: false",
+		"    !test: false",
+		"  tools: true",
+		"result: included",
+	}, m.Explain("tools,test,notdefault"))
+}
@@ -0,0 +1,157 @@
+package project
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/common"
+	"github.com/alibaba/git-repo-go/config"
+	log "github.com/jiangxin/multi-log"
+)
+
+// refHead is the name of HEAD in ref snapshot, and its value has prefix
+// "ref: " if HEAD is a symbolic ref.
+const refHead = "HEAD"
+
+// RefChange is a ref moved, created or deleted by an operation. Old is
+// empty if the ref is created, and New is empty if the ref is deleted.
+type RefChange struct {
+	Ref string `json:"ref"`
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+}
+
+// RefSnapshot returns HEAD and local branches of project, which are
+// refs changed by checkout, rebase and branch deletion.
+func (v Project) RefSnapshot() map[string]string {
+	result := make(map[string]string)
+	if !v.Exists() {
+		return result
+	}
+	for _, b := range v.Heads() {
+		result[b.Name] = b.Hash
+	}
+	// HEAD is not saved if not checked out yet.
+	headid, err := v.ResolveRevision(refHead)
+	if err != nil || headid == "" {
+		return result
+	}
+	if head := v.GetHead(); head != "" {
+		result[refHead] = "ref: " + head
+	} else {
+		result[refHead] = headid
+	}
+	return result
+}
+
+// DiffRefSnapshot compares two snapshots of refs, and returns changed
+// refs. HEAD is always the last one, so it is restored last.
+func DiffRefSnapshot(before, after map[string]string) []RefChange {
+	result := []RefChange{}
+	for ref, old := range before {
+		if after[ref] != old {
+			result = append(result, RefChange{Ref: ref, Old: old, New: after[ref]})
+		}
+	}
+	for ref, new := range after {
+		if _, ok := before[ref]; !ok {
+			result = append(result, RefChange{Ref: ref, New: new})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Ref == refHead || result[j].Ref == refHead {
+			return result[j].Ref == refHead && result[i].Ref != refHead
+		}
+		return result[i].Ref < result[j].Ref
+	})
+	return result
+}
+
+// restoreHead checks out the old HEAD, which is a branch or a commit.
+func (v Project) restoreHead(head string) error {
+	cmdArgs := []string{GIT, "checkout", "-q"}
+	if strings.HasPrefix(head, "ref: ") {
+		branch := strings.TrimPrefix(head, "ref: ")
+		if !common.IsHead(branch) {
+			return fmt.Errorf("cannot checkout '%s', not a branch", branch)
+		}
+		cmdArgs = append(cmdArgs, strings.TrimPrefix(branch, config.RefsHeads))
+	} else {
+		cmdArgs = append(cmdArgs, "--detach", head)
+	}
+	cmdArgs = append(cmdArgs, "--")
+	return executeCommandIn(v.WorkDir, cmdArgs)
+}
+
+// RestoreRefs reverts changes of refs. A ref which is changed again after
+// the operation is not restored unless force is set. HEAD and the current
+// branch are only restored if worktree is clean, and worktree is updated
+// together.
+func (v Project) RestoreRefs(changes []RefChange, force bool) error {
+	var (
+		current = v.RefSnapshot()
+		errMsgs = []string{}
+	)
+
+	for _, c := range changes {
+		if current[c.Ref] == c.Old {
+			continue
+		}
+		if current[c.Ref] != c.New && !force {
+			errMsgs = append(errMsgs,
+				fmt.Sprintf("'%s' is changed after the operation, use --force to restore", c.Ref))
+			continue
+		}
+		if c.Ref == refHead {
+			if c.Old == "" {
+				continue
+			}
+			if !v.IsClean() {
+				errMsgs = append(errMsgs, "worktree is dirty, cannot restore HEAD")
+				continue
+			}
+			log.Debugf("%srestore HEAD to %s", v.Prompt(), c.Old)
+			if err := v.restoreHead(c.Old); err != nil {
+				errMsgs = append(errMsgs, fmt.Sprintf("fail to restore HEAD: %s", err))
+			}
+			continue
+		}
+
+		// Current branch is restored by "git reset --keep", so that
+		// index and worktree are restored too.
+		if current[refHead] == "ref: "+c.Ref {
+			if c.Old == "" {
+				errMsgs = append(errMsgs,
+					fmt.Sprintf("cannot delete '%s', which is checked out", c.Ref))
+				continue
+			}
+			if !v.IsClean() {
+				errMsgs = append(errMsgs,
+					fmt.Sprintf("worktree is dirty, cannot restore '%s'", c.Ref))
+				continue
+			}
+			log.Debugf("%sreset %s to %s", v.Prompt(), c.Ref, c.Old)
+			cmdArgs := []string{GIT, "reset", "-q", "--keep", c.Old}
+			if err := executeCommandIn(v.WorkDir, cmdArgs); err != nil {
+				errMsgs = append(errMsgs, fmt.Sprintf("fail to restore '%s': %s", c.Ref, err))
+			}
+			continue
+		}
+
+		cmdArgs := []string{GIT, "update-ref", "-m", "repo: undo"}
+		if c.Old == "" {
+			cmdArgs = append(cmdArgs, "-d", c.Ref)
+		} else {
+			cmdArgs = append(cmdArgs, c.Ref, c.Old)
+		}
+		log.Debugf("%srestore %s to %s", v.Prompt(), c.Ref, c.Old)
+		if err := executeCommandIn(v.RepoDir(), cmdArgs); err != nil {
+			errMsgs = append(errMsgs, fmt.Sprintf("fail to restore '%s': %s", c.Ref, err))
+		}
+	}
+	if len(errMsgs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errMsgs, "\n"))
+	}
+	return nil
+}
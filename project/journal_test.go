@@ -0,0 +1,48 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffRefSnapshot(t *testing.T) {
+	assert := assert.New(t)
+
+	before := map[string]string{
+		"HEAD":                "ref: refs/heads/topic1",
+		"refs/heads/master":   "1111111111111111111111111111111111111111",
+		"refs/heads/topic1":   "2222222222222222222222222222222222222222",
+		"refs/heads/topic2":   "3333333333333333333333333333333333333333",
+		"refs/heads/unchange": "4444444444444444444444444444444444444444",
+	}
+	after := map[string]string{
+		"HEAD":                "5555555555555555555555555555555555555555",
+		"refs/heads/master":   "6666666666666666666666666666666666666666",
+		"refs/heads/new":      "7777777777777777777777777777777777777777",
+		"refs/heads/topic1":   "2222222222222222222222222222222222222222",
+		"refs/heads/unchange": "4444444444444444444444444444444444444444",
+	}
+
+	assert.Equal([]RefChange{
+		{
+			Ref: "refs/heads/master",
+			Old: "1111111111111111111111111111111111111111",
+			New: "6666666666666666666666666666666666666666",
+		},
+		{
+			Ref: "refs/heads/new",
+			New: "7777777777777777777777777777777777777777",
+		},
+		{
+			Ref: "refs/heads/topic2",
+			Old: "3333333333333333333333333333333333333333",
+		},
+		{
+			Ref: "HEAD",
+			Old: "ref: refs/heads/topic1",
+			New: "5555555555555555555555555555555555555555",
+		},
+	}, DiffRefSnapshot(before, after))
+	assert.Equal([]RefChange{}, DiffRefSnapshot(before, before))
+}
@@ -418,22 +418,30 @@ func (v Project) InstallGerritHooks() error {
 }
 
 // CopyFile copy files from src to dest.
+//
+// Src is relative to the worktree of the project, and dest is relative
+// to the top dir of the workspace. Both are checked by ResolvePath, so a
+// hostile manifest cannot write outside the workspace.
 func (v Project) CopyFile(src, dest string) error {
-	srcAbs := filepath.Clean(filepath.Join(v.WorkDir, src))
-	destAbs := filepath.Clean(filepath.Join(v.TopDir(), dest))
-
-	if !strings.HasPrefix(srcAbs, v.TopDir()) {
-		return fmt.Errorf("fail to copy file, src file '%s' beyond repo root '%s'", src, v.TopDir())
+	srcAbs, err := ResolvePath(v.WorkDir, src, false)
+	if err != nil {
+		return fmt.Errorf("bad src: %s", err)
 	}
-
-	if !strings.HasPrefix(destAbs, v.TopDir()) {
-		return fmt.Errorf("fail to copy file, dest file '%s' beyond repo root '%s'", dest, v.TopDir())
+	destAbs, err := ResolvePath(v.TopDir(), dest, false)
+	if err != nil {
+		return fmt.Errorf("bad dest: %s", err)
 	}
 
 	finfo, err := os.Stat(srcAbs)
 	if err != nil {
 		return nil
 	}
+	if finfo.IsDir() {
+		return fmt.Errorf("src '%s' is a directory", src)
+	}
+	if path.IsDir(destAbs) {
+		return fmt.Errorf("dest '%s' is a directory", dest)
+	}
 
 	if !path.Exist(filepath.Dir(destAbs)) {
 		os.MkdirAll(filepath.Dir(destAbs), 0755)
@@ -455,23 +463,37 @@ func (v Project) CopyFile(src, dest string) error {
 	if err != nil {
 		return fmt.Errorf("fail to copy file: %s", err)
 	}
+	destFile.Close()
+	recordWrittenFile(destAbs)
 	return nil
 }
 
-// LinkFile copy files from src to dest.
+// LinkFile creates symlink dest which points to src.
+//
+// Src is relative to the worktree of the project, and "." means the
+// worktree itself. Dest is relative to the top dir of the workspace, and
+// may be an existing symlink which will be replaced.
 func (v Project) LinkFile(src, dest string) error {
-	srcAbs := filepath.Clean(filepath.Join(v.WorkDir, src))
-	destAbs := filepath.Clean(filepath.Join(v.TopDir(), dest))
+	var (
+		srcAbs  string
+		destAbs string
+		err     error
+	)
 
-	if !strings.HasPrefix(srcAbs, v.TopDir()) {
-		return fmt.Errorf("fail to copy file, src file '%s' beyond repo root '%s'", src, v.TopDir())
+	if filepath.Clean(src) == "." {
+		srcAbs = v.WorkDir
+	} else {
+		srcAbs, err = ResolvePath(v.WorkDir, src, false)
+		if err != nil {
+			return fmt.Errorf("bad src: %s", err)
+		}
 	}
-
-	if !strings.HasPrefix(destAbs, v.TopDir()) {
-		return fmt.Errorf("fail to copy file, dest file '%s' beyond repo root '%s'", dest, v.TopDir())
+	destAbs, err = ResolvePath(v.TopDir(), dest, true)
+	if err != nil {
+		return fmt.Errorf("bad dest: %s", err)
 	}
 
-	_, err := os.Stat(srcAbs)
+	_, err = os.Stat(srcAbs)
 	if err != nil {
 		return nil
 	}
@@ -484,13 +506,22 @@ func (v Project) LinkFile(src, dest string) error {
 	if err != nil {
 		srcRel = srcAbs
 	}
-	if path.Exist(destAbs) {
+	if fi, err := os.Lstat(destAbs); err == nil {
+		if fi.IsDir() {
+			return fmt.Errorf("dest '%s' is a directory", dest)
+		}
 		os.Remove(destAbs)
 	}
 	if cap.CanSymlink() {
-		return os.Symlink(srcRel, destAbs)
+		err = os.Symlink(srcRel, destAbs)
+	} else {
+		err = os.Link(srcAbs, destAbs)
 	}
-	return os.Link(srcRel, destAbs)
+	if err != nil {
+		return err
+	}
+	recordWrittenFile(destAbs)
+	return nil
 }
 
 // CopyAndLinkFiles copies and links files.
@@ -1,6 +1,8 @@
 package project
 
 import (
+	"path/filepath"
+
 	"github.com/alibaba/git-repo-go/config"
 	"github.com/alibaba/git-repo-go/manifest"
 	"github.com/jiangxin/goconfig"
@@ -148,3 +150,23 @@ func NewManifestProject(topDir, mURL string) *ManifestProject {
 	}
 	return &p
 }
+
+// NewSubmanifestProject returns manifest project of submanifest, which is
+// checked out in ".repo/submanifests/<path>/manifests".
+func NewSubmanifestProject(sm *manifest.Submanifest, s *RepoSettings) *Project {
+	mp := manifest.Project{
+		Name:           sm.ProjectName(),
+		Path:           sm.RelPath(),
+		RemoteName:     sm.ManifestRemote.Name,
+		Revision:       sm.Revision,
+		ManifestRemote: sm.ManifestRemote,
+	}
+	p := NewProject(&mp, s, nil)
+
+	repoDir := sm.RepoDir(filepath.Join(s.TopDir, config.DotRepo))
+	p.WorkDir = filepath.Join(repoDir, config.Manifests)
+	p.DotGit = filepath.Join(p.WorkDir, ".git")
+	p.GitDir = filepath.Join(repoDir, config.ManifestsDotGit)
+	p.ObjectsGitDir = p.GitDir
+	return p
+}
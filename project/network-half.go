@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/alibaba/git-repo-go/common"
 	"github.com/alibaba/git-repo-go/file"
@@ -12,7 +13,9 @@ import (
 	log "github.com/jiangxin/multi-log"
 )
 
-// FetchOptions is options for git fetch.
+// FetchOptions is options for git fetch. ShowProgress is only set if
+// fetches do not run in parallel and stderr is a terminal, because
+// progress lines of different projects would overwrite each other.
 type FetchOptions struct {
 	RepoSettings
 
@@ -20,10 +23,39 @@ type FetchOptions struct {
 	IsNew             bool
 	CurrentBranchOnly bool
 	CloneBundle       bool
+	ShowProgress      bool
 	ForceSync         bool
 	NoTags            bool
 	OptimizedFetch    bool
 	Prune             bool
+	Unshallow         bool
+	Deepen            int
+	RetryFetches      int
+	RetryDelay        time.Duration
+}
+
+const (
+	defaultFetchRetryDelay = time.Second
+	maxFetchRetryDelay     = time.Minute
+)
+
+// fetchRetrySleep waits before retry of fetch, and can be replaced in tests.
+var fetchRetrySleep = time.Sleep
+
+// fetchRetryDelay returns delay before the nth (from 0) retry, which is
+// doubled for each retry.
+func fetchRetryDelay(base time.Duration, n int) time.Duration {
+	if base <= 0 {
+		base = defaultFetchRetryDelay
+	}
+	delay := base
+	for i := 0; i < n && delay < maxFetchRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxFetchRetryDelay {
+		delay = maxFetchRetryDelay
+	}
+	return delay
 }
 
 // Fetch runs git-fetch on repository.
@@ -59,10 +91,6 @@ func (v *Repository) Fetch(remote string, o *FetchOptions) error {
 		hasAlternates = true
 	}
 
-	if o.CloneBundle && !hasAlternates {
-		v.applyCloneBundle()
-	}
-
 	if v.RemoteURL == "" {
 		return fmt.Errorf("don't know where to fetch repo %s from remote %s", v.Name, remote)
 	}
@@ -82,13 +110,24 @@ func (v *Repository) Fetch(remote string, o *FetchOptions) error {
 		return nil
 	}
 
-	if o.Mirror && o.Depth > 0 {
-		o.Depth = 0
+	// Depth is only used for the first fetch, and later fetches keep
+	// shallow repository shallow, unless unshallow or deepen explicitly.
+	depth := 0
+	isShallow := v.IsShallow()
+	if !o.Mirror && !isShallow && v.isEmpty() {
+		depth = v.ShallowDepth(o.Depth)
+	}
+	// Bootstrap new repository from clone.bundle, which has full history.
+	if o.CloneBundle && !hasAlternates && depth == 0 && !isShallow {
+		v.applyCloneBundle(o)
 	}
-	if o.Depth > 0 {
-		o.CurrentBranchOnly = true
+	currentBranchOnly := o.CurrentBranchOnly
+	noTags := o.NoTags
+	if depth > 0 || (isShallow && !o.Unshallow) {
+		currentBranchOnly = true
+		noTags = true
 	}
-	if o.CurrentBranchOnly {
+	if currentBranchOnly && !(isShallow && (o.Unshallow || o.Deepen > 0)) {
 		if isSha || isTag {
 			if v.RevisionIsValid(revision) {
 				return nil
@@ -101,10 +140,12 @@ func (v *Repository) Fetch(remote string, o *FetchOptions) error {
 		"fetch",
 	}
 
-	if o.Depth > 0 {
-		cmdArgs = append(cmdArgs, fmt.Sprintf("--depth=%d", o.Depth))
-	} else if path.Exist(filepath.Join(v.RepoDir(), "shallow")) {
+	if depth > 0 {
+		cmdArgs = append(cmdArgs, fmt.Sprintf("--depth=%d", depth))
+	} else if isShallow && o.Unshallow {
 		cmdArgs = append(cmdArgs, "--unshallow")
+	} else if isShallow && o.Deepen > 0 {
+		cmdArgs = append(cmdArgs, fmt.Sprintf("--deepen=%d", o.Deepen))
 	}
 
 	if o.Quiet {
@@ -112,7 +153,7 @@ func (v *Repository) Fetch(remote string, o *FetchOptions) error {
 
 	}
 
-	if o.NoTags || o.Depth > 0 {
+	if noTags {
 		cmdArgs = append(cmdArgs, "--no-tags")
 	} else {
 		cmdArgs = append(cmdArgs, "--tags")
@@ -128,7 +169,7 @@ func (v *Repository) Fetch(remote string, o *FetchOptions) error {
 	}
 
 	cmdArgs = append(cmdArgs, v.RemoteURL)
-	if o.CurrentBranchOnly {
+	if currentBranchOnly {
 		if isSha {
 			cmdArgs = append(cmdArgs, revision)
 		} else if isTag {
@@ -152,7 +193,16 @@ func (v *Repository) Fetch(remote string, o *FetchOptions) error {
 	}
 	log.Debugf("%sfetching using command: %s", v.Prompt(), strings.Join(cmdArgs, " "))
 
-	err = executeCommandIn(v.RepoDir(), cmdArgs)
+	for i := 0; ; i++ {
+		err = executeCommandIn(v.RepoDir(), cmdArgs)
+		if err == nil || i >= o.RetryFetches {
+			break
+		}
+		delay := fetchRetryDelay(o.RetryDelay, i)
+		log.Warnf("%sfail to fetch, retry in %s (%d/%d)",
+			v.Prompt(), delay, i+1, o.RetryFetches)
+		fetchRetrySleep(delay)
+	}
 	if err != nil {
 		return fmt.Errorf("fail to fetch project '%s': %s", v.Name, err)
 	}
@@ -0,0 +1,164 @@
+package project
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func gitCommitIn(t *testing.T, dir string, n int) {
+	for i := 0; i < n; i++ {
+		cmd := exec.Command("git", "-c", "user.name=Tester", "-c", "user.email=tester@example.com",
+			"commit", "--allow-empty", "-q", "-m", fmt.Sprintf("commit #%d", i))
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("fail to commit: %s\n%s", err, out)
+		}
+	}
+}
+
+func TestFetchWithCloneDepth(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// Upstream repository "foo" with 5 commits.
+	upstream := filepath.Join(tmpdir, "foo.git")
+	cmd := exec.Command("git", "init", "-q", upstream)
+	assert.Nil(cmd.Run())
+	assert.Nil(exec.Command("git", "-C", upstream, "checkout", "-q", "-b", "master").Run())
+	gitCommitIn(t, upstream, 5)
+
+	xmlProject := manifest.Project{
+		Name:       "foo",
+		Path:       "foo",
+		RemoteName: "origin",
+		Revision:   "master",
+		CloneDepth: "2",
+	}
+	xmlProject.ManifestRemote = &manifest.Remote{
+		Name:  "origin",
+		Fetch: "..",
+	}
+	workdir := filepath.Join(tmpdir, "work")
+	p := NewProject(&xmlProject,
+		&RepoSettings{
+			TopDir:      workdir,
+			ManifestURL: "file://" + filepath.Join(tmpdir, "mirror", "manifests"),
+		}, nil)
+
+	countCommits := func() int {
+		commits, err := p.Revlist("refs/remotes/origin/master")
+		assert.Nil(err)
+		return len(commits)
+	}
+
+	// First fetch uses clone-depth, which overrides the global depth.
+	assert.Nil(p.SyncNetworkHalf(&FetchOptions{Quiet: true, RepoSettings: RepoSettings{Depth: 4}}))
+	assert.True(p.IsShallow())
+	assert.Equal(2, countCommits())
+
+	// Later fetch keeps project shallow.
+	gitCommitIn(t, upstream, 1)
+	assert.Nil(p.SyncNetworkHalf(&FetchOptions{Quiet: true}))
+	assert.True(p.IsShallow())
+	assert.Equal(3, countCommits())
+
+	// Deepen history of shallow project.
+	assert.Nil(p.SyncNetworkHalf(&FetchOptions{Quiet: true, Deepen: 1}))
+	assert.True(p.IsShallow())
+	assert.Equal(4, countCommits())
+
+	// Unshallow.
+	assert.Nil(p.SyncNetworkHalf(&FetchOptions{Quiet: true, Unshallow: true}))
+	assert.False(p.IsShallow())
+	assert.Equal(6, countCommits())
+
+	// Complete repository will not turn to shallow.
+	gitCommitIn(t, upstream, 1)
+	assert.Nil(p.SyncNetworkHalf(&FetchOptions{Quiet: true, RepoSettings: RepoSettings{Depth: 1}}))
+	assert.False(p.IsShallow())
+	assert.Equal(7, countCommits())
+}
+
+func TestShallowDepth(t *testing.T) {
+	assert := assert.New(t)
+
+	repo := Repository{}
+	assert.Equal(0, repo.ShallowDepth(0))
+	assert.Equal(3, repo.ShallowDepth(3))
+	repo.CloneDepth = "1"
+	assert.Equal(1, repo.ShallowDepth(3))
+	repo.CloneDepth = "bad"
+	assert.Equal(3, repo.ShallowDepth(3))
+}
+
+func TestFetchRetry(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	xmlProject := manifest.Project{
+		Name:       "foo",
+		Path:       "foo",
+		RemoteName: "origin",
+		Revision:   "master",
+	}
+	xmlProject.ManifestRemote = &manifest.Remote{
+		Name:  "origin",
+		Fetch: "..",
+	}
+	p := NewProject(&xmlProject,
+		&RepoSettings{
+			TopDir:      filepath.Join(tmpdir, "work"),
+			ManifestURL: "file://" + filepath.Join(tmpdir, "mirror", "manifests"),
+		}, nil)
+
+	// Upstream repository does not exist.
+	assert.NotNil(p.SyncNetworkHalf(&FetchOptions{Quiet: true}))
+
+	// Upstream repository is ready before the second retry.
+	delays := []time.Duration{}
+	fetchRetrySleep = func(delay time.Duration) {
+		delays = append(delays, delay)
+		if len(delays) == 2 {
+			upstream := filepath.Join(tmpdir, "foo.git")
+			assert.Nil(exec.Command("git", "init", "-q", upstream).Run())
+			assert.Nil(exec.Command("git", "-C", upstream, "checkout", "-q", "-b", "master").Run())
+			gitCommitIn(t, upstream, 1)
+		}
+	}
+	defer func() { fetchRetrySleep = time.Sleep }()
+	assert.Nil(p.SyncNetworkHalf(&FetchOptions{
+		Quiet:        true,
+		RetryFetches: 3,
+		RetryDelay:   200 * time.Millisecond,
+	}))
+	assert.Equal([]time.Duration{200 * time.Millisecond, 400 * time.Millisecond}, delays)
+}
+
+func TestFetchRetryDelay(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(time.Second, fetchRetryDelay(0, 0))
+	assert.Equal(2*time.Second, fetchRetryDelay(0, 1))
+	assert.Equal(4*time.Second, fetchRetryDelay(0, 2))
+	assert.Equal(30*time.Millisecond, fetchRetryDelay(15*time.Millisecond, 1))
+	assert.Equal(time.Minute, fetchRetryDelay(0, 10))
+	assert.Equal(time.Minute, fetchRetryDelay(0, 100))
+}
@@ -78,6 +78,31 @@ func (v Project) ManifestURL() string {
 	return v.Settings.ManifestURL
 }
 
+// GetAnnotation returns value of annotation, and whether it is defined.
+func (v Project) GetAnnotation(name string) (string, bool) {
+	for _, a := range v.Annotations {
+		if a.Name == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// Environ returns environments of project, which are used to run
+// commands in the project. Annotations of project are exported
+// with prefix "REPO__".
+func (v Project) Environ() []string {
+	env := []string{
+		"REPO_PROJECT=" + v.Name,
+		"REPO_PATH=" + v.Path,
+		"REPO_REMOTE=" + v.RemoteName,
+	}
+	for _, a := range v.Annotations {
+		env = append(env, "REPO__"+a.Name+"="+a.Value)
+	}
+	return env
+}
+
 func referencePath(mp *manifest.Project, s *RepoSettings) string {
 	var (
 		rdir = ""
@@ -390,6 +415,49 @@ func (v Project) UserEmail() string {
 	return ""
 }
 
+// fillDefaults fills missing fields of repository from its remote and
+// the default element of manifest.
+func (v *Repository) fillDefaults(m *manifest.Manifest) {
+	var (
+		remote *manifest.Remote
+		def    = &manifest.Default{}
+	)
+
+	if m == nil {
+		return
+	}
+	if m.Default != nil {
+		def = m.Default
+	}
+	if v.RemoteName == "" {
+		v.RemoteName = def.RemoteName
+	}
+	if v.ManifestRemote != nil && v.ManifestRemote.Name == v.RemoteName {
+		remote = v.ManifestRemote
+	} else {
+		remote = m.GetRemote(v.RemoteName)
+	}
+
+	// Revision of remote takes precedence over the default revision.
+	defaultRevision := def.Revision
+	if remote != nil && remote.Revision != "" {
+		defaultRevision = remote.Revision
+	}
+	if v.Revision == "" {
+		v.Revision = defaultRevision
+	}
+	if v.DestBranch == "" {
+		v.DestBranch = def.DestBranch
+	}
+	if v.Upstream == "" {
+		v.Upstream = def.Upstream
+	}
+	if (v.Revision == "" || common.IsImmutable(v.Revision)) &&
+		!common.IsImmutable(defaultRevision) {
+		v.ManifestDefaultRevision = defaultRevision
+	}
+}
+
 // NewProject returns a project: project worktree with a bared repo and a seperate repository.
 func NewProject(mp *manifest.Project, s *RepoSettings, m *manifest.Manifest) *Project {
 	var (
@@ -445,30 +513,7 @@ func NewProject(mp *manifest.Project, s *RepoSettings, m *manifest.Manifest) *Pr
 		Remotes:   NewRemoteMap(),
 	}
 
-	if m != nil {
-		if repo.RemoteName == "" {
-			repo.RemoteName = m.Default.RemoteName
-		}
-		if repo.Revision == "" {
-			if m.Default.Revision != "" {
-				repo.Revision = m.Default.Revision
-			}
-		}
-		if repo.DestBranch == "" {
-			if m.Default.DestBranch != "" {
-				repo.DestBranch = m.Default.DestBranch
-			}
-		}
-		if repo.Upstream == "" {
-			if m.Default.Upstream != "" {
-				repo.Upstream = m.Default.DestBranch
-			}
-		}
-		if (repo.Revision == "" || common.IsImmutable(repo.Revision)) &&
-			!common.IsImmutable(m.Default.Revision) {
-			repo.ManifestDefaultRevision = m.Default.Revision
-		}
-	}
+	repo.fillDefaults(m)
 
 	p := Project{
 		Repository: repo,
@@ -511,29 +556,7 @@ func NewMirrorProject(mp *manifest.Project, s *RepoSettings, m *manifest.Manifes
 		Reference: referencePath(mp, s),
 	}
 
-	if m != nil {
-		if repo.RemoteName == "" {
-			repo.RemoteName = m.Default.RemoteName
-		}
-		if repo.Revision == "" {
-			if m.Default.Revision != "" {
-				repo.Revision = m.Default.Revision
-			}
-		}
-		if repo.DestBranch == "" {
-			if m.Default.DestBranch != "" {
-				repo.DestBranch = m.Default.DestBranch
-			}
-		}
-		if repo.Upstream == "" {
-			if m.Default.Upstream != "" {
-				repo.Upstream = m.Default.DestBranch
-			}
-		}
-		if repo.Revision == "" || common.IsImmutable(repo.Revision) {
-			repo.ManifestDefaultRevision = m.Default.Revision
-		}
-	}
+	repo.fillDefaults(m)
 
 	p := Project{
 		Repository: repo,
@@ -148,6 +148,61 @@ func TestProjectMatchGroups(t *testing.T) {
 	assert.True(p.MatchGroups(mGroups))
 }
 
+func TestNewProjectWithDefaults(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &manifest.Manifest{
+		Remotes: []manifest.Remote{
+			manifest.Remote{
+				Name:     "origin",
+				Fetch:    "..",
+				Revision: "remote-master",
+			},
+			manifest.Remote{
+				Name:  "other",
+				Fetch: "..",
+			},
+		},
+		Default: &manifest.Default{
+			RemoteName: "origin",
+			Revision:   "default-master",
+			DestBranch: "default-dest",
+			Upstream:   "default-upstream",
+		},
+	}
+	s := &RepoSettings{
+		TopDir:      "/path/of/work",
+		ManifestURL: "https://example.com/manifest",
+	}
+	newProject := func(remote, revision string) *Project {
+		xmlProject := manifest.Project{
+			Name:       "my/foo",
+			Path:       "dir/foo",
+			RemoteName: remote,
+			Revision:   revision,
+		}
+		xmlProject.ManifestRemote = m.GetRemote(remote)
+		return NewProject(&xmlProject, s, m)
+	}
+
+	// Use revision of remote.
+	p := newProject("", "")
+	assert.Equal("origin", p.RemoteName)
+	assert.Equal("remote-master", p.Revision)
+	assert.Equal("default-dest", p.DestBranch)
+	assert.Equal("default-upstream", p.Upstream)
+	assert.Equal("", p.ManifestDefaultRevision)
+
+	// No revision of remote, use default revision.
+	p = newProject("other", "")
+	assert.Equal("default-master", p.Revision)
+
+	// Fixed revision, tracking revision of remote.
+	p = newProject("origin", "refs/tags/v1.0.0")
+	assert.Equal("refs/tags/v1.0.0", p.Revision)
+	assert.Equal("remote-master", p.ManifestDefaultRevision)
+}
+
 func TestIndexByName(t *testing.T) {
 	assert := assert.New(t)
 	projects := []*Project{
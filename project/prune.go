@@ -0,0 +1,364 @@
+package project
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	log "github.com/jiangxin/multi-log"
+)
+
+// How commits of a local branch are merged into upstream.
+const (
+	MergedByAncestry   = "merged"
+	MergedByCherryPick = "cherry-picked"
+	MergedByChangeID   = "change-id-matched"
+	MergedBySquash     = "squash-merged"
+
+	// Branch has no commits of its own, such as a new branch created by
+	// `git repo start`, which is not taken as merged.
+	MergedByNoCommits = "no-commits"
+)
+
+// changeIDPattern matches Change-Id trailer in commit message.
+var changeIDPattern = regexp.MustCompile(`(?m)^Change-Id:\s*(I[0-9a-f]{40})\s*$`)
+
+// MergedBranch is a local branch, whose commits are all contained in its
+// upstream.
+type MergedBranch struct {
+	Branch
+
+	Upstream  string
+	MergedBy  string
+	IsCurrent bool
+	Backup    string
+}
+
+// gitOutput runs git command in repository, and returns its output.
+func (v Repository) gitOutput(stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command(GIT, args...)
+	cmd.Dir = v.RepoDir()
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("fail to run git %s: %s", args[0], err)
+	}
+	return out, nil
+}
+
+// patchIDs reads output of "git patch-id", and returns patch IDs.
+func patchIDs(data []byte) []string {
+	result := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 {
+			result = append(result, fields[0])
+		}
+	}
+	return result
+}
+
+// changeIDs returns Change-Id trailers of commits in revision range.
+func (v Repository) changeIDs(args ...string) (map[string]bool, error) {
+	cmdArgs := append([]string{"log", "--format=%B%x00"}, args...)
+	out, err := v.gitOutput(nil, cmdArgs...)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool)
+	for _, m := range changeIDPattern.FindAllStringSubmatch(string(out), -1) {
+		result[m[1]] = true
+	}
+	return result, nil
+}
+
+// pickedBy checks whether each commit of branch has an equivalent commit
+// in upstream, which has the same patch-id or the same Change-Id, such as
+// changes cherry-picked by Gerrit. Returns MergedByCherryPick if all are
+// matched by patch-id, or MergedByChangeID if Change-Id is used.
+func (v Repository) pickedBy(branch, upstream string) string {
+	out, err := v.gitOutput(nil, "cherry", upstream, branch)
+	if err != nil {
+		log.Debugf("%s%s", v.Prompt(), err)
+		return ""
+	}
+	// Commits which have no equivalent commits by patch-id.
+	unmatched := []string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "+ ") {
+			unmatched = append(unmatched, strings.TrimSpace(line[2:]))
+		}
+	}
+	if len(unmatched) == 0 {
+		return MergedByCherryPick
+	}
+
+	upstreamIDs, err := v.changeIDs(upstream, "--not", branch)
+	if err != nil || len(upstreamIDs) == 0 {
+		return ""
+	}
+	for _, commit := range unmatched {
+		ids, err := v.changeIDs("-1", commit)
+		if err != nil || len(ids) == 0 {
+			return ""
+		}
+		for id := range ids {
+			if !upstreamIDs[id] {
+				return ""
+			}
+		}
+	}
+	return MergedByChangeID
+}
+
+// isSquashMerged checks whether changes of branch since merge base are
+// squashed into one commit in upstream, by comparing patch-id.
+func (v Repository) isSquashMerged(branch, upstream string) bool {
+	out, err := v.gitOutput(nil, "merge-base", upstream, branch)
+	if err != nil {
+		log.Debugf("%s%s", v.Prompt(), err)
+		return false
+	}
+	base := strings.TrimSpace(string(out))
+
+	diff, err := v.gitOutput(nil, "diff-tree", "-p", "--no-color", base, branch)
+	if err != nil || len(diff) == 0 {
+		return false
+	}
+	out, err = v.gitOutput(diff, "patch-id", "--stable")
+	if err != nil {
+		return false
+	}
+	ids := patchIDs(out)
+	if len(ids) == 0 {
+		return false
+	}
+	branchPatchID := ids[0]
+
+	commits, err := v.gitOutput(nil, "rev-list", "--no-merges", base+".."+upstream)
+	if err != nil || len(commits) == 0 {
+		return false
+	}
+	diff, err = v.gitOutput(commits, "diff-tree", "--stdin", "-p", "--no-color")
+	if err != nil {
+		return false
+	}
+	out, err = v.gitOutput(diff, "patch-id", "--stable")
+	if err != nil {
+		return false
+	}
+	for _, id := range patchIDs(out) {
+		if id == branchPatchID {
+			return true
+		}
+	}
+	return false
+}
+
+// hasOwnCommits checks reflog of branch to find out whether commits are
+// created on the branch, not only moved along with upstream by sync. It is
+// only a hint, because reflog may be expired or disabled, see
+// BranchesWithOwnCommits.
+func (v Repository) hasOwnCommits(branch string) bool {
+	out, err := v.gitOutput(nil, "reflog", "show", "--format=%gs", branch, "--")
+	if err != nil {
+		log.Debugf("%s%s", v.Prompt(), err)
+		return false
+	}
+	for _, msg := range strings.Split(string(out), "\n") {
+		for _, prefix := range []string{"commit", "cherry-pick", "revert", "am"} {
+			if strings.HasPrefix(msg, prefix+":") || strings.HasPrefix(msg, prefix+" (") {
+				return true
+			}
+		}
+		if strings.HasPrefix(msg, "merge ") && !strings.HasSuffix(msg, "Fast-forward") {
+			return true
+		}
+	}
+	return false
+}
+
+// MergedBy checks whether commits of branch are all contained in upstream,
+// and returns how they are merged, or empty string if not merged. Returns
+// MergedByNoCommits if branch has no commits of its own.
+func (v Repository) MergedBy(branch, upstream string) string {
+	commits, err := v.Revlist(branch, "--not", upstream)
+	if err != nil {
+		log.Debugf("%sfail to run rev-list for %s: %s", v.Prompt(), branch, err)
+		return ""
+	}
+	if len(commits) == 0 {
+		if v.hasOwnCommits(branch) {
+			return MergedByAncestry
+		}
+		return MergedByNoCommits
+	}
+	if pickedBy := v.pickedBy(branch, upstream); pickedBy != "" {
+		return pickedBy
+	}
+	if v.isSquashMerged(branch, upstream) {
+		return MergedBySquash
+	}
+	return ""
+}
+
+// BranchesWithOwnCommits returns local branches which have commits not in
+// their upstream. It is called before fetch, so that branches merged into
+// upstream later can be told from branches without commits of their own,
+// even if they have no reflog.
+func (v Project) BranchesWithOwnCommits() map[string]bool {
+	result := make(map[string]bool)
+	if !v.Exists() {
+		return result
+	}
+	for _, b := range v.Heads() {
+		upstream, err := v.BranchUpstream(b.Name)
+		if err != nil {
+			continue
+		}
+		commits, err := v.Revlist(b.Name, "--not", upstream)
+		if err == nil && len(commits) > 0 {
+			result[b.Name] = true
+		}
+	}
+	return result
+}
+
+// BranchUpstream returns revision ID of upstream of branch. Use tracking
+// branch of branch, or revision of project if branch does not track any.
+func (v Project) BranchUpstream(branch string) (string, error) {
+	if track := v.LocalTrackBranch(branch); track != "" {
+		return v.ResolveRevision(track)
+	}
+	return v.ResolveRemoteTracking(v.Revision)
+}
+
+// BackupBranch saves branch in refs/repo-backup/ namespace with reflog,
+// so that it can be recovered after deletion, and returns the backup ref.
+func (v Project) BackupBranch(b Branch, reason string) (string, error) {
+	refname := config.RefsBackup + b.ShortName()
+	cmdArgs := []string{
+		GIT,
+		"update-ref",
+		"--create-reflog",
+		"-m",
+		reason,
+		refname,
+		b.Hash,
+	}
+	err := executeCommandIn(v.RepoDir(), cmdArgs)
+	if err != nil {
+		return "", fmt.Errorf("fail to backup branch '%s': %s", b.ShortName(), err)
+	}
+	return refname, nil
+}
+
+// PruneOptions is options for PruneMergedBranches.
+type PruneOptions struct {
+	// Branches to check, or all local branches if empty.
+	Branches []Branch
+	// Force deletes branches even if they are not merged.
+	Force bool
+	// NoCommits also deletes branches without commits of their own.
+	NoCommits bool
+	// OwnCommits holds branches known to have commits of their own, such
+	// as result of BranchesWithOwnCommits before fetch.
+	OwnCommits map[string]bool
+	// DryRun only finds branches to delete, without deleting them.
+	DryRun bool
+}
+
+// PruneMergedBranches deletes local branches which are merged into their
+// upstream, after saving them in refs/repo-backup/ namespace, and returns
+// branches pruned and branches left as is. Current branch is only deleted
+// if worktree is clean, and HEAD is detached after it is saved.
+func (v Project) PruneMergedBranches(o *PruneOptions) ([]MergedBranch, []MergedBranch, error) {
+	var (
+		pruned   = []MergedBranch{}
+		pending  = []MergedBranch{}
+		errMsgs  = []string{}
+		head     = v.GetHead()
+		branches = o.Branches
+	)
+
+	if len(branches) == 0 {
+		branches = v.Heads()
+	}
+	for _, branch := range branches {
+		b := MergedBranch{
+			Branch:    branch,
+			IsCurrent: branch.Name == head,
+		}
+		if !o.Force {
+			if b.IsCurrent && !v.IsClean() {
+				log.Debugf("%sworktree is dirty, keep current branch %s",
+					v.Prompt(),
+					b.ShortName())
+				pending = append(pending, b)
+				continue
+			}
+			upstream, err := v.BranchUpstream(b.Name)
+			if err != nil {
+				log.Debugf("%scannot find upstream of %s: %s", v.Prompt(), b.ShortName(), err)
+				pending = append(pending, b)
+				continue
+			}
+			b.Upstream = upstream
+			b.MergedBy = v.MergedBy(b.Name, upstream)
+			if b.MergedBy == MergedByNoCommits && o.OwnCommits[b.Name] {
+				b.MergedBy = MergedByAncestry
+			}
+			if b.MergedBy == "" || (b.MergedBy == MergedByNoCommits && !o.NoCommits) {
+				pending = append(pending, b)
+				continue
+			}
+		}
+		if o.DryRun {
+			pruned = append(pruned, b)
+			continue
+		}
+
+		reason := "prune: abandon"
+		if b.MergedBy != "" {
+			reason = fmt.Sprintf("prune: %s into %s", b.MergedBy, b.Upstream)
+		}
+		backup, err := v.BackupBranch(b.Branch, reason)
+		if err != nil {
+			errMsgs = append(errMsgs, err.Error())
+			pending = append(pending, b)
+			continue
+		}
+		if b.IsCurrent {
+			if err := v.DetachHead(); err != nil {
+				errMsgs = append(errMsgs,
+					fmt.Sprintf("fail to detach from branch '%s': %s", b.ShortName(), err))
+				pending = append(pending, b)
+				continue
+			}
+		}
+		result := v.ExecuteCommand(GIT, "branch", "-D", b.ShortName())
+		if result.Error != nil {
+			errMsgs = append(errMsgs,
+				fmt.Sprintf("fail to delete branch '%s': %s", b.ShortName(), result.Stderr()))
+			pending = append(pending, b)
+			continue
+		}
+		b.Backup = backup
+		pruned = append(pruned, b)
+	}
+
+	if len(pruned) > 0 && !o.DryRun {
+		v.CleanPublishedCache()
+	}
+	if len(errMsgs) > 0 {
+		return pruned, pending, fmt.Errorf("%s", strings.Join(errMsgs, "\n"))
+	}
+	return pruned, pending, nil
+}
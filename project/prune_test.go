@@ -0,0 +1,99 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergedBy(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	git := func(args ...string) {
+		args = append([]string{
+			"-c", "user.name=Tester",
+			"-c", "user.email=tester@example.com",
+		}, args...)
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tmpdir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("fail to run git %v: %s\n%s", args, err, out)
+		}
+	}
+	commitWithMessage := func(name, content, message string) {
+		assert.Nil(ioutil.WriteFile(filepath.Join(tmpdir, name), []byte(content), 0644))
+		git("add", name)
+		git("commit", "-q", "-m", message)
+	}
+	commit := func(name string) {
+		commitWithMessage(name, name+"\n", "add "+name)
+	}
+	changeID1 := "Change-Id: I0123456789abcdef0123456789abcdef01234567"
+	changeID2 := "Change-Id: I89abcdef0123456789abcdef0123456789abcdef"
+
+	git("init", "-q")
+	git("checkout", "-q", "-b", "master")
+	commit("base")
+	git("branch", "merged")
+	git("branch", "squashed")
+	git("branch", "cherry")
+	git("branch", "unmerged")
+	git("branch", "partial")
+	git("branch", "gerrit")
+	git("branch", "gerrit-unmerged")
+	git("branch", "new")
+	git("branch", "synced")
+
+	git("checkout", "-q", "merged")
+	commit("a")
+	git("checkout", "-q", "squashed")
+	commit("b1")
+	commit("b2")
+	git("checkout", "-q", "cherry")
+	commit("c")
+	git("checkout", "-q", "unmerged")
+	commit("d")
+	git("checkout", "-q", "partial")
+	commit("e1")
+	commit("e2")
+	git("checkout", "-q", "gerrit")
+	commit("f1")
+	commitWithMessage("f2", "f2\n", "add f2\n\n"+changeID1)
+	git("checkout", "-q", "gerrit-unmerged")
+	commitWithMessage("g", "g\n", "add g\n\n"+changeID2)
+
+	git("checkout", "-q", "master")
+	git("merge", "-q", "--ff-only", "merged")
+	git("merge", "-q", "--squash", "squashed")
+	git("commit", "-q", "-m", "squash b1 and b2")
+	git("cherry-pick", "cherry")
+	git("cherry-pick", "partial~1")
+	// Cherry-picked by Gerrit, and amended during code review.
+	git("cherry-pick", "gerrit~1")
+	commitWithMessage("f2", "f2 amended\n", "add f2 (amended)\n\n"+changeID1)
+	// Fast-forwarded to upstream by sync, without commits of its own.
+	git("checkout", "-q", "synced")
+	git("merge", "-q", "--ff-only", "master")
+	git("checkout", "-q", "master")
+
+	repo := Repository{DotGit: filepath.Join(tmpdir, ".git")}
+	assert.Equal(MergedByAncestry, repo.MergedBy("merged", "master"))
+	assert.Equal(MergedBySquash, repo.MergedBy("squashed", "master"))
+	assert.Equal(MergedByCherryPick, repo.MergedBy("cherry", "master"))
+	assert.Equal("", repo.MergedBy("unmerged", "master"))
+	assert.Equal("", repo.MergedBy("partial", "master"))
+	assert.Equal(MergedByChangeID, repo.MergedBy("gerrit", "master"))
+	assert.Equal("", repo.MergedBy("gerrit-unmerged", "master"))
+	assert.Equal(MergedByNoCommits, repo.MergedBy("new", "master"))
+	assert.Equal(MergedByNoCommits, repo.MergedBy("synced", "master"))
+}
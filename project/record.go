@@ -0,0 +1,136 @@
+package project
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/path"
+)
+
+// Record is summary of project for structured output, such as JSON.
+type Record struct {
+	Name        string            `json:"name"`
+	Path        string            `json:"path"`
+	Remote      string            `json:"remote,omitempty"`
+	Revision    string            `json:"revision,omitempty"`
+	Branch      string            `json:"branch,omitempty"`
+	Ahead       int               `json:"ahead"`
+	Behind      int               `json:"behind"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Files       *StatusFiles      `json:"files,omitempty"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// UpstreamRevision returns revision to compare with the current branch,
+// which is the tracking branch, or remote tracking branch of manifest
+// revision.
+func (v Project) UpstreamRevision() string {
+	return v.upstreamRevisionOf("")
+}
+
+func (v Project) upstreamRevisionOf(branch string) string {
+	var (
+		rev string
+		err error
+	)
+
+	track := v.LocalTrackBranch(branch)
+	if track != "" {
+		rev, err = v.ResolveRevision(track)
+		if err == nil {
+			return rev
+		}
+	}
+	if v.Revision != "" {
+		rev, err = v.ResolveRemoteTracking(v.Revision)
+		if err == nil {
+			return rev
+		}
+	}
+	return ""
+}
+
+// AheadBehind returns number of commits ahead of and behind upstream.
+func (v Project) AheadBehind() (int, int) {
+	upstream := v.UpstreamRevision()
+	if upstream == "" {
+		return 0, 0
+	}
+	result := v.ExecuteCommand(GIT,
+		"rev-list",
+		"--left-right",
+		"--count",
+		"HEAD..."+upstream)
+	if result.Error != nil {
+		return 0, 0
+	}
+	fields := strings.Fields(result.Stdout())
+	if len(fields) != 2 {
+		return 0, 0
+	}
+	ahead, _ := strconv.Atoi(fields[0])
+	behind, _ := strconv.Atoi(fields[1])
+	return ahead, behind
+}
+
+// UnpublishedCommits returns commits (in oneline format) of branch, which
+// are neither in upstream nor published for review.
+func (v Project) UnpublishedCommits(branch string) []string {
+	if branch == "" {
+		branch = v.GetHead()
+		if branch == "" {
+			return nil
+		}
+	}
+	upstream := v.upstreamRevisionOf(branch)
+	if upstream == "" {
+		return nil
+	}
+
+	cmdArgs := []string{
+		GIT,
+		"log",
+		"--oneline",
+		config.RefsHeads + strings.TrimPrefix(branch, config.RefsHeads),
+		"--not",
+		upstream,
+	}
+	if pub := v.PublishedRevision(branch); pub != "" {
+		cmdArgs = append(cmdArgs, pub)
+	}
+	result := v.ExecuteCommand(cmdArgs...)
+	if result.Error != nil {
+		return nil
+	}
+	output := strings.TrimSpace(result.Stdout())
+	if output == "" {
+		return nil
+	}
+	return strings.Split(output, "\n")
+}
+
+// Record returns summary of project.
+func (v Project) Record() *Record {
+	record := Record{
+		Name:     v.Name,
+		Path:     v.Path,
+		Remote:   v.RemoteName,
+		Revision: v.Revision,
+	}
+
+	if len(v.Annotations) > 0 {
+		record.Annotations = make(map[string]string)
+		for _, a := range v.Annotations {
+			record.Annotations[a.Name] = a.Value
+		}
+	}
+
+	if v.IsMirror() || !path.Exist(v.WorkDir) {
+		return &record
+	}
+
+	record.Branch = strings.TrimPrefix(v.GetHead(), config.RefsHeads)
+	record.Ahead, record.Behind = v.AheadBehind()
+	return &record
+}
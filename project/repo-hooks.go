@@ -0,0 +1,213 @@
+package project
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/path"
+	log "github.com/jiangxin/multi-log"
+)
+
+const (
+	// HookTypePreUpload is the hook to run before upload.
+	HookTypePreUpload = "pre-upload"
+)
+
+// pythonHookDriver loads an Android style hook script and calls its
+// main() function with keyword arguments read from stdin as JSON.
+// Keyword arguments not defined in main() are dropped, unless main()
+// accepts **kwargs, so hooks written for Android repo still work.
+const pythonHookDriver = `
+import inspect, json, os, sys
+script = sys.argv[1]
+kwargs = json.load(sys.stdin)
+sys.path.insert(0, os.path.dirname(script))
+context = {"__file__": script}
+with open(script) as f:
+    exec(compile(f.read(), script, "exec"), context)
+main = context.get("main")
+if not callable(main):
+    sys.exit("%s: missing main() function" % script)
+try:
+    params = inspect.signature(main).parameters
+    if not any(p.kind == p.VAR_KEYWORD for p in params.values()):
+        kwargs = dict((k, v) for k, v in kwargs.items() if k in params)
+except (AttributeError, TypeError, ValueError):
+    pass
+main(**kwargs)
+`
+
+// RepoHookItem is a project with commits passed to the hook.
+type RepoHookItem struct {
+	Name    string
+	WorkDir string
+	Base    string
+	Head    string
+}
+
+// Range returns commit range in "<base>..<head>" format.
+func (v RepoHookItem) Range() string {
+	if v.Base == "" {
+		return v.Head
+	}
+	return v.Base + ".." + v.Head
+}
+
+// RepoHook is a hook defined by the `<repo-hooks>` element of manifest.
+// The hook script lives in the top directory of the hooks project.
+type RepoHook struct {
+	Type    string
+	Project *Project
+	Script  string
+
+	isPython bool
+}
+
+// NewRepoHook finds script of hookType in project p. Returns nil if the
+// hook is not in enabledList, or script not found.
+func NewRepoHook(hookType string, p *Project, enabledList string) *RepoHook {
+	enabled := false
+	for _, name := range strings.FieldsFunc(enabledList, func(c rune) bool {
+		return c == ',' || c == ' ' || c == '\t'
+	}) {
+		if name == hookType {
+			enabled = true
+			break
+		}
+	}
+	if !enabled || p == nil {
+		return nil
+	}
+
+	script := filepath.Join(p.WorkDir, hookType+".py")
+	if path.IsFile(script) {
+		return &RepoHook{
+			Type:     hookType,
+			Project:  p,
+			Script:   script,
+			isPython: true,
+		}
+	}
+	script = filepath.Join(p.WorkDir, hookType)
+	if fi, err := os.Stat(script); err == nil && fi.Mode().IsRegular() && fi.Mode()&0111 != 0 {
+		return &RepoHook{
+			Type:    hookType,
+			Project: p,
+			Script:  script,
+		}
+	}
+	log.Debugf("%scannot find script for hook %s", p.Prompt(), hookType)
+	return nil
+}
+
+func (v RepoHook) approvalKey() string {
+	return "repo.hooks." + v.Type + ".approvedhash"
+}
+
+// Hash returns sha256 of the hook script.
+func (v RepoHook) Hash() (string, error) {
+	data, err := ioutil.ReadFile(v.Script)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// IsApproved checks whether the current content of the hook script has
+// been approved by user.
+func (v RepoHook) IsApproved() bool {
+	hash, err := v.Hash()
+	if err != nil {
+		return false
+	}
+	return v.Project.Config().Get(v.approvalKey()) == hash
+}
+
+// HasApproval indicates the hook script has been approved before, maybe
+// with different content.
+func (v RepoHook) HasApproval() bool {
+	return v.Project.Config().Get(v.approvalKey()) != ""
+}
+
+// Approve saves hash of the hook script in config of the hooks project,
+// and user will not be asked again until the script is changed.
+func (v RepoHook) Approve() error {
+	hash, err := v.Hash()
+	if err != nil {
+		return err
+	}
+	cfg := v.Project.Config()
+	cfg.Set(v.approvalKey(), hash)
+	return v.Project.SaveConfig(cfg)
+}
+
+// Run executes hook script in top dir of workspace.
+//
+// Python script (such as "pre-upload.py") is loaded as the Android repo
+// does, and its main() function is called with keyword arguments:
+// project_list, worktree_list and commit_list. The extra commit_list is
+// only passed if main() has the argument or accepts **kwargs.
+//
+// Other executable script is called with one line for each project from
+// stdin in format: "<name> <worktree> <base>..<head>".
+func (v RepoHook) Run(items []RepoHookItem) error {
+	var (
+		cmdArgs []string
+		input   []byte
+		err     error
+	)
+
+	if v.isPython {
+		python := ""
+		for _, name := range []string{"python3", "python"} {
+			if python, err = exec.LookPath(name); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("cannot find python to run hook %s", v.Script)
+		}
+		kwargs := map[string][]string{
+			"project_list":  {},
+			"worktree_list": {},
+			"commit_list":   {},
+		}
+		for _, item := range items {
+			kwargs["project_list"] = append(kwargs["project_list"], item.Name)
+			kwargs["worktree_list"] = append(kwargs["worktree_list"], item.WorkDir)
+			kwargs["commit_list"] = append(kwargs["commit_list"], item.Range())
+		}
+		input, err = json.Marshal(kwargs)
+		if err != nil {
+			return err
+		}
+		cmdArgs = []string{python, "-c", pythonHookDriver, v.Script}
+	} else {
+		lines := []string{}
+		for _, item := range items {
+			lines = append(lines, fmt.Sprintf("%s %s %s", item.Name, item.WorkDir, item.Range()))
+		}
+		input = []byte(strings.Join(lines, "\n") + "\n")
+		cmdArgs = []string{v.Script}
+	}
+
+	log.Debugf("run %s hook: %s", v.Type, v.Script)
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	cmd.Dir = v.Project.TopDir()
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "REPO_HOOK_TYPE="+v.Type)
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("%s hook (%s) failed: %s", v.Type, v.Script, err)
+	}
+	return nil
+}
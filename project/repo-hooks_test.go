@@ -0,0 +1,162 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHooksProject(t *testing.T, topDir string) *Project {
+	xmlProject := manifest.Project{
+		Name: "hooks",
+		Path: "tools/hooks",
+	}
+	xmlProject.ManifestRemote = &manifest.Remote{
+		Name:  "origin",
+		Fetch: "..",
+	}
+	p := NewProject(&xmlProject,
+		&RepoSettings{
+			TopDir:      topDir,
+			ManifestURL: "https://example.com/manifests.git",
+		}, nil)
+	if err := os.MkdirAll(p.WorkDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.GitInit(); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestNewRepoHook(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	p := newTestHooksProject(t, tmpdir)
+	script := filepath.Join(p.WorkDir, "pre-upload")
+
+	assert.Nil(NewRepoHook(HookTypePreUpload, p, "pre-upload"), "no script")
+
+	assert.Nil(ioutil.WriteFile(script, []byte("#!/bin/sh\n"), 0644))
+	assert.Nil(NewRepoHook(HookTypePreUpload, p, "pre-upload"), "not executable")
+
+	assert.Nil(os.Chmod(script, 0755))
+	assert.Nil(NewRepoHook(HookTypePreUpload, p, ""), "not enabled")
+	assert.Nil(NewRepoHook(HookTypePreUpload, p, "commit-msg"), "not enabled")
+	hook := NewRepoHook(HookTypePreUpload, p, "commit-msg, pre-upload")
+	if assert.NotNil(hook) {
+		assert.Equal(script, hook.Script)
+		assert.False(hook.isPython)
+	}
+
+	pyScript := filepath.Join(p.WorkDir, "pre-upload.py")
+	assert.Nil(ioutil.WriteFile(pyScript, []byte("def main(**kwargs): pass\n"), 0644))
+	hook = NewRepoHook(HookTypePreUpload, p, "pre-upload")
+	if assert.NotNil(hook) {
+		assert.Equal(pyScript, hook.Script)
+		assert.True(hook.isPython)
+	}
+}
+
+func TestRepoHookApprove(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	p := newTestHooksProject(t, tmpdir)
+	script := filepath.Join(p.WorkDir, "pre-upload")
+	assert.Nil(ioutil.WriteFile(script, []byte("#!/bin/sh\nexit 0\n"), 0755))
+
+	hook := NewRepoHook(HookTypePreUpload, p, "pre-upload")
+	assert.NotNil(hook)
+	assert.False(hook.IsApproved())
+	assert.False(hook.HasApproval())
+	assert.Nil(hook.Approve())
+	assert.True(hook.IsApproved())
+	assert.True(hook.HasApproval())
+
+	// Approval is invalid if script changed.
+	assert.Nil(ioutil.WriteFile(script, []byte("#!/bin/sh\nexit 1\n"), 0755))
+	assert.False(hook.IsApproved())
+}
+
+func TestRepoHookRun(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	p := newTestHooksProject(t, tmpdir)
+	output := filepath.Join(tmpdir, "output")
+	items := []RepoHookItem{
+		{Name: "foo", WorkDir: "/work/foo", Base: "1111", Head: "2222"},
+		{Name: "bar", WorkDir: "/work/bar", Head: "3333"},
+	}
+
+	script := filepath.Join(p.WorkDir, "pre-upload")
+	assert.Nil(ioutil.WriteFile(script,
+		[]byte("#!/bin/sh\ncat >output\ntest \"$REPO_HOOK_TYPE\" = pre-upload\n"),
+		0755))
+	hook := NewRepoHook(HookTypePreUpload, p, "pre-upload")
+	assert.Nil(hook.Run(items))
+	data, err := ioutil.ReadFile(output)
+	assert.Nil(err)
+	assert.Equal("foo /work/foo 1111..2222\nbar /work/bar 3333\n", string(data))
+
+	assert.Nil(ioutil.WriteFile(script, []byte("#!/bin/sh\nexit 1\n"), 0755))
+	assert.NotNil(hook.Run(items))
+
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not found")
+	}
+	pyScript := filepath.Join(p.WorkDir, "pre-upload.py")
+	assert.Nil(ioutil.WriteFile(pyScript, []byte(`
+import sys
+
+def main(project_list, worktree_list=None, **kwargs):
+    with open("output", "w") as f:
+        f.write(",".join(project_list) + "\n")
+        f.write(",".join(worktree_list) + "\n")
+        f.write(",".join(kwargs["commit_list"]) + "\n")
+    if "bad" in project_list:
+        sys.exit(1)
+`), 0644))
+	hook = NewRepoHook(HookTypePreUpload, p, "pre-upload")
+	assert.Nil(hook.Run(items))
+	data, err = ioutil.ReadFile(output)
+	assert.Nil(err)
+	assert.Equal("foo,bar\n/work/foo,/work/bar\n1111..2222,3333\n", string(data))
+
+	items[0].Name = "bad"
+	assert.NotNil(hook.Run(items))
+
+	// Hook without **kwargs does not get commit_list.
+	assert.Nil(ioutil.WriteFile(pyScript, []byte(`
+def main(project_list, worktree_list):
+    with open("output", "w") as f:
+        f.write(",".join(project_list) + "\n")
+`), 0644))
+	items[0].Name = "foo"
+	assert.Nil(hook.Run(items))
+	data, err = ioutil.ReadFile(output)
+	assert.Nil(err)
+	assert.Equal("foo,bar\n", string(data))
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/alibaba/git-repo-go/common"
@@ -16,6 +17,7 @@ import (
 	log "github.com/jiangxin/multi-log"
 	"gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
 )
 
 const (
@@ -156,6 +158,28 @@ func (v Repository) isUnborn() bool {
 	return err != nil
 }
 
+// isEmpty checks if repository has no reference at all, e.g. has never
+// been fetched.
+func (v Repository) isEmpty() bool {
+	repo := v.Raw()
+	if repo == nil {
+		return true
+	}
+	refs, err := repo.References()
+	if err != nil {
+		return true
+	}
+	empty := true
+	refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference {
+			empty = false
+			return storer.ErrStop
+		}
+		return nil
+	})
+	return empty
+}
+
 // HasAlternates checks if repository has defined alternates.
 func (v Repository) HasAlternates() bool {
 	altFile := filepath.Join(v.GitDir, "objects", "info", "alternates")
@@ -169,10 +193,6 @@ func (v Repository) HasAlternates() bool {
 	return true
 }
 
-func (v Repository) applyCloneBundle() {
-	// TODO: download and clone from bundle file
-}
-
 // GetHead returns current branch name
 func (v Repository) GetHead() string {
 	var head string
@@ -205,6 +225,27 @@ func (v Repository) IsRebaseInProgress() bool {
 		path.Exist(filepath.Join(gitDir, ".dotest"))
 }
 
+// IsShallow checks if repository is a shallow clone.
+func (v Repository) IsShallow() bool {
+	return path.Exist(filepath.Join(v.RepoDir(), "shallow"))
+}
+
+// ShallowDepth returns depth used for the first fetch of the repository.
+// Attribute clone-depth of project overrides the global depth defined by
+// `git repo init --depth`.
+func (v Repository) ShallowDepth(defaultDepth int) int {
+	if v.CloneDepth != "" {
+		depth, err := strconv.Atoi(v.CloneDepth)
+		if err == nil && depth >= 0 {
+			return depth
+		}
+		log.Warnf("%sbad clone-depth '%s', use default depth",
+			v.Prompt(),
+			v.CloneDepth)
+	}
+	return defaultDepth
+}
+
 // RevisionIsValid returns true if revision can be resolved
 func (v Repository) RevisionIsValid(revision string) bool {
 	raw := v.Raw()
@@ -0,0 +1,68 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+)
+
+// ResolvePath joins name to root, and makes sure the result stays in root.
+//
+// Name must be a relative path without "..", must not point into ".git"
+// or ".repo", and none of its components which already exist may be a
+// symlink. If skipFinal is true, the last component is allowed to be a
+// symlink, such as dest of linkfile which is created by ourselves.
+func ResolvePath(root, name string, skipFinal bool) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("path is empty")
+	}
+	slashName := filepath.ToSlash(name)
+	if filepath.IsAbs(name) ||
+		strings.HasPrefix(slashName, "/") ||
+		filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("path '%s' is absolute", name)
+	}
+
+	parts := []string{}
+	for _, part := range strings.Split(slashName, "/") {
+		switch {
+		case part == "" || part == ".":
+			continue
+		case part == "..":
+			return "", fmt.Errorf("path '%s' contains '..'", name)
+		case strings.EqualFold(part, ".git"), strings.EqualFold(part, config.DotRepo):
+			return "", fmt.Errorf("path '%s' points into '%s'", name, part)
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "", fmt.Errorf("path '%s' points to '%s'", name, root)
+	}
+
+	// Check existing components, missing ones will be created later.
+	current := root
+	for i, part := range parts {
+		current = filepath.Join(current, part)
+		fi, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		isFinal := i == len(parts)-1
+		if fi.Mode()&os.ModeSymlink != 0 && !(skipFinal && isFinal) {
+			return "", fmt.Errorf("path '%s' traverses symlink '%s'",
+				name,
+				filepath.Join(parts[:i+1]...))
+		}
+		if !isFinal && !fi.IsDir() {
+			return "", fmt.Errorf("path '%s' traverses non-directory '%s'",
+				name,
+				filepath.Join(parts[:i+1]...))
+		}
+	}
+	return filepath.Join(root, filepath.Join(parts...)), nil
+}
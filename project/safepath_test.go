@@ -0,0 +1,146 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolvePath(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	outside := filepath.Join(tmpdir, "outside")
+	root := filepath.Join(tmpdir, "root")
+	assert.Nil(os.MkdirAll(outside, 0755))
+	assert.Nil(os.MkdirAll(filepath.Join(root, "dir"), 0755))
+	assert.Nil(ioutil.WriteFile(filepath.Join(root, "file"), []byte("file"), 0644))
+	assert.Nil(os.Symlink(outside, filepath.Join(root, "link")))
+
+	p, err := ResolvePath(root, "dir/new/file", false)
+	assert.Nil(err)
+	assert.Equal(filepath.Join(root, "dir", "new", "file"), p)
+
+	p, err = ResolvePath(root, "./dir//file", false)
+	assert.Nil(err)
+	assert.Equal(filepath.Join(root, "dir", "file"), p)
+
+	p, err = ResolvePath(root, "link", true)
+	assert.Nil(err)
+	assert.Equal(filepath.Join(root, "link"), p)
+
+	for name, msg := range map[string]string{
+		"":               "path is empty",
+		".":              "path '.' points to '" + root + "'",
+		"/etc/passwd":    "path '/etc/passwd' is absolute",
+		"../outside/x":   "path '../outside/x' contains '..'",
+		"dir/../../x":    "path 'dir/../../x' contains '..'",
+		".repo/manifest": "path '.repo/manifest' points into '.repo'",
+		"dir/.git/HEAD":  "path 'dir/.git/HEAD' points into '.git'",
+		"dir/.GIT/HEAD":  "path 'dir/.GIT/HEAD' points into '.GIT'",
+		"link":           "path 'link' traverses symlink 'link'",
+		"link/x":         "path 'link/x' traverses symlink 'link'",
+		"file/x":         "path 'file/x' traverses non-directory 'file'",
+	} {
+		_, err = ResolvePath(root, name, false)
+		if assert.NotNil(err, "should fail to resolve '%s'", name) {
+			assert.Equal(msg, err.Error())
+		}
+	}
+
+	_, err = ResolvePath(root, "link/x", true)
+	assert.NotNil(err)
+}
+
+func TestCopyAndLinkFilesSafely(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	topDir := filepath.Join(tmpdir, "work")
+	outside := filepath.Join(tmpdir, "outside")
+	xmlProject := manifest.Project{
+		Name:       "app",
+		Path:       "app",
+		RemoteName: "origin",
+		Revision:   "master",
+	}
+	xmlProject.ManifestRemote = &manifest.Remote{
+		Name:  "origin",
+		Fetch: "..",
+	}
+	p := NewProject(&xmlProject,
+		&RepoSettings{
+			TopDir:      topDir,
+			ManifestURL: "https://example.com/manifests.git",
+		}, nil)
+	assert.Nil(os.MkdirAll(p.WorkDir, 0755))
+	assert.Nil(os.MkdirAll(outside, 0755))
+	assert.Nil(ioutil.WriteFile(filepath.Join(p.WorkDir, "README"), []byte("readme"), 0644))
+	assert.Nil(os.Symlink(outside, filepath.Join(topDir, "out")))
+
+	// Normal copyfile and linkfile.
+	assert.Nil(p.CopyFile("README", "docs/README"))
+	data, err := ioutil.ReadFile(filepath.Join(topDir, "docs", "README"))
+	assert.Nil(err)
+	assert.Equal("readme", string(data))
+	assert.Nil(p.LinkFile("README", "README.link"))
+	target, err := os.Readlink(filepath.Join(topDir, "README.link"))
+	assert.Nil(err)
+	assert.Equal(filepath.Join("app", "README"), target)
+	// Replace existing symlink.
+	assert.Nil(p.LinkFile(".", "README.link"))
+	target, err = os.Readlink(filepath.Join(topDir, "README.link"))
+	assert.Nil(err)
+	assert.Equal("app", target)
+
+	// Checksums of written files are recorded, and not changed by user.
+	dest, err := ResolvePath(topDir, "docs/README", false)
+	assert.Nil(err)
+	checksum := FileChecksum(dest)
+	assert.Equal(checksum, WrittenFileChecksum(dest))
+	assert.Nil(ioutil.WriteFile(dest, []byte("modified"), 0644))
+	assert.Equal(checksum, WrittenFileChecksum(dest))
+	assert.NotEqual(checksum, FileChecksum(dest))
+	dest, err = ResolvePath(topDir, "README.link", true)
+	assert.Nil(err)
+	assert.Equal("symlink:app", WrittenFileChecksum(dest))
+
+	// Unsafe paths.
+	err = p.CopyFile("README", "out/README")
+	if assert.NotNil(err) {
+		assert.Equal("bad dest: path 'out/README' traverses symlink 'out'", err.Error())
+	}
+	err = p.CopyFile("README", "README.link")
+	if assert.NotNil(err) {
+		assert.Equal("bad dest: path 'README.link' traverses symlink 'README.link'", err.Error())
+	}
+	err = p.CopyFile("../../outside/x", "x")
+	if assert.NotNil(err) {
+		assert.Equal("bad src: path '../../outside/x' contains '..'", err.Error())
+	}
+	err = p.LinkFile("README", ".repo/manifest.xml")
+	if assert.NotNil(err) {
+		assert.Equal("bad dest: path '.repo/manifest.xml' points into '.repo'", err.Error())
+	}
+	err = p.LinkFile("README", "out/README")
+	if assert.NotNil(err) {
+		assert.Equal("bad dest: path 'out/README' traverses symlink 'out'", err.Error())
+	}
+	files, err := ioutil.ReadDir(outside)
+	assert.Nil(err)
+	assert.Equal(0, len(files))
+}
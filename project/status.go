@@ -151,15 +151,42 @@ func combineGitStatus(sti, stf []*gitStatus, sto []string) string {
 	return result
 }
 
-// Status shows combined output of git status for project.
-func (v Project) Status() *CmdExecResult {
-	result := NewCmdExecResult(&v)
+// FileStatus is status of a changed file.
+type FileStatus struct {
+	Status     string `json:"status"`
+	Path       string `json:"path"`
+	SrcPath    string `json:"src_path,omitempty"`
+	Similarity string `json:"similarity,omitempty"`
+}
 
-	rb := v.IsRebaseInProgress()
-	if rb {
-		result.Error = fmt.Errorf("prior sync failed; rebase still in progress")
-		return result
+// StatusFiles holds changed files of project grouped by index and worktree.
+type StatusFiles struct {
+	Index     []FileStatus `json:"index"`
+	WorkTree  []FileStatus `json:"worktree"`
+	Untracked []string     `json:"untracked"`
+}
+
+// IsClean indicates there are no changed files.
+func (v StatusFiles) IsClean() bool {
+	return len(v.Index) == 0 && len(v.WorkTree) == 0 && len(v.Untracked) == 0
+}
+
+func newFileStatusList(list []*gitStatus) []FileStatus {
+	result := []FileStatus{}
+	for _, s := range list {
+		result = append(result, FileStatus{
+			Status:     s.Status,
+			Path:       s.Path,
+			SrcPath:    s.SrcPath,
+			Similarity: s.Level,
+		})
 	}
+	return result
+}
+
+// gitStatus returns changes in index, changes in worktree and untracked files.
+func (v Project) gitStatus() ([]*gitStatus, []*gitStatus, []string, error) {
+	var err error
 
 	di := v.ExecuteCommand("git",
 		"diff-index",
@@ -176,10 +203,6 @@ func (v Project) Status() *CmdExecResult {
 		"--others",
 		"--exclude-standard")
 
-	if di.Empty() && df.Empty() && do.Empty() {
-		return result
-	}
-
 	sti := parseGitStatus(di.Out)
 	stf := parseGitStatus(df.Out)
 	sto := []string{}
@@ -189,21 +212,54 @@ func (v Project) Status() *CmdExecResult {
 		}
 	}
 
-	output := combineGitStatus(sti, stf, sto)
-	result.Out = []byte(output)
-
 	if di.Error != nil && df.Error != nil {
 		errMsg := di.Stderr()
 		if errMsg != "" && errMsg[len(errMsg)-1] != '\n' {
 			errMsg += "\n"
 		}
 		errMsg += df.Stderr()
-		result.Error = errors.New(errMsg)
+		err = errors.New(errMsg)
 	} else if di.Error != nil {
-		result.Error = di.Error
+		err = di.Error
 	} else if df.Error != nil {
-		result.Error = df.Error
+		err = df.Error
 	}
 
+	return sti, stf, sto, err
+}
+
+// StatusFiles returns changed files of project.
+func (v Project) StatusFiles() (*StatusFiles, error) {
+	if v.IsRebaseInProgress() {
+		return nil, fmt.Errorf("prior sync failed; rebase still in progress")
+	}
+
+	sti, stf, sto, err := v.gitStatus()
+	return &StatusFiles{
+		Index:     newFileStatusList(sti),
+		WorkTree:  newFileStatusList(stf),
+		Untracked: sto,
+	}, err
+}
+
+// Status shows combined output of git status for project.
+func (v Project) Status() *CmdExecResult {
+	result := NewCmdExecResult(&v)
+
+	rb := v.IsRebaseInProgress()
+	if rb {
+		result.Error = fmt.Errorf("prior sync failed; rebase still in progress")
+		return result
+	}
+
+	sti, stf, sto, err := v.gitStatus()
+	if len(sti) == 0 && len(stf) == 0 && len(sto) == 0 && err == nil {
+		return result
+	}
+
+	output := combineGitStatus(sti, stf, sto)
+	result.Out = []byte(output)
+	result.Error = err
+
 	return result
 }
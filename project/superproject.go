@@ -0,0 +1,146 @@
+package project
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/common"
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/alibaba/git-repo-go/path"
+	log "github.com/jiangxin/multi-log"
+)
+
+const (
+	superprojectDir = "superproject"
+	superprojectRef = "refs/superproject"
+)
+
+// Superproject is a bare repository of the superproject defined in
+// manifest, whose gitlinks pin revisions of all projects.
+type Superproject struct {
+	Name     string
+	GitDir   string
+	URL      string
+	Revision string
+}
+
+// Fetch fetches the single commit of superproject.
+func (v Superproject) Fetch(quiet bool) error {
+	if !path.IsGitDir(v.GitDir) {
+		err := executeCommand(GIT, "init", "--bare", "-q", v.GitDir)
+		if err != nil {
+			return fmt.Errorf("fail to init superproject '%s': %s", v.Name, err)
+		}
+	}
+
+	cmdArgs := []string{
+		GIT,
+		"fetch",
+	}
+	if quiet {
+		cmdArgs = append(cmdArgs, "--quiet")
+	}
+	cmdArgs = append(cmdArgs,
+		"--no-tags",
+		"--depth=1",
+		v.URL,
+		"+"+v.Revision+":"+superprojectRef)
+	log.Debugf("fetching superproject: %s", strings.Join(cmdArgs, " "))
+	err := executeCommandIn(v.GitDir, cmdArgs)
+	if err != nil {
+		return fmt.Errorf("fail to fetch superproject '%s': %s", v.Name, err)
+	}
+	return nil
+}
+
+// Revisions reads gitlinks of the fetched superproject commit, and returns
+// revisions of projects indexed by path.
+func (v Superproject) Revisions() (map[string]string, error) {
+	cmd := exec.Command(GIT, "ls-tree", "-r", "-z", superprojectRef)
+	cmd.Dir = v.GitDir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("fail to read superproject '%s': %s", v.Name, err)
+	}
+	return parseGitlinks(out), nil
+}
+
+// parseGitlinks parses output of "git ls-tree -r -z", and returns commit
+// IDs of gitlinks indexed by path.
+func parseGitlinks(data []byte) map[string]string {
+	revisions := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, 0); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+	for scanner.Scan() {
+		// Format: <mode> SP <type> SP <object> TAB <file>
+		line := scanner.Text()
+		tab := strings.IndexByte(line, '\t')
+		if tab < 0 {
+			continue
+		}
+		fields := strings.Fields(line[:tab])
+		if len(fields) != 3 || fields[1] != "commit" {
+			continue
+		}
+		revisions[line[tab+1:]] = fields[2]
+	}
+	return revisions
+}
+
+// NewSuperproject returns superproject defined in manifest, or nil if
+// manifest has no superproject.
+func NewSuperproject(m *manifest.Manifest, s *RepoSettings) (*Superproject, error) {
+	if m == nil || m.Superproject == nil {
+		return nil, nil
+	}
+
+	name := m.Superproject.Name
+	if name == "" {
+		return nil, fmt.Errorf("no name for superproject")
+	}
+	remote := m.GetRemote(m.Superproject.Remote)
+	if remote == nil {
+		return nil, fmt.Errorf("cannot find remote '%s' for superproject '%s'",
+			m.Superproject.Remote,
+			name)
+	}
+	revision := m.SuperprojectRevision()
+	if revision == "" {
+		return nil, fmt.Errorf("no revision for superproject '%s'", name)
+	}
+	if !common.IsSha(revision) && !strings.HasPrefix(revision, config.Refs) {
+		revision = config.RefsHeads + revision
+	}
+
+	manifestURL := s.ManifestURL
+	if manifestURL != "" && !strings.HasSuffix(manifestURL, ".git") {
+		manifestURL += ".git"
+	}
+	u, err := common.URLJoin(manifestURL, remote.Fetch, name+".git")
+	if err != nil {
+		return nil, fmt.Errorf("fail to get url of superproject '%s': %s", name, err)
+	}
+
+	return &Superproject{
+		Name: name,
+		GitDir: filepath.Join(s.TopDir,
+			config.DotRepo,
+			superprojectDir,
+			name+".git"),
+		URL:      u,
+		Revision: revision,
+	}, nil
+}
@@ -0,0 +1,101 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGitlinks(t *testing.T) {
+	assert := assert.New(t)
+
+	data := strings.Join([]string{
+		"100644 blob 9dc1bd2c73b5fa82d9f9b5ff3c5ef9bb4e1bfbe3\tdefault.xml",
+		"160000 commit 3b48e8bb56a8fcd50e3e2bbc84e4a8b9d26e8ef6\tdir/foo",
+		"160000 commit 6a4c1de52d9a9a2b8a0e2f7c7c24fbd9aa4e0f31\tbar with space",
+		"",
+	}, "\x00")
+	assert.Equal(map[string]string{
+		"dir/foo":        "3b48e8bb56a8fcd50e3e2bbc84e4a8b9d26e8ef6",
+		"bar with space": "6a4c1de52d9a9a2b8a0e2f7c7c24fbd9aa4e0f31",
+	}, parseGitlinks([]byte(data)))
+}
+
+func TestSuperprojectRevisions(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// Superproject with gitlinks of "dir/foo" and "bar".
+	upstream := filepath.Join(tmpdir, "platform", "superproject.git")
+	assert.Nil(exec.Command("git", "init", "-q", upstream).Run())
+	assert.Nil(exec.Command("git", "-C", upstream, "checkout", "-q", "-b", "master").Run())
+	fooID := "3b48e8bb56a8fcd50e3e2bbc84e4a8b9d26e8ef6"
+	barID := "6a4c1de52d9a9a2b8a0e2f7c7c24fbd9aa4e0f31"
+	for _, link := range []string{
+		"160000," + fooID + ",dir/foo",
+		"160000," + barID + ",bar",
+	} {
+		assert.Nil(exec.Command("git", "-C", upstream,
+			"update-index", "--add", "--cacheinfo", link).Run())
+	}
+	gitCommitIn(t, upstream, 1)
+
+	m := &manifest.Manifest{
+		Remotes: []manifest.Remote{
+			manifest.Remote{
+				Name:  "origin",
+				Fetch: "..",
+			},
+		},
+		Default: &manifest.Default{
+			RemoteName: "origin",
+			Revision:   "master",
+		},
+	}
+	s := &RepoSettings{
+		TopDir:      filepath.Join(tmpdir, "work"),
+		ManifestURL: filepath.Join(tmpdir, "mirror", "manifests"),
+	}
+
+	sp, err := NewSuperproject(m, s)
+	assert.Nil(err)
+	assert.Nil(sp)
+
+	m.Superproject = &manifest.Superproject{
+		Name:   "platform/superproject",
+		Remote: "unknown",
+	}
+	_, err = NewSuperproject(m, s)
+	assert.Equal("cannot find remote 'unknown' for superproject 'platform/superproject'",
+		err.Error())
+
+	m.Superproject.Remote = ""
+	sp, err = NewSuperproject(m, s)
+	assert.Nil(err)
+	assert.Equal("file://"+upstream, sp.URL)
+	assert.Equal("refs/heads/master", sp.Revision)
+	assert.Equal(filepath.Join(tmpdir, "work", ".repo", "superproject", "platform", "superproject.git"),
+		sp.GitDir)
+
+	assert.Nil(sp.Fetch(true))
+	revisions, err := sp.Revisions()
+	assert.Nil(err)
+	assert.Equal(map[string]string{
+		"dir/foo": fooID,
+		"bar":     barID,
+	}, revisions)
+
+	// Fetch again.
+	assert.Nil(sp.Fetch(true))
+}
@@ -0,0 +1,314 @@
+package project
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/helper"
+)
+
+// DefaultCheckEmail is the email checked by checkEmail if it is enabled
+// without naming "author", "committer" or "both".
+const DefaultCheckEmail = "committer"
+
+var reEmail = regexp.MustCompile(`<([^<>]*)>`)
+
+// UploadCommit is a commit ready for upload, which is checked by upload
+// checks before git push.
+type UploadCommit struct {
+	ID             string
+	Parents        []string
+	AuthorEmail    string
+	CommitterEmail string
+	Message        string
+}
+
+// Subject returns the first line of commit message.
+func (v UploadCommit) Subject() string {
+	return strings.SplitN(strings.TrimSpace(v.Message), "\n", 2)[0]
+}
+
+// IsMerge indicates commit has more than one parent.
+func (v UploadCommit) IsMerge() bool {
+	return len(v.Parents) > 1
+}
+
+// UploadCheck is a rule to check commits before upload. The rule can be
+// tuned or disabled (set to "false") by git config variable
+// "review.<url>.<Key>", and Default is used if the variable is not set.
+type UploadCheck struct {
+	Key     string
+	Default func(branch *ReviewableBranch) string
+	Check   func(branch *ReviewableBranch, commit *UploadCommit, value string) error
+}
+
+// uploadChecks holds all registered upload checks.
+var uploadChecks = []UploadCheck{}
+
+// RegisterUploadCheck adds a rule to check commits before upload.
+func RegisterUploadCheck(check UploadCheck) {
+	uploadChecks = append(uploadChecks, check)
+}
+
+// UploadCheckError is a problem found by upload check in a commit.
+type UploadCheckError struct {
+	Commit string
+	Key    string
+	Err    error
+}
+
+func (v UploadCheckError) Error() string {
+	return fmt.Sprintf("%s: %s (%s)", v.Commit, v.Err, v.Key)
+}
+
+func isDisabled(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "0", "no", "false", "off":
+		return true
+	}
+	return false
+}
+
+// parseSize parses size with optional unit "k", "m" or "g", such as "10m".
+func parseSize(value string) (int64, error) {
+	s := strings.ToLower(strings.TrimSpace(value))
+	unit := int64(1)
+	switch {
+	case strings.HasSuffix(s, "k"):
+		unit = 1 << 10
+	case strings.HasSuffix(s, "m"):
+		unit = 1 << 20
+	case strings.HasSuffix(s, "g"):
+		unit = 1 << 30
+	}
+	if unit > 1 {
+		s = s[:len(s)-1]
+	}
+	size, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("bad size '%s'", value)
+	}
+	return size * unit, nil
+}
+
+// emailOf returns email address in "Name <email>" format.
+func emailOf(ident string) string {
+	m := reEmail.FindStringSubmatch(ident)
+	if m == nil {
+		return strings.TrimSpace(ident)
+	}
+	return m[1]
+}
+
+// checkChangeID only works for Gerrit remotes, and is disabled by default,
+// because Change-Id is missing if commit-msg hook is not installed yet.
+func checkChangeID(branch *ReviewableBranch, commit *UploadCommit, value string) error {
+	if branch.Remote == nil || branch.Remote.GetType() != helper.ProtoTypeGerrit {
+		return nil
+	}
+	if !changeIDPattern.MatchString(commit.Message) {
+		return fmt.Errorf("missing Change-Id in commit message")
+	}
+	return nil
+}
+
+func checkEmail(branch *ReviewableBranch, commit *UploadCommit, value string) error {
+	email := emailOf(branch.Project.UserEmail())
+	if email == "" {
+		return nil
+	}
+	value = strings.ToLower(value)
+	if value != "author" && value != "committer" && value != "both" {
+		value = DefaultCheckEmail
+	}
+	if value != "committer" && !strings.EqualFold(commit.AuthorEmail, email) {
+		return fmt.Errorf("author email <%s> does not match <%s>",
+			commit.AuthorEmail, email)
+	}
+	if value != "author" && !strings.EqualFold(commit.CommitterEmail, email) {
+		return fmt.Errorf("committer email <%s> does not match <%s>",
+			commit.CommitterEmail, email)
+	}
+	return nil
+}
+
+func checkFixup(branch *ReviewableBranch, commit *UploadCommit, value string) error {
+	subject := commit.Subject()
+	for _, prefix := range []string{"fixup!", "squash!", "amend!"} {
+		if strings.HasPrefix(subject, prefix) {
+			return fmt.Errorf("%s commit, squash it first", prefix)
+		}
+	}
+	return nil
+}
+
+func checkMerge(branch *ReviewableBranch, commit *UploadCommit, value string) error {
+	if commit.IsMerge() {
+		return fmt.Errorf("merge commit is not allowed")
+	}
+	return nil
+}
+
+func checkFileSize(branch *ReviewableBranch, commit *UploadCommit, value string) error {
+	limit, err := parseSize(value)
+	if err != nil {
+		return err
+	}
+	if limit == 0 || commit.IsMerge() {
+		return nil
+	}
+
+	out, err := branch.Project.gitOutput(nil,
+		"diff-tree", "-r", "--root", "--no-commit-id", "--diff-filter=AMT", commit.ID)
+	if err != nil {
+		return err
+	}
+	// Raw output of diff-tree: ":<mode> <mode> <oid> <oid> <status>\t<path>"
+	files := make(map[string]string)
+	input := bytes.Buffer{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.SplitN(scanner.Text(), "\t", 2)
+		fields := strings.Fields(line[0])
+		if len(line) != 2 || len(fields) != 5 {
+			continue
+		}
+		files[fields[3]] = line[1]
+		input.WriteString(fields[3] + "\n")
+	}
+	if input.Len() == 0 {
+		return nil
+	}
+
+	out, err = branch.Project.gitOutput(input.Bytes(), "cat-file", "--batch-check")
+	if err != nil {
+		return err
+	}
+	bigFiles := []string{}
+	scanner = bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || fields[1] != "blob" {
+			continue
+		}
+		size, _ := strconv.ParseInt(fields[2], 10, 64)
+		if size > limit {
+			bigFiles = append(bigFiles, fmt.Sprintf("%s (%d bytes)", files[fields[0]], size))
+		}
+	}
+	if len(bigFiles) > 0 {
+		return fmt.Errorf("file larger than %s: %s", value, strings.Join(bigFiles, ", "))
+	}
+	return nil
+}
+
+// disabledByDefault is default of built-in upload checks, which are opt-in,
+// so that upload of existing workspaces is not rejected unexpectedly.
+func disabledByDefault(*ReviewableBranch) string {
+	return "false"
+}
+
+func init() {
+	RegisterUploadCheck(UploadCheck{
+		Key:     "checkChangeId",
+		Default: disabledByDefault,
+		Check:   checkChangeID,
+	})
+	RegisterUploadCheck(UploadCheck{
+		Key:     "checkEmail",
+		Default: disabledByDefault,
+		Check:   checkEmail,
+	})
+	RegisterUploadCheck(UploadCheck{
+		Key:     "checkFixup",
+		Default: disabledByDefault,
+		Check:   checkFixup,
+	})
+	RegisterUploadCheck(UploadCheck{
+		Key:     "checkMerge",
+		Default: disabledByDefault,
+		Check:   checkMerge,
+	})
+	RegisterUploadCheck(UploadCheck{
+		Key:     "maxFileSize",
+		Default: disabledByDefault,
+		Check:   checkFileSize,
+	})
+}
+
+// UploadCommits returns commits of branch for upload, the latest first.
+func (v ReviewableBranch) UploadCommits() ([]UploadCommit, error) {
+	result := []UploadCommit{}
+	base := v.base()
+	args := []string{"log", "--format=%H%x00%P%x00%ae%x00%ce%x00%B%x01", v.Branch.Hash}
+	if base != "" {
+		args = append(args, "--not", base)
+	}
+	out, err := v.Project.gitOutput(nil, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range strings.Split(string(out), "\x01") {
+		fields := strings.SplitN(strings.TrimLeft(record, "\n"), "\x00", 5)
+		if len(fields) != 5 {
+			continue
+		}
+		result = append(result, UploadCommit{
+			ID:             fields[0],
+			Parents:        strings.Fields(fields[1]),
+			AuthorEmail:    fields[2],
+			CommitterEmail: fields[3],
+			Message:        fields[4],
+		})
+	}
+	return result, nil
+}
+
+// uploadCheckValue returns value of config "review.<url>.<key>" for check.
+func (v ReviewableBranch) uploadCheckValue(check UploadCheck) string {
+	if v.Remote != nil && v.Remote.Review != "" {
+		key := fmt.Sprintf("review.%s.%s", v.Remote.Review, check.Key)
+		cfg := v.Project.ConfigWithDefault()
+		if cfg.HasKey(key) {
+			return cfg.Get(key)
+		}
+	}
+	if check.Default == nil {
+		return "true"
+	}
+	return check.Default(&v)
+}
+
+// CheckCommits runs upload checks on commits of branch, and returns
+// problems found.
+func (v ReviewableBranch) CheckCommits() ([]UploadCheckError, error) {
+	result := []UploadCheckError{}
+	commits, err := v.UploadCommits()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, len(uploadChecks))
+	for i, check := range uploadChecks {
+		values[i] = v.uploadCheckValue(check)
+	}
+	for _, commit := range commits {
+		for i, check := range uploadChecks {
+			if isDisabled(values[i]) {
+				continue
+			}
+			err = check.Check(&v, &commit, values[i])
+			if err != nil {
+				result = append(result, UploadCheckError{
+					Commit: commit.ID[:7],
+					Key:    check.Key,
+					Err:    err,
+				})
+			}
+		}
+	}
+	return result, nil
+}
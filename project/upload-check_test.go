@@ -0,0 +1,80 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSize(t *testing.T) {
+	assert := assert.New(t)
+
+	for value, expect := range map[string]int64{
+		"0":    0,
+		"100":  100,
+		"1k":   1024,
+		"10M":  10 << 20,
+		" 2g ": 2 << 30,
+	} {
+		size, err := parseSize(value)
+		assert.Nil(err, value)
+		assert.Equal(expect, size, value)
+	}
+
+	for _, value := range []string{"", "k", "-1", "1t", "true"} {
+		_, err := parseSize(value)
+		assert.NotNil(err, value)
+	}
+}
+
+func TestEmailOf(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("jx@example.com", emailOf("Jiang Xin <jx@example.com>"))
+	assert.Equal("jx@example.com", emailOf(`"Jiang Xin" <jx@example.com>`))
+	assert.Equal("jx@example.com", emailOf("jx@example.com"))
+	assert.Equal("", emailOf(""))
+}
+
+func TestUploadCommitChecks(t *testing.T) {
+	assert := assert.New(t)
+
+	commit := UploadCommit{
+		ID:      "0123456789abcdef0123456789abcdef01234567",
+		Parents: []string{"1111111111111111111111111111111111111111"},
+		Message: "topic: new file\n\nChange-Id: I0123456789abcdef0123456789abcdef01234567\n",
+	}
+	assert.Equal("topic: new file", commit.Subject())
+	assert.Nil(checkFixup(nil, &commit, "true"))
+	assert.Nil(checkMerge(nil, &commit, "true"))
+
+	commit.Message = "fixup! topic: new file\n"
+	err := checkFixup(nil, &commit, "true")
+	assert.Equal("fixup! commit, squash it first", err.Error())
+	commit.Message = "squash! topic: new file\n"
+	err = checkFixup(nil, &commit, "true")
+	assert.Equal("squash! commit, squash it first", err.Error())
+
+	commit.Parents = append(commit.Parents, "2222222222222222222222222222222222222222")
+	assert.True(commit.IsMerge())
+	err = checkMerge(nil, &commit, "true")
+	assert.Equal("merge commit is not allowed", err.Error())
+
+	checkErr := UploadCheckError{
+		Commit: commit.ID[:7],
+		Key:    "checkMerge",
+		Err:    err,
+	}
+	assert.Equal("0123456: merge commit is not allowed (checkMerge)", checkErr.Error())
+}
+
+func TestIsDisabled(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, value := range []string{"", "0", "no", "False", "off"} {
+		assert.True(isDisabled(value), value)
+	}
+	for _, value := range []string{"1", "yes", "true", "committer", "10m"} {
+		assert.False(isDisabled(value), value)
+	}
+}
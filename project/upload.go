@@ -1,10 +1,13 @@
 package project
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 
 	"github.com/alibaba/git-repo-go/common"
 	"github.com/alibaba/git-repo-go/config"
@@ -29,6 +32,7 @@ type ReviewableBranch struct {
 	Error       error
 	CodeReview  config.CodeReview // Push to update specific code review, only available for single repository mode.
 	Remote      *Remote
+	Review      *helper.ReviewInfo // Code review created or updated by upload.
 
 	isPublished int
 }
@@ -103,16 +107,7 @@ func (v *ReviewableBranch) Published() *Reference {
 
 // Commits contains commits avaiable for review.
 func (v ReviewableBranch) Commits() []string {
-	var (
-		commits []string
-		err     error
-	)
-
-	if v.CodeReview.Empty() {
-		commits, err = v.Project.Revlist(v.Branch.Hash, "--not", v.RemoteTrack.Track.Hash)
-	} else {
-		commits, err = v.Project.Revlist(v.Branch.Hash, "--not", v.CodeReview.Ref)
-	}
+	commits, err := v.Project.Revlist(v.Branch.Hash, "--not", v.base())
 	if err != nil {
 		log.Errorf("%sfail to get commits of ReviewableBranch %s: %s",
 			v.Project.Prompt(),
@@ -123,22 +118,107 @@ func (v ReviewableBranch) Commits() []string {
 	return commits
 }
 
-// UploadForReview sends review for branch.
-func (v ReviewableBranch) UploadForReview(o *config.UploadOptions) error {
-	var err error
+// SavedCodeReview returns code review saved by last upload of branch in
+// "branch.<name>.review-id". Published ref of the branch is used as ref of
+// the code review, and empty CodeReview is returned if the branch has not
+// been published. If the published ref is merged into upstream, the code
+// review is closed and the saved review is cleared.
+func (v *Project) SavedCodeReview(branch string) config.CodeReview {
+	branch = strings.TrimPrefix(branch, config.RefsHeads)
+	id := v.Config().Get("branch." + branch + ".review-id")
+	if id == "" {
+		return config.CodeReview{}
+	}
+	ref := config.RefsPub + branch
+	if _, err := v.ResolveRevision(ref); err != nil {
+		return config.CodeReview{}
+	}
+	if upstream, err := v.BranchUpstream(branch); err == nil {
+		commits, err := v.Revlist(ref, "--not", upstream)
+		if err == nil && len(commits) == 0 {
+			log.Notef("%scode review #%s of branch '%s' is merged, will create a new one",
+				v.Prompt(),
+				id,
+				branch)
+			if err = v.ClearSavedReview(branch); err != nil {
+				log.Warnf("%sfail to clear saved review of branch '%s': %s",
+					v.Prompt(),
+					branch,
+					err)
+			}
+			return config.CodeReview{}
+		}
+	}
+	return config.CodeReview{ID: id, Ref: ref}
+}
+
+// ClearSavedReview removes code review of branch saved by upload.
+func (v *Project) ClearSavedReview(branch string) error {
+	cfg := v.Config()
+	prefix := "branch." + strings.TrimPrefix(branch, config.RefsHeads)
+	cfg.Unset(prefix + ".review-id")
+	cfg.Unset(prefix + ".review-url")
+	return v.SaveConfig(cfg)
+}
 
+// base returns revision which commits of branch for upload are based on,
+// that is the code review to update, or the tracking branch.
+func (v ReviewableBranch) base() string {
+	if v.CodeReview.Empty() {
+		return v.RemoteTrack.Track.Hash
+	}
+	return v.CodeReview.Ref
+}
+
+// SaveReview saves code review of branch created or updated by upload in
+// git config "branch.<name>.review-id" and "branch.<name>.review-url".
+func (v ReviewableBranch) SaveReview() error {
+	if v.Review == nil {
+		return nil
+	}
+	cfg := v.Project.Config()
+	prefix := "branch." + v.Branch.ShortName()
+	if v.Review.ID != "" {
+		cfg.Set(prefix+".review-id", v.Review.ID)
+	}
+	if v.Review.URL != "" {
+		cfg.Set(prefix+".review-url", v.Review.URL)
+	}
+	return v.Project.SaveConfig(cfg)
+}
+
+// UploadPlan is the resolved git push command to upload a branch for
+// review, which is shown by "upload --plan".
+type UploadPlan struct {
+	Project     string                 `json:"project"`
+	Path        string                 `json:"path"`
+	Branch      string                 `json:"branch"`
+	Remote      string                 `json:"remote,omitempty"`
+	RemoteURL   string                 `json:"remote_url"`
+	DestBranch  string                 `json:"dest_branch,omitempty"`
+	OldOid      string                 `json:"old_oid,omitempty"`
+	Reviewers   []string               `json:"reviewers"`
+	Cc          []string               `json:"cc"`
+	PushCommand *helper.GitPushCommand `json:"push_command"`
+	Command     []string               `json:"command"`
+	Env         []string               `json:"env,omitempty"`
+}
+
+// UploadPlan resolves remote, destination and git push command to upload
+// branch for review, without running git push.
+func (v ReviewableBranch) UploadPlan(o *config.UploadOptions) (*UploadPlan, error) {
 	p := v.Project
 	if p == nil {
-		return fmt.Errorf("no project for reviewable branch")
+		return nil, fmt.Errorf("no project for reviewable branch")
 	}
 
 	remoteName, remoteURL := p.GetRemotePushNameURL(v.Remote)
 	if remoteURL == "" {
-		return fmt.Errorf("project '%s' has no review url", p.Name)
+		return nil, fmt.Errorf("project '%s' has no review url", p.Name)
 	}
 	gitURL := config.ParseGitURL(remoteURL)
 	if gitURL == nil {
-		return fmt.Errorf("bad review URL: %s", remoteURL)
+		return nil, fmt.Errorf("bad review URL: %s", remoteURL)
 	}
 	o.RemoteName = remoteName
 	o.RemoteURL = remoteURL
@@ -146,13 +226,13 @@ func (v ReviewableBranch) UploadForReview(o *config.UploadOptions) error {
 	if v.CodeReview.Empty() && o.DestBranch == "" {
 		o.DestBranch = v.DestBranch
 		if o.DestBranch == "" {
-			return fmt.Errorf("no destination for review")
+			return nil, fmt.Errorf("no destination for review")
 		}
 	}
 
 	pushCmd, err := v.Remote.GetGitPushCommand(o)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	cmdArgs := []string{pushCmd.Cmd}
@@ -174,12 +254,77 @@ func (v ReviewableBranch) UploadForReview(o *config.UploadOptions) error {
 		}
 	}
 
+	plan := UploadPlan{
+		Project:     p.Name,
+		Path:        p.Path,
+		Branch:      v.Branch.ShortName(),
+		Remote:      remoteName,
+		RemoteURL:   remoteURL,
+		DestBranch:  o.DestBranch,
+		OldOid:      o.OldOid,
+		Reviewers:   []string{},
+		Cc:          []string{},
+		PushCommand: pushCmd,
+		Command:     cmdArgs,
+		Env:         envs,
+	}
+	if len(o.People) > 0 {
+		plan.Reviewers = append(plan.Reviewers, o.People[0]...)
+	}
+	if len(o.People) > 1 {
+		plan.Cc = append(plan.Cc, o.People[1]...)
+	}
+	return &plan, nil
+}
+
+// UploadForReview sends review for branch.
+func (v *ReviewableBranch) UploadForReview(o *config.UploadOptions) error {
+	return v.UploadForReviewWithOutput(o, nil)
+}
+
+// lockedWriter serializes writes from stdout and stderr of a command, which
+// are copied by different goroutines of os/exec.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (v *lockedWriter) Write(p []byte) (int, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.w.Write(p)
+}
+
+// UploadForReviewWithOutput sends review for branch, and writes output of
+// git push to out instead of console, so that output of uploads running
+// simultaneously are not mixed up. Code review created or updated is
+// parsed from messages of server, and saved in v.Review.
+func (v *ReviewableBranch) UploadForReviewWithOutput(o *config.UploadOptions, out io.Writer) error {
+	notef := func(format string, args ...interface{}) {
+		if out == nil {
+			log.Notef(format, args...)
+		} else {
+			fmt.Fprint(out, log.Snotef(format, args...))
+		}
+	}
+
+	p := v.Project
+	plan, err := v.UploadPlan(o)
+	if err != nil {
+		return err
+	}
+	cmdArgs := plan.Command
+	envs := plan.Env
+
 	if config.IsDryRun() || o.MockGitPush {
-		log.Notef("%swill execute command: %s",
+		notef("%swill execute command: %s",
 			v.Project.Prompt(),
 			strings.Join(cmdArgs, " "))
 		for _, env := range envs {
-			log.Notef("%swith extra environment: %s", v.Project.Prompt(), env)
+			notef("%swith extra environment: %s", v.Project.Prompt(), env)
+		}
+		if o.MockPushOut != "" {
+			v.Review = v.Remote.ParseReviewInfo(o.MockPushOut)
 		}
 	} else {
 		log.Debugf("%sreview by command: %s",
@@ -187,9 +332,17 @@ func (v ReviewableBranch) UploadForReview(o *config.UploadOptions) error {
 			strings.Join(cmdArgs, " "))
 		cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
 		cmd.Dir = p.WorkDir
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+		// Messages of server are sent to stderr of git push.
+		serverOutput := bytes.Buffer{}
+		if out == nil {
+			cmd.Stdin = os.Stdin
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = io.MultiWriter(os.Stderr, &serverOutput)
+		} else {
+			w := &lockedWriter{w: out}
+			cmd.Stdout = w
+			cmd.Stderr = io.MultiWriter(w, &serverOutput)
+		}
 		if len(envs) > 0 {
 			cmd.Env = []string{}
 			cmd.Env = append(cmd.Env, os.Environ()...)
@@ -202,6 +355,7 @@ func (v ReviewableBranch) UploadForReview(o *config.UploadOptions) error {
 		if err != nil {
 			return fmt.Errorf("upload failed: %s", err)
 		}
+		v.Review = v.Remote.ParseReviewInfo(serverOutput.String())
 	}
 
 	branchName := v.Branch.Name
@@ -241,6 +395,56 @@ func (v ReviewableBranch) UploadForReview(o *config.UploadOptions) error {
 	return nil
 }
 
+// ReviewRef finds reference of code review created or updated by upload,
+// such as "refs/changes/34/1234/2" of Gerrit, which points to the uploaded
+// commit in remote.
+func (v ReviewableBranch) ReviewRef() (string, error) {
+	// Only list references in the namespace of code reviews, such as
+	// "refs/changes/*" of Gerrit.
+	sample, err := v.Remote.GetDownloadRef("1", "1")
+	if err != nil {
+		return "", err
+	}
+	items := strings.SplitN(sample, "/", 3)
+	if len(items) != 3 {
+		return "", fmt.Errorf("bad reference of code review: %s", sample)
+	}
+	out, err := v.Project.gitOutput(nil,
+		"ls-remote",
+		v.Remote.Name,
+		items[0]+"/"+items[1]+"/*")
+	if err != nil {
+		return "", err
+	}
+
+	refs := []string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != v.Branch.Hash {
+			continue
+		}
+		ref := fields[1]
+		names := strings.Split(ref, "/")
+		patch := names[len(names)-1]
+		for _, id := range names[2:] {
+			if want, err := v.Remote.GetDownloadRef(id, patch); err == nil && want == ref {
+				refs = append(refs, ref)
+				break
+			}
+		}
+	}
+	if len(refs) == 0 {
+		return "", fmt.Errorf("cannot find code review of commit %s in remote '%s'",
+			v.Branch.Hash,
+			v.Remote.Name)
+	} else if len(refs) > 1 {
+		return "", fmt.Errorf("commit %s belongs to more than one code review: %s",
+			v.Branch.Hash,
+			strings.Join(refs, ", "))
+	}
+	return refs[0], nil
+}
+
 // GetUploadableBranch returns branch which has commits ready for upload.
 func (v *Project) GetUploadableBranch(branch string, remote *Remote, remoteBranch string) *ReviewableBranch {
 	if remote == nil {
@@ -27,6 +27,23 @@ test_expect_success "git-repo sync (-n), default jobs" '
 	)
 '
 
+test_expect_success "fetch times of projects are saved" '
+	(
+		cd work &&
+		test -f .repo/.repo_fetchtimes.json &&
+		sed -n -e "s/^ *\"\([^\"]*\)\":.*/\1/p" \
+			.repo/.repo_fetchtimes.json | sort >actual &&
+		cat >expect <<-EOF &&
+		drivers/driver1
+		main
+		project1
+		project1/module1
+		project2
+		EOF
+		test_cmp expect actual
+	)
+'
+
 test_expect_success "git-repo sync (-n), 100 jobs" '
 	(
 		cd work &&
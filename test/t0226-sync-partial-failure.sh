@@ -0,0 +1,127 @@
+#!/bin/sh
+
+test_description="git-repo sync with projects failed to fetch"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url &&
+		mkdir .repo/local_manifests &&
+		cat >.repo/local_manifests/broken.xml <<-EOF
+		<?xml version="1.0" encoding="UTF-8"?>
+		<manifest>
+		  <project name="others/not-exist" path="others/broken" remote="driver" revision="master" />
+		  <project name="others/demo1" path="others/broken/demo1" remote="driver" revision="master" />
+		</manifest>
+		EOF
+	)
+'
+
+test_expect_success "sync with --fail-fast, abort before checkout" '
+	(
+		cd work &&
+		test_must_fail git-repo sync -j 1 --fail-fast \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" \
+			>out 2>&1 &&
+		grep "^others/broken  *| failure | fail to fetch project" out &&
+		test ! -d main &&
+		test ! -d projects
+	)
+'
+
+test_expect_success "sync continue on error, and checkout other projects" '
+	(
+		cd work &&
+		test_must_fail git-repo sync --retry-fetches 1 \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" \
+			>out 2>&1 &&
+		grep "retry in 1s (1/1)" out &&
+		sed -n -e "/^Failed to sync/,/^$/p" out >actual &&
+		cat >expect <<-EOF &&
+		Failed to sync the following projects:
+		------------------------------------------------------------------------------
+		others/broken        | failure | fail to fetch project '"'"'others/not-exist'"'"': exit status 128
+		others/broken/demo1  | skipped | parent project '"'"'others/broken'"'"' is not synced
+		
+		EOF
+		test_cmp expect actual &&
+		test -f main/VERSION &&
+		test -d projects/app1/module1 &&
+		test ! -d others/broken
+	)
+'
+
+test_expect_success "sync results in JSON" '
+	(
+		cd work &&
+		test_must_fail git-repo sync -l --format json \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" \
+			>actual 2>/dev/null &&
+		cat >expect <<-EOF &&
+		[
+		  {
+		    "name": "drivers/driver1",
+		    "path": "drivers/driver-1",
+		    "status": "success"
+		  },
+		  {
+		    "name": "main",
+		    "path": "main",
+		    "status": "success"
+		  },
+		  {
+		    "name": "others/not-exist",
+		    "path": "others/broken",
+		    "status": "failure",
+		    "error": "cannot checkout, invalid remote tracking branch '"'"'master'"'"': revision refs/remotes/driver/master in others/not-exist not found"
+		  },
+		  {
+		    "name": "others/demo1",
+		    "path": "others/broken/demo1",
+		    "status": "skipped",
+		    "error": "parent project '"'"'others/broken'"'"' is not synced"
+		  },
+		  {
+		    "name": "project1",
+		    "path": "projects/app1",
+		    "status": "success"
+		  },
+		  {
+		    "name": "project1/module1",
+		    "path": "projects/app1/module1",
+		    "status": "success"
+		  },
+		  {
+		    "name": "project2",
+		    "path": "projects/app2",
+		    "status": "success"
+		  }
+		]
+		EOF
+		test_cmp expect actual
+	)
+'
+
+test_expect_success "sync with bad options" '
+	(
+		cd work &&
+		test_must_fail git-repo sync -n --retry-fetches -1 &&
+		test_must_fail git-repo sync -n --format xml
+	)
+'
+
+test_done
@@ -0,0 +1,186 @@
+#!/bin/sh
+
+test_description="test 'git-repo sync' with submanifest"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup team repositories" '
+	repos="$(pwd)/repos" &&
+	mkdir -p src/app src/manifests &&
+	(
+		cd src/app &&
+		git init -q &&
+		echo app >README &&
+		git add README &&
+		test_tick &&
+		git commit -q -m "Initial app"
+	) &&
+	(
+		cd src/manifests &&
+		git init -q &&
+		cat >default.xml <<-EOF &&
+		<?xml version="1.0" encoding="UTF-8"?>
+		<manifest>
+		  <remote name="team" fetch="." />
+		  <default remote="team" revision="master" />
+		  <project name="app" path="app" groups="team" />
+		</manifest>
+		EOF
+		git add default.xml &&
+		test_tick &&
+		git commit -q -m "Team manifest"
+	) &&
+	git -C src/app branch -M master &&
+	git -C src/manifests branch -M master &&
+	git clone -q --bare src/app repos/app.git &&
+	git clone -q --bare src/manifests repos/manifests.git
+'
+
+test_expect_success "setup workspace with submanifest" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url &&
+		mkdir -p .repo/local_manifests &&
+		cat >.repo/local_manifests/team.xml <<-EOF
+		<?xml version="1.0" encoding="UTF-8"?>
+		<manifest>
+		  <remote name="team" fetch="file://$repos" />
+		  <contactinfo bugurl="https://example.com/bugs" />
+		  <submanifest name="team" remote="team" project="manifests"
+		               revision="master" groups="submanifest" />
+		</manifest>
+		EOF
+	)
+'
+
+test_expect_success "git-repo sync with submanifest" '
+	(
+		cd work &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	) &&
+	test -f work/.repo/submanifests/team/manifests/default.xml &&
+	echo app >expect &&
+	test_cmp expect work/team/app/README
+'
+
+test_expect_success "projects of submanifest are listed" '
+	(
+		cd work &&
+		git-repo list -g all
+	) >actual &&
+	cat >expect <<-EOF &&
+	drivers/driver-1 : drivers/driver1
+	main : main
+	projects/app1 : project1
+	projects/app1/module1 : project1/module1
+	projects/app2 : project2
+	team/app : app
+	EOF
+	test_cmp expect actual &&
+	(
+		cd work &&
+		git-repo list -g submanifest
+	) >actual &&
+	cat >expect <<-EOF &&
+	team/app : app
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "show contactinfo in git-repo info" '
+	(
+		cd work &&
+		git-repo info
+	) >out &&
+	grep "^Bug URL" out >actual &&
+	cat >expect <<-EOF &&
+	Bug URL: https://example.com/bugs
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "new commits in submanifest project are synced" '
+	(
+		cd src/app &&
+		echo "app v2" >README &&
+		test_tick &&
+		git commit -q -a -m "Version 2" &&
+		git push -q "$repos/app.git" master
+	) &&
+	(
+		cd work &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	) &&
+	echo "app v2" >expect &&
+	test_cmp expect work/team/app/README
+'
+
+test_expect_success "saved manifest can be loaded again" '
+	(
+		cd work &&
+		git-repo manifest -o ../saved.xml &&
+		git-repo diffmanifests ../saved.xml
+	) >actual &&
+	test_must_be_empty actual &&
+	grep "<submanifest name=\"team\"" saved.xml &&
+	! grep "path=\"team/app\"" saved.xml
+'
+
+test_expect_success "projects of submanifest are frozen in manifest with revisions" '
+	(
+		cd work &&
+		git-repo manifest -r -o ../frozen.xml &&
+		git-repo diffmanifests --raw ../frozen.xml
+	) >out &&
+	! grep "<submanifest" frozen.xml &&
+	grep "name=\"app\" path=\"team/app\" remote=\"team\" revision=\"$(git -C work/team/app rev-parse HEAD)\"" frozen.xml &&
+	grep "^C team/app $(git -C work/team/app rev-parse HEAD) master$" out
+'
+
+test_expect_success "no problems in saved manifests with submanifest" '
+	(
+		cd work &&
+		git-repo manifest --lint &&
+		git-repo manifest --lint ../saved.xml ../frozen.xml
+	) >actual &&
+	test_must_be_empty actual
+'
+
+test_expect_success "local manifest can extend and remove project of submanifest" '
+	(
+		cd work &&
+		cat >.repo/local_manifests/zz-extend.xml <<-EOF &&
+		<?xml version="1.0" encoding="UTF-8"?>
+		<manifest>
+		  <extend-project name="app" path="team/app" groups="extended" />
+		</manifest>
+		EOF
+		git-repo manifest --lint &&
+		git-repo list -g extended &&
+		cat >.repo/local_manifests/zz-extend.xml <<-EOF &&
+		<?xml version="1.0" encoding="UTF-8"?>
+		<manifest>
+		  <remove-project name="app" />
+		</manifest>
+		EOF
+		git-repo manifest --lint &&
+		git-repo list -p &&
+		rm .repo/local_manifests/zz-extend.xml
+	) >actual &&
+	grep "^team/app : app$" actual &&
+	! grep "^team/app$" actual
+'
+
+test_done
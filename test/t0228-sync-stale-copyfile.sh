@@ -0,0 +1,138 @@
+#!/bin/sh
+
+test_description="sync removes stale files of copyfile and linkfile"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url &&
+		mkdir .repo/local_manifests &&
+		cat >.repo/local_manifests/01-cleanup.xml <<-EOF &&
+		<manifest>
+		  <remove-project name="main" path="main" />
+		</manifest>
+		EOF
+		cat >.repo/local_manifests/02-main.xml <<-EOF
+		<manifest>
+		  <project name="main" path="main" groups="app">
+		    <copyfile src="VERSION" dest="VERSION" />
+		    <copyfile src="VERSION" dest="out/VERSION" />
+		    <linkfile src="Makefile" dest="Makefile" />
+		    <linkfile src="Makefile" dest="out/Makefile" />
+		  </project>
+		</manifest>
+		EOF
+	)
+'
+
+test_expect_success "sync creates files of copyfile and linkfile" '
+	(
+		cd work &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" &&
+		test -f VERSION &&
+		test -f out/VERSION &&
+		test -L Makefile &&
+		test -L out/Makefile &&
+		cut -f1,3 .repo/copy-link-files.list
+	) >actual &&
+	cat >expect <<-EOF &&
+	linkfile	Makefile
+	copyfile	VERSION
+	linkfile	out/Makefile
+	copyfile	out/VERSION
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "sync removes stale files, except modified ones" '
+	(
+		cd work &&
+		echo modified >VERSION &&
+		cat >.repo/local_manifests/02-main.xml <<-EOF &&
+		<manifest>
+		  <project name="main" path="main" groups="app">
+		    <linkfile src="Makefile" dest="Makefile" />
+		  </project>
+		</manifest>
+		EOF
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" &&
+		test -L Makefile &&
+		test ! -e out &&
+		test "$(cat VERSION)" = modified &&
+		cut -f1,3 .repo/copy-link-files.list
+	) >actual &&
+	cat >expect <<-EOF &&
+	linkfile	Makefile
+	copyfile	VERSION
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "removed stale file is not tracked any more" '
+	(
+		cd work &&
+		rm VERSION &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" &&
+		cut -f1,3 .repo/copy-link-files.list
+	) >actual &&
+	cat >expect <<-EOF &&
+	linkfile	Makefile
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "modified file is not taken as baseline by sync of other projects" '
+	(
+		cd work &&
+		cat >.repo/local_manifests/02-main.xml <<-EOF &&
+		<manifest>
+		  <project name="main" path="main" groups="app">
+		    <copyfile src="VERSION" dest="VERSION" />
+		    <linkfile src="Makefile" dest="Makefile" />
+		  </project>
+		</manifest>
+		EOF
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" &&
+		test -f VERSION &&
+		echo modified >VERSION &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" \
+			project1 &&
+		cat >.repo/local_manifests/02-main.xml <<-EOF &&
+		<manifest>
+		  <project name="main" path="main" groups="app">
+		    <linkfile src="Makefile" dest="Makefile" />
+		  </project>
+		</manifest>
+		EOF
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" &&
+		test "$(cat VERSION)" = modified
+	)
+'
+
+test_done
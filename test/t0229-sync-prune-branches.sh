@@ -0,0 +1,160 @@
+#!/bin/sh
+
+test_description="sync with --prune-branches"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${HOME}/r/hello/manifests.git"
+
+test_expect_success "setup" '
+	cp -a "${REPO_TEST_REPOSITORIES}" r &&
+	mkdir work
+'
+
+test_expect_success "init from Maint branch and sync" '
+	(
+		cd work &&
+		git-repo init -u "$manifest_url" -b Maint &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	)
+'
+
+test_expect_success "create branches merged into upstream in different ways" '
+	(
+		cd work/projects/app1 &&
+		base=$(git rev-parse HEAD) &&
+
+		git checkout -q -b merged $base &&
+		echo a >a.txt &&
+		git add a.txt &&
+		test_tick &&
+		git commit -q -m "app1: add a" &&
+		git push -q aone HEAD:Maint &&
+
+		git checkout -q -b squashed $base &&
+		echo b1 >b1.txt &&
+		git add b1.txt &&
+		test_tick &&
+		git commit -q -m "app1: add b1" &&
+		echo b2 >b2.txt &&
+		git add b2.txt &&
+		test_tick &&
+		git commit -q -m "app1: add b2" &&
+		git checkout -q aone/Maint &&
+		git merge -q --squash squashed &&
+		test_tick &&
+		git commit -q -m "app1: add b1 and b2" &&
+		git push -q aone HEAD:Maint &&
+
+		git checkout -q -b cherry $base &&
+		echo c >c.txt &&
+		git add c.txt &&
+		test_tick &&
+		git commit -q -m "app1: add c" &&
+		git checkout -q aone/Maint &&
+		test_tick &&
+		git cherry-pick cherry &&
+		git push -q aone HEAD:Maint &&
+
+		git checkout -q -b unmerged --track aone/Maint &&
+		git reset -q --hard $base &&
+		echo d >d.txt &&
+		git add d.txt &&
+		test_tick &&
+		git commit -q -m "app1: add d"
+	) &&
+	(
+		cd work/projects/app2 &&
+		git checkout -q -b new --track aone/Maint
+	)
+'
+
+test_expect_success "sync --prune-branches" '
+	(
+		cd work &&
+		git-repo sync --prune-branches \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	) >out &&
+	sed -n -e "/^Pruned branches/,/^$/p" out |
+		sed -e "s/was [0-9a-f]*,/was XXX,/" >actual &&
+	cat >expect <<-EOF &&
+	Pruned branches (already merged, backups saved in refs/repo-backup/)
+	------------------------------------------------------------------------------
+	projects/app1        | cherry (was XXX, cherry-picked)
+	projects/app1        | merged (was XXX, merged)
+	projects/app1        | squashed (was XXX, squash-merged)
+
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "unmerged branches and new branches are kept" '
+	(
+		cd work/projects/app1 &&
+		git for-each-ref --format="%(refname)" refs/heads/ &&
+		cd ../app2 &&
+		git for-each-ref --format="%(refname)" refs/heads/ &&
+		git symbolic-ref -q HEAD
+	) >actual &&
+	cat >expect <<-EOF &&
+	refs/heads/unmerged
+	refs/heads/new
+	refs/heads/new
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "pruned branches are saved in refs/repo-backup/" '
+	(
+		cd work/projects/app1 &&
+		git for-each-ref --format="%(refname) %(subject)" refs/repo-backup/ &&
+		git reflog show --format="%gs" refs/repo-backup/merged
+	) >actual &&
+	cat >expect <<-EOF &&
+	refs/repo-backup/cherry app1: add c
+	refs/repo-backup/merged app1: add a
+	refs/repo-backup/squashed app1: add b2
+	prune: merged into $(cd work/projects/app1 && git rev-parse aone/Maint)
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "branch without reflog is pruned after merged into upstream" '
+	(
+		cd work/projects/app1 &&
+		git config core.logAllRefUpdates false &&
+		git checkout -q -b nolog --track aone/Maint &&
+		echo e >e.txt &&
+		git add e.txt &&
+		test_tick &&
+		git commit -q -m "app1: add e" &&
+		git checkout -q --detach &&
+		test_must_fail git reflog exists refs/heads/nolog &&
+		git push -q "$(git remote get-url aone)" nolog:Maint
+	) &&
+	(
+		cd work &&
+		git-repo sync --prune-branches \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	) >out &&
+	git -C work/projects/app1 config --unset core.logAllRefUpdates &&
+	sed -n -e "/^Pruned branches/,/^$/p" out |
+		sed -e "s/was [0-9a-f]*,/was XXX,/" >actual &&
+	cat >expect <<-EOF &&
+	Pruned branches (already merged, backups saved in refs/repo-backup/)
+	------------------------------------------------------------------------------
+	projects/app1        | nolog (was XXX, merged)
+
+	EOF
+	test_cmp expect actual
+'
+
+test_done
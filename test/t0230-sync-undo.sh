@@ -0,0 +1,164 @@
+#!/bin/sh
+
+test_description="undo sync -d and prune using journal of operations"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	)
+'
+
+test_expect_success "nothing to undo" '
+	(
+		cd work &&
+		test_must_fail git-repo undo
+	) >actual 2>&1 &&
+	grep "nothing to undo" actual
+'
+
+test_expect_success "commits on detached HEAD are discarded by sync -d" '
+	(
+		cd work/main &&
+		git checkout -q --detach HEAD &&
+		test_tick &&
+		git commit -q --allow-empty -m "commit on detached HEAD" &&
+		git rev-parse HEAD >../../expect-head
+	) &&
+	(
+		cd work &&
+		git-repo sync -d -l
+	) &&
+	(
+		cd work/main &&
+		git rev-parse HEAD >../../actual-head
+	) &&
+	! test_cmp expect-head actual-head
+'
+
+test_expect_success "undo sync -d restores detached HEAD" '
+	(
+		cd work &&
+		git-repo undo --list >../out &&
+		git-repo undo >../actual-undo &&
+		cd main &&
+		git rev-parse HEAD >../../actual-head
+	) &&
+	sed -e "s/[0-9]\{4\}-[-0-9]* [:0-9]*/<date>/" out >actual &&
+	cat >expect <<-EOF &&
+	   1  <date>  sync -d -l: 1 refs in 1 projects
+	EOF
+	test_cmp expect actual &&
+	test_cmp expect-head actual-head &&
+	cat >expect <<-EOF &&
+	Undo operation 1: sync -d -l
+	project main> HEAD: $(cat expect-head)
+	EOF
+	test_cmp expect actual-undo
+'
+
+test_expect_success "undo again fails" '
+	(
+		cd work &&
+		test_must_fail git-repo undo 1
+	) >actual 2>&1 &&
+	grep "operation 1 is already undone" actual
+'
+
+test_expect_success "branches deleted by prune are restored by undo" '
+	(
+		cd work &&
+		git-repo start --all jx/topic &&
+		git-repo sync -d -l &&
+		git-repo prune >/dev/null &&
+		(
+			cd main &&
+			test_must_fail git rev-parse --verify -q refs/heads/jx/topic
+		) &&
+		git-repo undo &&
+		(
+			cd main &&
+			git rev-parse --verify -q refs/heads/jx/topic
+		) &&
+		(
+			cd projects/app1 &&
+			git rev-parse --verify -q refs/heads/jx/topic
+		)
+	)
+'
+
+test_expect_success "undo fails if refs changed after the operation" '
+	(
+		cd work &&
+		git-repo prune >/dev/null &&
+		(
+			cd main &&
+			git branch jx/topic HEAD~1
+		) &&
+		test_must_fail git-repo undo >../actual 2>&1 &&
+		(
+			cd main &&
+			git rev-parse HEAD~1 >../../expect &&
+			git rev-parse jx/topic >../../actual-topic
+		) &&
+		git-repo undo --force &&
+		(
+			cd main &&
+			git rev-parse HEAD >../../expect &&
+			git rev-parse jx/topic >../../actual-topic
+		)
+	) &&
+	grep "main> .refs/heads/jx/topic. is changed after the operation" actual &&
+	test_cmp expect actual-topic
+'
+
+test_expect_success "undo sync which rebased the checked-out branch" '
+	(
+		cd work &&
+		git-repo start jx/rebase main &&
+		cd main &&
+		upstream=$(git rev-parse --symbolic-full-name jx/rebase@{upstream}) &&
+		echo local >local.txt &&
+		git add local.txt &&
+		test_tick &&
+		git commit -q -m "add local.txt" &&
+		git rev-parse HEAD >../../expect-head &&
+		git checkout -q --detach $upstream &&
+		echo upstream >upstream.txt &&
+		git add upstream.txt &&
+		test_tick &&
+		git commit -q -m "add upstream.txt" &&
+		git update-ref $upstream HEAD &&
+		git checkout -q jx/rebase
+	) &&
+	(
+		cd work &&
+		git-repo sync -l main &&
+		test -f main/upstream.txt &&
+		git-repo undo &&
+		cd main &&
+		git rev-parse HEAD >../../actual-head &&
+		git symbolic-ref HEAD >../../actual-branch &&
+		git status --porcelain >../../actual-status &&
+		test ! -f upstream.txt &&
+		test -f local.txt
+	) &&
+	test_cmp expect-head actual-head &&
+	echo refs/heads/jx/rebase >expect-branch &&
+	test_cmp expect-branch actual-branch &&
+	test_must_be_empty actual-status
+'
+
+test_done
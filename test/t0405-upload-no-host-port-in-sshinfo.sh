@@ -76,6 +76,7 @@ test_expect_success "no host/port in ssh_info: bad review url" '
 		[FAILED] main/           my/topic1      
 		       (bad review URL: file:///path/to/hello/main.git)
 		
+		Error: fail to upload 1 of 1 branches
 		EOF
 		test_must_fail git-repo upload \
 			--assume-yes \
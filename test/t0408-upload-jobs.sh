@@ -0,0 +1,82 @@
+#!/bin/sh
+
+test_description="upload branches of projects simultaneously"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work
+'
+
+test_expect_success "git-repo init & sync" '
+	(
+		cd work &&
+		git-repo init -u $manifest_url -g all -b Maint &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\", \"version\":2}"
+	)
+'
+
+test_expect_success "create commits" '
+	(
+		cd work &&
+		git repo start --all my/topic1 &&
+		for p in main projects/app1 projects/app2
+		do
+			(
+				cd $p &&
+				echo hack >topic1.txt &&
+				git add topic1.txt &&
+				test_tick &&
+				git commit -q -m "topic1: new file"
+			) || return 1
+		done
+	)
+'
+
+test_expect_success "upload with -j, output is grouped by project" '
+	(
+		cd work &&
+		git-repo upload \
+			-j 3 \
+			--assume-yes \
+			--no-edit \
+			--mock-git-push \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\", \"version\":2}" \
+			>out 2>&1
+	) &&
+	for p in main projects/app1 projects/app2
+	do
+		grep -A1 "^project $p/ (branch my/topic1):" work/out || return 1
+	done >actual &&
+	cat >expect <<-EOF &&
+	project main/ (branch my/topic1):
+	NOTE: main> will execute command: git push ssh://git@ssh.example.com/main.git refs/heads/my/topic1:refs/for/Maint/my/topic1
+	project projects/app1/ (branch my/topic1):
+	NOTE: projects/app1> will execute command: git push ssh://git@ssh.example.com/project1.git refs/heads/my/topic1:refs/for/Maint/my/topic1
+	project projects/app2/ (branch my/topic1):
+	NOTE: projects/app2> will execute command: git push ssh://git@ssh.example.com/project2.git refs/heads/my/topic1:refs/for/Maint/my/topic1
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "all branches are published" '
+	(
+		cd work &&
+		for p in main projects/app1 projects/app2
+		do
+			git -C $p rev-parse refs/published/my/topic1 || return 1
+		done
+	)
+'
+
+test_done
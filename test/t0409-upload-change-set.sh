@@ -0,0 +1,199 @@
+#!/bin/sh
+
+test_description="upload and download change set across projects"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${HOME}/r/hello/manifests.git"
+
+test_expect_success "setup" '
+	cp -a "${REPO_TEST_REPOSITORIES}" r &&
+	mkdir work
+'
+
+test_expect_success "git-repo init & sync" '
+	(
+		cd work &&
+		git-repo init -u "$manifest_url" -b Maint &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\", \"version\":2}"
+	)
+'
+
+test_expect_success "create commits" '
+	(
+		cd work &&
+		git repo start --all my/topic1 &&
+		for p in main projects/app1
+		do
+			(
+				cd $p &&
+				echo hack >topic1.txt &&
+				git add topic1.txt &&
+				test_tick &&
+				git commit -q -m "topic1: new file" &&
+				git push -q aone my/topic1:refs/merge-requests/12/head &&
+				git push -q aone my/topic1:refs/changes/34/34/1
+			) || return 1
+		done
+	)
+'
+
+test_expect_success "bad topic" '
+	(
+		cd work &&
+		test_must_fail git-repo upload \
+			--topic "bad topic" \
+			--assume-yes \
+			--no-edit \
+			--mock-git-push \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\", \"version\":2}"
+	) >actual 2>&1 &&
+	grep "invalid topic .bad topic." actual
+'
+
+test_expect_success "upload change set to agit server" '
+	(
+		cd work &&
+		git-repo upload \
+			--topic my-feature \
+			--assume-yes \
+			--no-edit \
+			--mock-git-push \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\", \"version\":2}" \
+			>out 2>&1
+	) &&
+	grep "will execute command" work/out >actual &&
+	cat >expect <<-EOF &&
+	NOTE: main> will execute command: git push ssh://git@ssh.example.com/main.git refs/heads/my/topic1:refs/for/Maint/my-feature
+	NOTE: projects/app1> will execute command: git push ssh://git@ssh.example.com/project1.git refs/heads/my/topic1:refs/for/Maint/my-feature
+	EOF
+	test_cmp expect actual &&
+	cat >expect <<-EOF &&
+	<?xml version="1.0" encoding="UTF-8"?>
+	<manifest>
+	  <project name="main" path="main" remote="aone" revision="refs/merge-requests/12/head" dest-branch="Maint"></project>
+	  <project name="project1" path="projects/app1" remote="aone" revision="refs/merge-requests/12/head" dest-branch="Maint"></project>
+	</manifest>
+	EOF
+	test_cmp expect work/.repo/change-sets/my-feature.xml
+'
+
+test_expect_success "upload change set to gerrit server" '
+	(
+		cd work &&
+		git -C main update-ref -d refs/published/my/topic1 &&
+		git -C projects/app1 update-ref -d refs/published/my/topic1 &&
+		git-repo upload \
+			--topic my-feature \
+			--auto-topic \
+			--assume-yes \
+			--no-cache \
+			--no-edit \
+			--mock-git-push \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"gerrit\"}" \
+			>out 2>&1
+	) &&
+	grep "will execute command" work/out >actual &&
+	cat >expect <<-EOF &&
+	NOTE: main> will execute command: git push --receive-pack=gerrit receive-pack ssh://committer@ssh.example.com/main.git refs/heads/my/topic1:refs/for/Maint%topic=my-feature
+	NOTE: projects/app1> will execute command: git push --receive-pack=gerrit receive-pack ssh://committer@ssh.example.com/project1.git refs/heads/my/topic1:refs/for/Maint%topic=my-feature
+	EOF
+	test_cmp expect actual &&
+	cat >expect <<-EOF &&
+	<?xml version="1.0" encoding="UTF-8"?>
+	<manifest>
+	  <project name="main" path="main" remote="aone" revision="refs/changes/34/34/1" dest-branch="Maint"></project>
+	  <project name="project1" path="projects/app1" remote="aone" revision="refs/changes/34/34/1" dest-branch="Maint"></project>
+	</manifest>
+	EOF
+	test_cmp expect work/.repo/change-sets/my-feature.xml
+'
+
+test_expect_success "download needs a change set file" '
+	(
+		cd work &&
+		test_must_fail git-repo download --change-set my-feature
+	) >actual 2>&1 &&
+	grep "cannot read change set" actual
+'
+
+test_expect_success "download change set in the same workspace" '
+	(
+		cd work &&
+		git -C main checkout -q --detach aone/Maint &&
+		git -C projects/app1 checkout -q --detach aone/Maint &&
+		git-repo download --change-set .repo/change-sets/my-feature.xml &&
+		git -C main rev-parse my/topic1 >expect &&
+		git -C main rev-parse HEAD >actual &&
+		test_cmp expect actual &&
+		git -C projects/app1 rev-parse my/topic1 >expect &&
+		git -C projects/app1 rev-parse HEAD >actual &&
+		test_cmp expect actual
+	)
+'
+
+test_expect_success "download change set file in another workspace" '
+	mkdir work2 &&
+	(
+		cd work2 &&
+		git-repo init -u "$manifest_url" -b Maint &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\", \"version\":2}" &&
+		git-repo download --change-set ../work/.repo/change-sets/my-feature.xml &&
+		git -C ../work/main rev-parse my/topic1 >expect &&
+		git -C main rev-parse HEAD >actual &&
+		test_cmp expect actual &&
+		git -C ../work/projects/app1 rev-parse my/topic1 >expect &&
+		git -C projects/app1 rev-parse HEAD >actual &&
+		test_cmp expect actual
+	)
+'
+
+test_expect_success "fail to upload change set if review is not found" '
+	(
+		cd work &&
+		git -C main checkout -q my/topic1 &&
+		git -C main update-ref -d refs/published/my/topic1 &&
+		(
+			cd projects/app1 &&
+			git checkout -q my/topic1 &&
+			echo hack >topic2.txt &&
+			git add topic2.txt &&
+			test_tick &&
+			git commit -q -m "topic2: new file"
+		) &&
+		test_must_fail git-repo upload \
+			--topic my-feature2 \
+			--assume-yes \
+			--no-cache \
+			--no-edit \
+			--mock-git-push \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\", \"version\":2}" \
+			>out 2>&1
+	) &&
+	grep "^\[FAILED\] projects/app1/ " work/out &&
+	grep "not saved in change set .my-feature2.: cannot find code review of commit" work/out &&
+	cat >expect <<-EOF &&
+	<?xml version="1.0" encoding="UTF-8"?>
+	<manifest>
+	  <project name="main" path="main" remote="aone" revision="refs/merge-requests/12/head" dest-branch="Maint"></project>
+	</manifest>
+	EOF
+	test_cmp expect work/.repo/change-sets/my-feature2.xml
+'
+
+test_done
@@ -0,0 +1,217 @@
+#!/bin/sh
+
+test_description="check commits before upload"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+# upload <type> [<options>...]
+upload () {
+	type=$1 &&
+	shift &&
+	git-repo upload \
+		--assume-yes \
+		--no-edit \
+		--mock-git-push \
+		--no-cache \
+		--mock-ssh-info-status 200 \
+		--mock-ssh-info-response \
+		"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"$type\"}" \
+		"$@"
+}
+
+# show_problems filters out confirmation of upload
+show_problems () {
+	grep -e "^ERROR" -e "^Error" -e "^    [0-9a-f]\{7\}:" |
+	sed -e "s/^    [0-9a-f]\{7\}:/    <hash>:/"
+}
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u "$manifest_url" -g all -b Maint &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" &&
+		git repo start --all my/topic
+	)
+'
+
+test_expect_success "upload checks are disabled by default" '
+	(
+		cd work/main &&
+		echo hack >topic.txt &&
+		git add topic.txt &&
+		test_tick &&
+		git commit -q -m "topic: new file" &&
+		echo hack >>topic.txt &&
+		git add topic.txt &&
+		test_tick &&
+		git commit -q -m "fixup! topic: new file"
+	) &&
+	(
+		cd work &&
+		upload agit &&
+		git -C main update-ref -d refs/published/my/topic
+	) >out 2>&1 &&
+	grep "will execute command: git push" out
+'
+
+test_expect_success "fixup commit is rejected" '
+	(
+		cd work &&
+		git -C main config review.https://example.com.checkFixup true &&
+		test_must_fail upload agit
+	) >out 2>&1 &&
+	show_problems <out >actual &&
+	cat >expect <<-EOF &&
+	ERROR: project main/ (branch my/topic) fails to pass upload checks:
+	    <hash>: fixup! commit, squash it first (checkFixup)
+	Error: found 1 problems in commits, upload aborted (use --no-verify to skip checks)
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "upload with --no-verify" '
+	(
+		cd work &&
+		upload agit --no-verify
+	) >out 2>&1 &&
+	grep "will execute command: git push" out
+'
+
+test_expect_success "disable check by review.<url>.checkFixup" '
+	(
+		cd work &&
+		git -C main update-ref -d refs/published/my/topic &&
+		git -C main config review.https://example.com.checkFixup false &&
+		upload agit
+	) >out 2>&1 &&
+	grep "will execute command: git push" out
+'
+
+test_expect_success "merge commit is rejected" '
+	(
+		cd work/main &&
+		git reset -q --hard HEAD~2 &&
+		git checkout -q -b my/side HEAD &&
+		echo side >side.txt &&
+		git add side.txt &&
+		test_tick &&
+		git commit -q -m "side: new file" &&
+		git checkout -q my/topic &&
+		echo hack >topic.txt &&
+		git add topic.txt &&
+		test_tick &&
+		git commit -q -m "topic: new file" &&
+		test_tick &&
+		git merge -q --no-ff -m "merge side" my/side
+	) &&
+	(
+		cd work &&
+		git -C main config review.https://example.com.checkMerge true &&
+		test_must_fail upload agit
+	) >out 2>&1 &&
+	show_problems <out >actual &&
+	cat >expect <<-EOF &&
+	ERROR: project main/ (branch my/topic) fails to pass upload checks:
+	    <hash>: merge commit is not allowed (checkMerge)
+	Error: found 1 problems in commits, upload aborted (use --no-verify to skip checks)
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "email of author and committer" '
+	(
+		cd work/main &&
+		git reset -q --hard HEAD~2 &&
+		echo hack >topic.txt &&
+		git add topic.txt &&
+		test_tick &&
+		GIT_COMMITTER_EMAIL=other@example.com git commit -q -m "topic: new file"
+	) &&
+	(
+		cd work &&
+		git -C main config review.https://example.com.checkEmail true &&
+		test_must_fail upload agit &&
+		git -C main config review.https://example.com.checkEmail author &&
+		test_must_fail upload agit
+	) >out 2>&1 &&
+	show_problems <out >actual &&
+	cat >expect <<-EOF &&
+	ERROR: project main/ (branch my/topic) fails to pass upload checks:
+	    <hash>: committer email <other@example.com> does not match <committer@example.com> (checkEmail)
+	Error: found 1 problems in commits, upload aborted (use --no-verify to skip checks)
+	ERROR: project main/ (branch my/topic) fails to pass upload checks:
+	    <hash>: author email <author@example.com> does not match <committer@example.com> (checkEmail)
+	Error: found 1 problems in commits, upload aborted (use --no-verify to skip checks)
+	EOF
+	test_cmp expect actual &&
+	git -C work/main config --unset review.https://example.com.checkEmail
+'
+
+test_expect_success "file over size limit" '
+	(
+		cd work/main &&
+		git reset -q --hard HEAD~1 &&
+		printf "%02000d" 0 >big.txt &&
+		git add big.txt &&
+		test_tick &&
+		git commit -q -m "topic: big file"
+	) &&
+	(
+		cd work &&
+		upload agit >/dev/null &&
+		git -C main update-ref -d refs/published/my/topic &&
+		git -C main config review.https://example.com.maxFileSize 1k &&
+		test_must_fail upload agit
+	) >out 2>&1 &&
+	show_problems <out >actual &&
+	cat >expect <<-EOF &&
+	ERROR: project main/ (branch my/topic) fails to pass upload checks:
+	    <hash>: file larger than 1k: big.txt (2000 bytes) (maxFileSize)
+	Error: found 1 problems in commits, upload aborted (use --no-verify to skip checks)
+	EOF
+	test_cmp expect actual &&
+	git -C work/main config --unset review.https://example.com.maxFileSize
+'
+
+test_expect_success "missing Change-Id for gerrit" '
+	(
+		cd work/main &&
+		git reset -q --hard HEAD~1 &&
+		echo hack >topic.txt &&
+		git add topic.txt &&
+		test_tick &&
+		git commit -q --no-verify -m "topic: no Change-Id" &&
+		echo hack >>topic.txt &&
+		git add topic.txt &&
+		test_tick &&
+		git commit -q --no-verify -m "topic: has Change-Id" \
+			-m "Change-Id: I0123456789abcdef0123456789abcdef01234567"
+	) &&
+	(
+		cd work &&
+		upload agit >/dev/null &&
+		git -C main update-ref -d refs/published/my/topic &&
+		upload gerrit >/dev/null &&
+		git -C main update-ref -d refs/published/my/topic &&
+		git -C main config review.https://example.com.checkChangeId true &&
+		test_must_fail upload gerrit
+	) >out 2>&1 &&
+	show_problems <out >actual &&
+	cat >expect <<-EOF &&
+	ERROR: project main/ (branch my/topic) fails to pass upload checks:
+	    <hash>: missing Change-Id in commit message (checkChangeId)
+	Error: found 1 problems in commits, upload aborted (use --no-verify to skip checks)
+	EOF
+	test_cmp expect actual
+'
+
+test_done
@@ -0,0 +1,205 @@
+#!/bin/sh
+
+test_description="show plan of upload in JSON format"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u "$manifest_url" -g all -b Maint &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\", \"version\":2}" &&
+		git repo start --all my/topic &&
+		for p in main projects/app1
+		do
+			(
+				cd $p &&
+				echo hack >topic.txt &&
+				git add topic.txt &&
+				test_tick &&
+				git commit -q -m "topic: new file"
+			) || return 1
+		done
+	)
+'
+
+test_expect_success "unsupported format of plan" '
+	(
+		cd work &&
+		test_must_fail git-repo upload --plan=yaml
+	) >out 2>&1 &&
+	grep "unsupported format .yaml. for --plan, only json is supported" out
+'
+
+test_expect_success "upload --plan=json" '
+	(
+		cd work &&
+		git-repo upload \
+			--plan=json \
+			--reviewers user1,user2 \
+			--cc user3 \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\", \"version\":2}" \
+			>../out 2>../err
+	) &&
+	sed -e "s/git-repo\/[^ \"\\]*/git-repo\/n.n.n.n/g" <out >actual &&
+	cat >expect <<-EOF &&
+	[
+	  {
+	    "project": "main",
+	    "path": "main",
+	    "branch": "my/topic",
+	    "remote_url": "ssh://git@ssh.example.com/main.git",
+	    "dest_branch": "Maint",
+	    "reviewers": [
+	      "user1",
+	      "user2"
+	    ],
+	    "cc": [
+	      "user3"
+	    ],
+	    "push_command": {
+	      "cmd": "git",
+	      "args": [
+	        "push",
+	        "-o",
+	        "reviewers=user1,user2",
+	        "-o",
+	        "cc=user3",
+	        "ssh://git@ssh.example.com/main.git",
+	        "refs/heads/my/topic:refs/for/Maint/my/topic"
+	      ],
+	      "env": [
+	        "AGIT_FLOW=git-repo/n.n.n.n"
+	      ]
+	    },
+	    "command": [
+	      "git",
+	      "push",
+	      "-o",
+	      "reviewers=user1,user2",
+	      "-o",
+	      "cc=user3",
+	      "ssh://git@ssh.example.com/main.git",
+	      "refs/heads/my/topic:refs/for/Maint/my/topic"
+	    ],
+	    "env": [
+	      "AGIT_FLOW=git-repo/n.n.n.n",
+	      "GIT_SSH_COMMAND=ssh -o SendEnv=AGIT_FLOW"
+	    ]
+	  },
+	  {
+	    "project": "project1",
+	    "path": "projects/app1",
+	    "branch": "my/topic",
+	    "remote_url": "ssh://git@ssh.example.com/project1.git",
+	    "dest_branch": "Maint",
+	    "reviewers": [
+	      "user1",
+	      "user2"
+	    ],
+	    "cc": [
+	      "user3"
+	    ],
+	    "push_command": {
+	      "cmd": "git",
+	      "args": [
+	        "push",
+	        "-o",
+	        "reviewers=user1,user2",
+	        "-o",
+	        "cc=user3",
+	        "ssh://git@ssh.example.com/project1.git",
+	        "refs/heads/my/topic:refs/for/Maint/my/topic"
+	      ],
+	      "env": [
+	        "AGIT_FLOW=git-repo/n.n.n.n"
+	      ]
+	    },
+	    "command": [
+	      "git",
+	      "push",
+	      "-o",
+	      "reviewers=user1,user2",
+	      "-o",
+	      "cc=user3",
+	      "ssh://git@ssh.example.com/project1.git",
+	      "refs/heads/my/topic:refs/for/Maint/my/topic"
+	    ],
+	    "env": [
+	      "AGIT_FLOW=git-repo/n.n.n.n",
+	      "GIT_SSH_COMMAND=ssh -o SendEnv=AGIT_FLOW"
+	    ]
+	  }
+	]
+	EOF
+	test_cmp expect actual &&
+	test_must_be_empty err
+'
+
+test_expect_success "nothing is pushed by upload --plan" '
+	(
+		cd work &&
+		test_must_fail git -C main rev-parse --verify -q refs/published/my/topic &&
+		test_must_fail git -C projects/app1 rev-parse --verify -q refs/published/my/topic
+	)
+'
+
+test_expect_success "old oid of published branch is shown in plan" '
+	(
+		cd work &&
+		git-repo upload \
+			--assume-yes \
+			--no-edit \
+			--mock-git-push \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\", \"version\":2}" \
+			main >/dev/null 2>&1 &&
+		git -C main rev-parse HEAD >../expect-oid &&
+		(
+			cd main &&
+			echo hack >>topic.txt &&
+			git add topic.txt &&
+			test_tick &&
+			git commit -q -m "topic: update file"
+		) &&
+		git-repo upload \
+			--plan=json \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\", \"version\":2}" \
+			main >../out
+	) &&
+	grep "\"old_oid\": \"$(cat expect-oid)\"" out &&
+	grep "\"oldoid=$(cat expect-oid)\"" out
+'
+
+test_expect_success "plan of change set needs topic to pin it" '
+	(
+		cd work &&
+		test_must_fail git-repo upload --plan=json --change-set >../out 2>&1 &&
+		grep "plan with --change-set needs --topic to pin the topic" ../out &&
+		git-repo upload \
+			--plan=json \
+			--change-set \
+			--topic my-feature \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\", \"version\":2}" \
+			main >../out
+	) &&
+	grep "refs/heads/my/topic:refs/for/Maint/my-feature" out
+'
+
+test_done
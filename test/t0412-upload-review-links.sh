@@ -0,0 +1,213 @@
+#!/bin/sh
+
+test_description="save and show links of reviews after upload"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u "$manifest_url" -g all -b Maint &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" &&
+		git repo start --all my/topic &&
+		for p in main projects/app1
+		do
+			(
+				cd $p &&
+				echo hack >topic.txt &&
+				git add topic.txt &&
+				test_tick &&
+				git commit -q -m "topic: new file"
+			) || return 1
+		done
+	)
+'
+
+test_expect_success "show review links of agit server" '
+	cat >agit-output <<-EOF &&
+	remote: +------------------------------------------------------------------------+
+	remote: | Merge request #123 was created or updated.                             |
+	remote: | View merge request at URL:                                             |
+	remote: | https://example.com/merge_requests/123                                 |
+	remote: +------------------------------------------------------------------------+
+	EOF
+	(
+		cd work &&
+		git-repo upload \
+			--assume-yes \
+			--no-edit \
+			--mock-git-push \
+			--mock-git-push-output ../agit-output \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" \
+			>out 2>&1
+	) &&
+	sed -n -e "/^-----/,\$p" work/out >actual &&
+	cat >expect <<-EOF &&
+	----------------------------------------------------------------------
+	[REVIEW] main/           my/topic        #123 https://example.com/merge_requests/123
+	[REVIEW] projects/app1/  my/topic        #123 https://example.com/merge_requests/123
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "review is saved in config of branch" '
+	(
+		cd work/main &&
+		git config branch.my/topic.review-id &&
+		git config branch.my/topic.review-url
+	) >actual &&
+	cat >expect <<-EOF &&
+	123
+	https://example.com/merge_requests/123
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "show review links of gerrit" '
+	cat >gerrit-output <<-EOF &&
+	remote: Processing changes: new: 1, done
+	remote:
+	remote: New Changes:
+	remote:   https://example.com/c/main/+/1001 topic: new file
+	remote:
+	EOF
+	(
+		cd work &&
+		git -C main update-ref -d refs/published/my/topic &&
+		git-repo upload \
+			--assume-yes \
+			--no-cache \
+			--no-edit \
+			--mock-git-push \
+			--mock-git-push-output ../gerrit-output \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"gerrit\"}" \
+			main >out 2>&1 &&
+		git -C main config branch.my/topic.review-id >>out
+	) &&
+	sed -n -e "/^-----/,\$p" work/out >actual &&
+	cat >expect <<-EOF &&
+	----------------------------------------------------------------------
+	[REVIEW] main/           my/topic        #1001 https://example.com/c/main/+/1001
+	1001
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "no review link if server shows nothing" '
+	(
+		cd work &&
+		git -C main update-ref -d refs/published/my/topic &&
+		git-repo upload \
+			--assume-yes \
+			--no-edit \
+			--mock-git-push \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" \
+			main >out 2>&1
+	) &&
+	sed -n -e "/^-----/,\$p" work/out >actual &&
+	cat >expect <<-EOF &&
+	----------------------------------------------------------------------
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "setup single repository" '
+	git clone -q "file://${REPO_TEST_REPOSITORIES}/hello/main.git" single &&
+	(
+		cd single &&
+		git config remote.origin.url https://example.com/jiangxin/main.git &&
+		git checkout -q -b my/topic origin/master &&
+		echo hack >topic.txt &&
+		git add topic.txt &&
+		test_tick &&
+		git commit -q -m "topic: new file"
+	)
+'
+
+# upload_single [<options>...]
+upload_single () {
+	git-repo upload --single \
+		--assume-yes \
+		--no-cache \
+		--no-edit \
+		--mock-git-push \
+		--mock-ssh-info-status 200 \
+		--mock-ssh-info-response \
+		"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" \
+		"$@"
+}
+
+test_expect_success "saved review is not updated by default" '
+	(
+		cd single &&
+		upload_single --mock-git-push-output ../agit-output >../out 2>&1 &&
+		git config branch.my/topic.review-id >../actual &&
+		echo hack >>topic.txt &&
+		git add topic.txt &&
+		test_tick &&
+		git commit -q -m "topic: update file" &&
+		upload_single >../out 2>&1
+	) &&
+	echo 123 >expect &&
+	test_cmp expect actual &&
+	grep -e "^NOTE: will" out >actual &&
+	cat >expect <<-EOF &&
+	NOTE: will execute command: git push --receive-pack=agit-receive-pack -o oldoid=$(git -C single rev-parse HEAD~1) ssh://git@ssh.example.com/jiangxin/main.git refs/heads/my/topic:refs/for/master/my/topic
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "saved review is updated by next upload if enabled" '
+	(
+		cd single &&
+		git config review.https://example.com.updateSavedReview true &&
+		upload_single --mock-git-push-output ../agit-output >../out 2>&1 &&
+		echo hack >>topic.txt &&
+		git add topic.txt &&
+		test_tick &&
+		git commit -q -m "topic: update file again" &&
+		upload_single >../out 2>&1
+	) &&
+	grep -e "^NOTE: will" out >actual &&
+	cat >expect <<-EOF &&
+	NOTE: will update code review #123 uploaded from branch '"'"'my/topic'"'"' (unset branch.my/topic.review-id to create a new one)
+	NOTE: will execute command: git push --receive-pack=agit-receive-pack -o oldoid=$(git -C single rev-parse HEAD~1) ssh://git@ssh.example.com/jiangxin/main.git refs/heads/my/topic:refs/for-review/123
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "saved review is cleared after merged" '
+	(
+		cd single &&
+		git update-ref refs/remotes/origin/master refs/published/my/topic &&
+		echo hack >>topic.txt &&
+		git add topic.txt &&
+		test_tick &&
+		git commit -q -m "topic: new change" &&
+		upload_single >../out 2>&1 &&
+		test_must_fail git config branch.my/topic.review-id
+	) &&
+	grep -e "^NOTE: " out >actual &&
+	cat >expect <<-EOF &&
+	NOTE: code review #123 of branch '"'"'my/topic'"'"' is merged, will create a new one
+	NOTE: will execute command: git push --receive-pack=agit-receive-pack -o oldoid=$(git -C single rev-parse HEAD~1) ssh://git@ssh.example.com/jiangxin/main.git refs/heads/my/topic:refs/for/master/my/topic
+	EOF
+	test_cmp expect actual
+'
+
+test_done
@@ -0,0 +1,61 @@
+#!/bin/sh
+
+test_description="test 'git-repo forall --format json'"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	)
+'
+
+test_expect_success "exit code, stdout and stderr" '
+	(
+		cd work &&
+		git-repo forall -r "^main$" -r "^project2$" -j 2 --format json -c '"'"'
+			echo "stdout of $REPO_PROJECT"
+			echo "stderr of $REPO_PROJECT" >&2
+			test "$REPO_PROJECT" = main'"'"'
+	) >actual &&
+	cat >expect<<-EOF &&
+	[
+	  {
+	    "name": "main",
+	    "path": "main",
+	    "remote": "aone",
+	    "revision": "master",
+	    "ahead": 0,
+	    "behind": 0,
+	    "exit_code": 0,
+	    "stdout": "stdout of main\n",
+	    "stderr": "stderr of main\n"
+	  },
+	  {
+	    "name": "project2",
+	    "path": "projects/app2",
+	    "remote": "aone",
+	    "revision": "master",
+	    "ahead": 0,
+	    "behind": 0,
+	    "exit_code": 1,
+	    "stdout": "stdout of project2\n",
+	    "stderr": "stderr of project2\n"
+	  }
+	]
+	EOF
+	test_cmp expect actual
+'
+
+test_done
@@ -0,0 +1,125 @@
+#!/bin/sh
+
+test_description="test 'git-repo status --format json'"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" &&
+		git-repo start --all jx/topic
+	)
+'
+
+test_expect_success "bad format" '
+	(
+		cd work &&
+		test_must_fail git-repo status --format xml
+	)
+'
+
+test_expect_success "changes in index and worktree" '
+	(
+		cd work &&
+		(
+			cd main &&
+			echo hello >>VERSION &&
+			git add VERSION &&
+			echo hello >>Makefile &&
+			echo hello >new.txt
+		) &&
+		git-repo status --format json main projects/app2
+	) >actual &&
+	cat >expect<<-EOF &&
+	[
+	  {
+	    "name": "main",
+	    "path": "main",
+	    "remote": "aone",
+	    "revision": "master",
+	    "branch": "jx/topic",
+	    "ahead": 0,
+	    "behind": 0,
+	    "files": {
+	      "index": [
+	        {
+	          "status": "M",
+	          "path": "VERSION"
+	        }
+	      ],
+	      "worktree": [
+	        {
+	          "status": "M",
+	          "path": "Makefile"
+	        }
+	      ],
+	      "untracked": [
+	        "new.txt"
+	      ]
+	    }
+	  },
+	  {
+	    "name": "project2",
+	    "path": "projects/app2",
+	    "remote": "aone",
+	    "revision": "master",
+	    "branch": "jx/topic",
+	    "ahead": 0,
+	    "behind": 0,
+	    "files": {
+	      "index": [],
+	      "worktree": [],
+	      "untracked": []
+	    }
+	  }
+	]
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "ahead of upstream" '
+	(
+		cd work/main &&
+		git commit -q -m "update VERSION" &&
+		git-repo status --format json main
+	) >actual &&
+	cat >expect<<-EOF &&
+	[
+	  {
+	    "name": "main",
+	    "path": "main",
+	    "remote": "aone",
+	    "revision": "master",
+	    "branch": "jx/topic",
+	    "ahead": 1,
+	    "behind": 0,
+	    "files": {
+	      "index": [],
+	      "worktree": [
+	        {
+	          "status": "M",
+	          "path": "Makefile"
+	        }
+	      ],
+	      "untracked": [
+	        "new.txt"
+	      ]
+	    }
+	  }
+	]
+	EOF
+	test_cmp expect actual
+'
+
+test_done
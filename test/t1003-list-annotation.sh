@@ -0,0 +1,105 @@
+#!/bin/sh
+
+test_description="test annotations in 'git-repo list' and 'git-repo forall'"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url &&
+		mkdir .repo/local_manifests &&
+		cat >.repo/local_manifests/annotations.xml <<-EOF &&
+		<?xml version="1.0" encoding="UTF-8"?>
+		<manifest>
+		  <project name="others/demo1" path="others/demo-1" remote="driver" revision="master">
+		    <annotation name="owner" value="team-a" />
+		    <annotation name="license" value="Apache-2.0" />
+		  </project>
+		  <project name="others/demo2" path="others/demo-2" remote="driver" revision="master">
+		    <annotation name="owner" value="team-b" />
+		  </project>
+		</manifest>
+		EOF
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	)
+'
+
+test_expect_success "git-repo list with annotations" '
+	(
+		cd work &&
+		git-repo list
+	) >actual &&
+	cat >expect<<-EOF &&
+	drivers/driver-1 : drivers/driver1
+	main : main
+	others/demo-1 : others/demo1 [owner=team-a, license=Apache-2.0]
+	others/demo-2 : others/demo2 [owner=team-b]
+	projects/app1 : project1
+	projects/app1/module1 : project1/module1
+	projects/app2 : project2
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git-repo list --annotation name=value" '
+	(
+		cd work &&
+		git-repo list --annotation owner=team-a
+	) >actual &&
+	cat >expect<<-EOF &&
+	others/demo-1 : others/demo1 [owner=team-a, license=Apache-2.0]
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git-repo list --annotation name" '
+	(
+		cd work &&
+		git-repo list -p --annotation owner
+	) >actual &&
+	cat >expect<<-EOF &&
+	others/demo-1
+	others/demo-2
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git-repo list with multiple --annotation" '
+	(
+		cd work &&
+		git-repo list -n --annotation owner --annotation license=Apache-2.0
+	) >actual &&
+	cat >expect<<-EOF &&
+	others/demo1
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git-repo forall exports annotations" '
+	(
+		cd work &&
+		git-repo forall -j 1 -c '"'"'echo "$REPO_PATH: ${REPO__owner:-none} ${REPO__license:-none}"'"'"'
+	) >actual &&
+	cat >expect<<-EOF &&
+	main: none none
+	projects/app1: none none
+	projects/app1/module1: none none
+	projects/app2: none none
+	drivers/driver-1: none none
+	others/demo-1: team-a Apache-2.0
+	others/demo-2: team-b none
+	EOF
+	test_cmp expect actual
+'
+
+test_done
@@ -0,0 +1,73 @@
+#!/bin/sh
+
+test_description="test 'git-repo list --format json'"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	)
+'
+
+test_expect_success "git-repo list --format json" '
+	(
+		cd work &&
+		git-repo start jx/topic projects/app1 &&
+		git-repo list --format json -r app
+	) >actual &&
+	cat >expect<<-EOF &&
+	[
+	  {
+	    "name": "project1",
+	    "path": "projects/app1",
+	    "remote": "aone",
+	    "revision": "master",
+	    "branch": "jx/topic",
+	    "ahead": 0,
+	    "behind": 0
+	  },
+	  {
+	    "name": "project1/module1",
+	    "path": "projects/app1/module1",
+	    "remote": "aone",
+	    "revision": "refs/tags/v1.0.0",
+	    "ahead": 0,
+	    "behind": 0
+	  },
+	  {
+	    "name": "project2",
+	    "path": "projects/app2",
+	    "remote": "aone",
+	    "revision": "master",
+	    "ahead": 0,
+	    "behind": 0
+	  }
+	]
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "no projects matched" '
+	(
+		cd work &&
+		git-repo list --format json -r not-exist
+	) >actual &&
+	cat >expect<<-EOF &&
+	[]
+	EOF
+	test_cmp expect actual
+'
+
+test_done
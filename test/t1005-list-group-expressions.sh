@@ -0,0 +1,104 @@
+#!/bin/sh
+
+test_description="test group expressions of 'git-repo list' and 'git-repo forall'"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -g all -u $manifest_url &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	)
+'
+
+test_expect_success "git-repo list -g <expression>" '
+	(
+		cd work &&
+		git-repo list -g "drivers & !notdefault" &&
+		git-repo list -g "(app | drivers) & !notdefault"
+	) >actual &&
+	cat >expect<<-EOF &&
+	drivers/driver-1 : drivers/driver1
+	drivers/driver-1 : drivers/driver1
+	main : main
+	projects/app1 : project1
+	projects/app1/module1 : project1/module1
+	projects/app2 : project2
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git-repo list -g <items with expression>" '
+	(
+		cd work &&
+		git-repo list -g "app,-(app & !default),drivers & notdefault"
+	) >actual &&
+	cat >expect<<-EOF &&
+	drivers/driver-2 : drivers/driver2
+	main : main
+	projects/app1 : project1
+	projects/app1/module1 : project1/module1
+	projects/app2 : project2
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git-repo forall -g <expression>" '
+	(
+		cd work &&
+		git-repo forall -g "drivers & notdefault" -c "echo \$REPO_PATH"
+	) >actual &&
+	cat >expect<<-EOF &&
+	drivers/driver-2
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "bad group expression" '
+	(
+		cd work &&
+		test_must_fail git-repo list -g "(app | drivers" &&
+		test_must_fail git-repo forall -g "app &" -c true &&
+		test_must_fail git-repo init -g "!" --platform all
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	Error: invalid groups '"'"'(app | drivers'"'"': missing '"'"')'"'"'
+	Error: invalid groups '"'"'app &'"'"': missing group name at end of expression
+	Error: invalid groups '"'"'!,platform-linux,platform-darwin,platform-windows'"'"': missing group name at end of expression
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git-repo list --groups-of" '
+	(
+		cd work &&
+		git-repo list --groups-of drivers/driver-2 \
+			-g "default,-app,(drivers & !test) | tools"
+	) >actual &&
+	cat >expect<<-EOF &&
+	project drivers/driver-2 (drivers/driver2)
+	match groups: default,-app,(drivers & !test) | tools
+	project groups: all, drivers, notdefault
+	default => not matched
+	-app => not matched
+	drivers & !test | tools => matched, include
+	  drivers & !test: true
+	    drivers: true
+	    !test: true
+	  tools: false
+	result: included
+	EOF
+	test_cmp expect actual
+'
+
+test_done
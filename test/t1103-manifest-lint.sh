@@ -0,0 +1,76 @@
+#!/bin/sh
+
+test_description="test 'git-repo manifest --lint'"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url
+	)
+'
+
+test_expect_success "no problems in manifest of workspace" '
+	(
+		cd work &&
+		git-repo manifest --lint
+	) >actual &&
+	test_must_be_empty actual
+'
+
+test_expect_success "report problems in local manifests" '
+	(
+		cd work &&
+		mkdir -p .repo/local_manifests &&
+		cat >.repo/local_manifests/bad.xml <<-EOF &&
+		<?xml version="1.0" encoding="UTF-8"?>
+		<manifest>
+		  <project name="app" path="projects/app1" remote="unknown">
+		    <copyfile src="VERSION" dest="../VERSION" />
+		  </project>
+		  <extend-project name="project3" />
+		</manifest>
+		EOF
+		test_must_fail git-repo manifest --lint
+	) >actual &&
+	cat >expect <<-EOF &&
+	.repo/local_manifests/bad.xml:3: duplicate path '"'"'projects/app1'"'"' for project '"'"'app'"'"', first defined in $(pwd)/work/.repo/manifest.xml:18
+	.repo/local_manifests/bad.xml:4: dest '"'"'../VERSION'"'"' of copyfile in project '"'"'app'"'"' contains '"'"'..'"'"'
+	.repo/local_manifests/bad.xml:6: extend-project names unknown project '"'"'project3'"'"'
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "lint manifest file outside workspace" '
+	cp work/.repo/local_manifests/bad.xml bad.xml &&
+	test_must_fail git-repo manifest --lint work/.repo/manifests/default.xml bad.xml >actual &&
+	cat >expect <<-EOF &&
+	bad.xml:3: unknown remote '"'"'unknown'"'"' for project '"'"'app'"'"'
+	bad.xml:4: dest '"'"'../VERSION'"'"' of copyfile in project '"'"'app'"'"' contains '"'"'..'"'"'
+	bad.xml:6: extend-project names unknown project '"'"'project3'"'"'
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "sync shows problems of manifest as warnings" '
+	(
+		cd work &&
+		cat >.repo/local_manifests/bad.xml <<-EOF &&
+		<?xml version="1.0" encoding="UTF-8"?>
+		<manifest>
+		  <remove-project name="project3" />
+		</manifest>
+		EOF
+		git-repo sync -n
+	) >out 2>&1 &&
+	grep "manifest: .repo/local_manifests/bad.xml:3: remove-project names unknown project .project3." out
+'
+
+test_done
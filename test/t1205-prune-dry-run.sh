@@ -0,0 +1,166 @@
+#!/bin/sh
+
+test_description="test 'git-repo prune' with cherry-picked and squashed changes"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	)
+'
+
+test_expect_success "create branches in main project" '
+	(
+		cd work &&
+		git-repo start jx/merged main &&
+		git-repo start jx/picked main &&
+		(
+			cd main &&
+			test_tick &&
+			echo picked >picked.txt &&
+			git add picked.txt &&
+			git commit -m "add picked.txt"
+		) &&
+		git-repo start jx/gerrit main &&
+		(
+			cd main &&
+			test_tick &&
+			echo gerrit >gerrit.txt &&
+			git add gerrit.txt &&
+			git commit -m "add gerrit.txt" \
+				-m "Change-Id: I0123456789abcdef0123456789abcdef01234567"
+		) &&
+		git-repo start jx/squashed main &&
+		(
+			cd main &&
+			test_tick &&
+			echo squash1 >squash1.txt &&
+			git add squash1.txt &&
+			git commit -m "add squash1.txt" &&
+			test_tick &&
+			echo squash2 >squash2.txt &&
+			git add squash2.txt &&
+			git commit -m "add squash2.txt"
+		) &&
+		git-repo start jx/pending main &&
+		(
+			cd main &&
+			test_tick &&
+			echo pending >pending.txt &&
+			git add pending.txt &&
+			git commit -m "add pending.txt"
+		)
+	)
+'
+
+test_expect_success "changes are merged into upstream in different ways" '
+	(
+		cd work/main &&
+		upstream=$(git rev-parse --symbolic-full-name jx/pending@{upstream}) &&
+		git checkout -q --detach $upstream &&
+		test_tick &&
+		git commit --allow-empty -m "upstream: hack" &&
+		test_tick &&
+		git cherry-pick jx/picked &&
+		test_tick &&
+		git cherry-pick jx/gerrit &&
+		echo "gerrit amended" >gerrit.txt &&
+		git add gerrit.txt &&
+		test_tick &&
+		git commit --amend -m "add gerrit.txt (amended)" \
+			-m "Change-Id: I0123456789abcdef0123456789abcdef01234567" &&
+		git merge --squash jx/squashed &&
+		test_tick &&
+		git commit -m "squash merge of jx/squashed" &&
+		git update-ref $upstream HEAD &&
+		git checkout -q jx/pending
+	)
+'
+
+test_expect_success "git-repo prune --dry-run shows rules" '
+	gerrit=$(git -C work/main rev-parse --short=7 jx/gerrit) &&
+	merged=$(git -C work/main rev-parse --short=7 jx/merged) &&
+	picked=$(git -C work/main rev-parse --short=7 jx/picked) &&
+	squashed=$(git -C work/main rev-parse --short=7 jx/squashed) &&
+	(
+		cd work &&
+		git-repo prune --dry-run main
+	) >actual 2>&1 &&
+	cat >expect <<-EOF &&
+	Would prune branches (dry run, rules which find them merged)
+	------------------------------------------------------------------------------
+	jx/gerrit                 | main (was $gerrit, change-id-matched)
+
+	jx/merged                 | main (was $merged, no-commits)
+
+	jx/picked                 | main (was $picked, cherry-picked)
+
+	jx/squashed               | main (was $squashed, squash-merged)
+
+	Pending branches (which have unmerged commits, leave it as is)
+	------------------------------------------------------------------------------
+	Project main/
+	* jx/pending ( 1 commit, Thu Apr 7 15:14:13 -0700 2005)
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git-repo prune --dry-run deletes nothing" '
+	(
+		cd work/main &&
+		git for-each-ref --format="%(refname:short)" refs/heads/
+	) >actual &&
+	cat >expect <<-EOF &&
+	jx/gerrit
+	jx/merged
+	jx/pending
+	jx/picked
+	jx/squashed
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git-repo prune deletes merged branches with backups" '
+	(
+		cd work &&
+		git-repo prune main >/dev/null &&
+		cd main &&
+		git for-each-ref --format="%(refname)" refs/heads/ refs/repo-backup/
+	) >actual &&
+	cat >expect <<-EOF &&
+	refs/heads/jx/pending
+	refs/repo-backup/jx/gerrit
+	refs/repo-backup/jx/merged
+	refs/repo-backup/jx/picked
+	refs/repo-backup/jx/squashed
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "current branch is not detached if fail to backup" '
+	(
+		cd work &&
+		git-repo start hack/topic main &&
+		git -C main update-ref refs/repo-backup/hack HEAD &&
+		git-repo prune main >/dev/null 2>&1 &&
+		git -C main symbolic-ref HEAD
+	) >actual &&
+	cat >expect <<-EOF &&
+	refs/heads/hack/topic
+	EOF
+	test_cmp expect actual
+'
+
+test_done
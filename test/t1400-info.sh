@@ -0,0 +1,95 @@
+#!/bin/sh
+
+test_description="test 'git-repo info'"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url -g all -b Maint &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	)
+'
+
+test_expect_success "git-repo info" '
+	(
+		cd work &&
+		git-repo start jx/topic main &&
+		(
+			cd main &&
+			echo hack >topic.txt &&
+			git add topic.txt &&
+			test_tick &&
+			git commit -q -m "topic: new file"
+		) &&
+		git-repo info main projects/app2 |
+			sed -e "s/platform-[^,]*/platform-*/"
+	) >actual &&
+	cat >expect<<-EOF &&
+	Manifest URL: $manifest_url
+	Manifest branch: Maint
+	Manifest file: default.xml
+	Groups: all,platform-*
+	Mirror: false
+	Archive: false
+	Depth: 0
+	Reference: 
+	Submodules: false
+	------------------------------------------------------------------------------
+	Project: main
+	Mount path: main
+	Remote: aone
+	Revision: Maint
+	Current branch: jx/topic
+	Upstream: refs/remotes/aone/Maint
+	Local commits: 1
+	  cd36c87 topic: new file
+	------------------------------------------------------------------------------
+	Project: project2
+	Mount path: projects/app2
+	Remote: aone
+	Revision: Maint
+	Current branch: (detached)
+	Upstream: 
+	------------------------------------------------------------------------------
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git-repo info --overview" '
+	(
+		cd work &&
+		git-repo start jx/topic2 projects/app2 &&
+		git-repo info --overview
+	) >actual &&
+	cat >expect<<-EOF &&
+	Projects Overview
+	------------------------------------------------------------------------------
+	project main/                                    branch jx/topic
+	  -cd36c87 topic: new file
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git-repo info --overview, no local work" '
+	(
+		cd work &&
+		git-repo info --overview projects/app2
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	NOTE: no local commits in projects
+	EOF
+	test_cmp expect actual
+'
+
+test_done
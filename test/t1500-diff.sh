@@ -0,0 +1,76 @@
+#!/bin/sh
+
+test_description="test 'git-repo diff'"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	)
+'
+
+test_expect_success "git-repo diff, nothing changed" '
+	(
+		cd work &&
+		git-repo diff
+	) >actual &&
+	test ! -s actual
+'
+
+test_expect_success "git-repo diff, with path prefix" '
+	(
+		cd work &&
+		echo hack >>main/VERSION &&
+		echo hack >projects/app1/module1/new.txt &&
+		git -C projects/app1/module1 add new.txt &&
+		git-repo diff -j 3
+	) >actual &&
+	cat >expect <<-EOF &&
+	diff --git a/main/VERSION b/main/VERSION
+	index 853153e..27ef0e0 100644
+	--- a/main/VERSION
+	+++ b/main/VERSION
+	@@ -1 +1,2 @@
+	 v2.0.0-dev
+	+hack
+	diff --git a/projects/app1/module1/new.txt b/projects/app1/module1/new.txt
+	new file mode 100644
+	index 0000000..fcf9276
+	--- /dev/null
+	+++ b/projects/app1/module1/new.txt
+	@@ -0,0 +1 @@
+	+hack
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git-repo diff, selected projects" '
+	(
+		cd work &&
+		git-repo diff projects/app1/module1
+	) >actual &&
+	cat >expect <<-EOF &&
+	diff --git a/projects/app1/module1/new.txt b/projects/app1/module1/new.txt
+	new file mode 100644
+	index 0000000..fcf9276
+	--- /dev/null
+	+++ b/projects/app1/module1/new.txt
+	@@ -0,0 +1 @@
+	+hack
+	EOF
+	test_cmp expect actual
+'
+
+test_done
@@ -0,0 +1,109 @@
+#!/bin/sh
+
+test_description="test 'git-repo diffmanifests'"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" &&
+		git-repo manifest -r -o ../new.xml
+	) &&
+	old=$(git -C work/main rev-parse HEAD~2) &&
+	sed \
+		-e "/name=\"project2\"/d" \
+		-e "/name=\"main\"/s/revision=\"[^\"]*\"/revision=\"$old\"/" \
+		-e "s#</manifest>#  <project name=\"project3\" path=\"projects/app3\" revision=\"master\"></project>\n</manifest>#" \
+		<new.xml >old.xml
+'
+
+test_expect_success "git-repo diffmanifests <manifest1> <manifest2>" '
+	(
+		cd work &&
+		git-repo diffmanifests ../old.xml ../new.xml
+	) >actual &&
+	cat >expect <<-EOF &&
+	added projects :
+	  projects/app2 at revision 98dc74a3fac99714338633327dbab62b5189375b
+	removed projects :
+	  projects/app3 at revision master
+	changed projects :
+	  main changed from cd58644e7e854cc1798c47f58febdd19b64ff97e to 4d13a6c1a2c17fcb3b109f2b1586d1485463e636
+	    + 4d13a6c Version 2.0.0-dev
+	    + a5e4ae3 Version 1.0.0
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git-repo diffmanifests --raw <manifest1> <manifest2>" '
+	(
+		cd work &&
+		git-repo diffmanifests --raw ../new.xml ../old.xml
+	) >actual &&
+	cat >expect <<-EOF &&
+	A projects/app3 master
+	R projects/app2 98dc74a3fac99714338633327dbab62b5189375b
+	C main 4d13a6c1a2c17fcb3b109f2b1586d1485463e636 cd58644e7e854cc1798c47f58febdd19b64ff97e
+	  - 4d13a6c Version 2.0.0-dev
+	  - a5e4ae3 Version 1.0.0
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git-repo diffmanifests with current manifest" '
+	(
+		cd work &&
+		git-repo diffmanifests ../old.xml
+	) >actual &&
+	cat >expect <<-EOF &&
+	added projects :
+	  projects/app2 at revision master
+	removed projects :
+	  projects/app3 at revision master
+	changed projects :
+	  drivers/driver-1 changed from faa6f5cedc80d51cb57505376ef99878b66cd020 to Maint
+	  main changed from cd58644e7e854cc1798c47f58febdd19b64ff97e to master
+	    + 4d13a6c Version 2.0.0-dev
+	    + a5e4ae3 Version 1.0.0
+	  projects/app1 changed from 2fdfd9b9ff3bb556a74363bd0dacec0d29a0cc2a to master
+	  projects/app1/module1 changed from 8fc882db0d6eaa24013f4ee3772e6765eb920d21 to refs/tags/v1.0.0
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "local manifests are not loaded again for saved manifest" '
+	(
+		cd work &&
+		mkdir -p .repo/local_manifests &&
+		cat >.repo/local_manifests/local.xml <<-EOF &&
+		<?xml version="1.0" encoding="UTF-8"?>
+		<manifest>
+		  <project name="project3" path="projects/app3" revision="master" />
+		</manifest>
+		EOF
+		git-repo manifest -o ../local.xml &&
+		git-repo diffmanifests ../local.xml &&
+		rm -r .repo/local_manifests
+	) >actual &&
+	test_must_be_empty actual
+'
+
+test_expect_success "git-repo diffmanifests with bad manifest" '
+	(
+		cd work &&
+		test_must_fail git-repo diffmanifests not-exist.xml
+	)
+'
+
+test_done
@@ -0,0 +1,116 @@
+package workspace
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/file"
+	"github.com/alibaba/git-repo-go/manifest"
+)
+
+// changeSetsDir saves change sets uploaded from this workspace in the
+// admin dir, one manifest snippet for each topic.
+const changeSetsDir = "change-sets"
+
+var reBadTopic = regexp.MustCompile(`[\s%,~^:?*\[\\]`)
+
+// NewTopic generates topic for a change set. Use name of local branch if
+// all branches have the same name, and append current time.
+func NewTopic(branches []string) string {
+	prefix := "change-set"
+	for i, b := range branches {
+		b = strings.TrimPrefix(b, config.RefsHeads)
+		if i == 0 {
+			prefix = b
+		} else if b != prefix {
+			prefix = "change-set"
+			break
+		}
+	}
+	return prefix + "-" + time.Now().Format("20060102150405")
+}
+
+// CheckTopic checks whether topic is a valid name for a change set, which
+// is used in refspec of git push.
+func CheckTopic(topic string) error {
+	if topic == "" {
+		return fmt.Errorf("empty topic")
+	}
+	if reBadTopic.MatchString(topic) ||
+		strings.HasPrefix(topic, "/") ||
+		strings.HasSuffix(topic, "/") ||
+		strings.Contains(topic, "..") ||
+		strings.Contains(topic, "//") {
+		return fmt.Errorf("invalid topic '%s'", topic)
+	}
+	return nil
+}
+
+func (v *RepoWorkSpace) changeSetFile(topic string) string {
+	return filepath.Join(v.RootDir,
+		config.DotRepo,
+		changeSetsDir,
+		url.PathEscape(topic)+".xml")
+}
+
+// SaveChangeSet saves revisions of projects uploaded with the same topic
+// as a manifest snippet, and returns the file saved.
+func (v *RepoWorkSpace) SaveChangeSet(topic string, projects []manifest.Project) (string, error) {
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].Path < projects[j].Path
+	})
+	data, err := manifest.Marshal(&manifest.Manifest{Projects: projects})
+	if err != nil {
+		return "", err
+	}
+	data = append([]byte(`<?xml version="1.0" encoding="UTF-8"?>`+"\n"), data...)
+	data = append(data, '\n')
+
+	filename := v.changeSetFile(topic)
+	err = os.MkdirAll(filepath.Dir(filename), 0755)
+	if err != nil {
+		return "", err
+	}
+	lockFile := filename + ".lock"
+	lockf, err := file.New(lockFile).OpenCreateRewriteExcl()
+	if err != nil {
+		return "", fmt.Errorf("fail to create lockfile '%s': %s", lockFile, err)
+	}
+	defer lockf.Close()
+	_, err = lockf.Write(data)
+	if err != nil {
+		return "", fmt.Errorf("fail to save lockfile '%s': %s", lockFile, err)
+	}
+	lockf.Close()
+
+	err = os.Rename(lockFile, filename)
+	if err != nil {
+		return "", fmt.Errorf("fail to rename lockfile to '%s': %s", filename, err)
+	}
+	return filename, nil
+}
+
+// LoadChangeSet reads change set from file, which is saved by upload in
+// admin dir, or shared by others.
+func LoadChangeSet(filename string) (*manifest.Manifest, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read change set: %s", err)
+	}
+	ms, err := manifest.Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse change set '%s': %s", filename, err)
+	}
+	if len(ms.Projects) == 0 {
+		return nil, fmt.Errorf("no project in change set '%s'", filename)
+	}
+	return ms, nil
+}
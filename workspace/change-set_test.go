@@ -0,0 +1,74 @@
+package workspace
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopic(t *testing.T) {
+	assert := assert.New(t)
+
+	topic := NewTopic([]string{"refs/heads/jx/topic", "jx/topic"})
+	assert.True(strings.HasPrefix(topic, "jx/topic-"), topic)
+	assert.Nil(CheckTopic(topic))
+	topic = NewTopic([]string{"jx/topic1", "jx/topic2"})
+	assert.True(strings.HasPrefix(topic, "change-set-"), topic)
+
+	for _, topic := range []string{
+		"feature",
+		"jx/feature-1",
+		"feature_2.0",
+	} {
+		assert.Nil(CheckTopic(topic), topic)
+	}
+	for _, topic := range []string{
+		"",
+		"bad topic",
+		"bad,topic",
+		"bad%topic",
+		"bad:topic",
+		"/bad",
+		"bad/",
+		"bad//topic",
+		"bad..topic",
+	} {
+		assert.NotNil(CheckTopic(topic), topic)
+	}
+}
+
+func TestChangeSet(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	ws := RepoWorkSpace{RootDir: tmpdir}
+	projects := []manifest.Project{
+		{Name: "project1", Path: "projects/app1", Revision: "2222222222222222222222222222222222222222"},
+		{Name: "main", Path: "main", Revision: "1111111111111111111111111111111111111111"},
+	}
+	filename, err := ws.SaveChangeSet("jx/feature", projects)
+	assert.Nil(err)
+	assert.Equal(filepath.Join(tmpdir, config.DotRepo, changeSetsDir, "jx%2Ffeature.xml"), filename)
+
+	ms, err := LoadChangeSet(filename)
+	if assert.Nil(err) {
+		assert.Equal(2, len(ms.Projects))
+		assert.Equal("main", ms.Projects[0].Path)
+		assert.Equal("1111111111111111111111111111111111111111", ms.Projects[0].Revision)
+		assert.Equal("projects/app1", ms.Projects[1].Path)
+	}
+
+	_, err = LoadChangeSet("jx/feature")
+	assert.Equal("cannot read change set: open jx/feature: no such file or directory", err.Error())
+}
@@ -0,0 +1,201 @@
+package workspace
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/file"
+	"github.com/alibaba/git-repo-go/project"
+	log "github.com/jiangxin/multi-log"
+)
+
+const (
+	// copyLinkFilesList records files created by copyfile and linkfile
+	// elements of manifest in the admin dir.
+	copyLinkFilesList = "copy-link-files.list"
+
+	copyFileType = "copyfile"
+	linkFileType = "linkfile"
+)
+
+// copyLinkFile is a file created by copyfile or linkfile element.
+type copyLinkFile struct {
+	Type     string
+	Dest     string
+	Checksum string
+}
+
+// String returns a line in copyLinkFilesList.
+func (v copyLinkFile) String() string {
+	return v.Type + "\t" + v.Checksum + "\t" + v.Dest
+}
+
+// readCopyLinkFiles reads files recorded in list file.
+func readCopyLinkFiles(listFile string) []copyLinkFile {
+	result := []copyLinkFile{}
+
+	f, err := os.Open(listFile)
+	if err != nil {
+		return result
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			log.Warnf("bad line in '%s': %s", listFile, line)
+			continue
+		}
+		result = append(result, copyLinkFile{
+			Type:     fields[0],
+			Checksum: fields[1],
+			Dest:     fields[2],
+		})
+	}
+	return result
+}
+
+// writeCopyLinkFiles saves files to list file.
+func writeCopyLinkFiles(listFile string, files []copyLinkFile) error {
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Dest < files[j].Dest
+	})
+
+	lockFile := listFile + ".lock"
+	lockf, err := file.New(lockFile).OpenCreateRewriteExcl()
+	if err != nil {
+		return fmt.Errorf("fail to create lockfile '%s': %s", lockFile, err)
+	}
+	defer lockf.Close()
+	for _, f := range files {
+		_, err = lockf.WriteString(f.String() + "\n")
+		if err != nil {
+			return fmt.Errorf("fail to save lockfile '%s': %s", lockFile, err)
+		}
+	}
+	lockf.Close()
+
+	err = os.Rename(lockFile, listFile)
+	if err != nil {
+		return fmt.Errorf("fail to rename lockfile to '%s': %s", listFile, err)
+	}
+	return nil
+}
+
+// UpdateCopyLinkFiles records files created by copyfile and linkfile
+// elements in `copy-link-files.list`, and removes files which are created
+// by previous syncs but no longer defined in manifest. Stale files which
+// are modified by user are not removed, and are returned.
+func (v *RepoWorkSpace) UpdateCopyLinkFiles(submodulesOK bool) ([]string, error) {
+	var (
+		remains  = []string{}
+		errMsgs  = []string{}
+		newFiles = []copyLinkFile{}
+		defined  = make(map[string]bool)
+	)
+
+	allProjects, err := v.GetProjects(&GetProjectsOptions{
+		MissingOK:    true,
+		SubmodulesOK: submodulesOK,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	listFile := filepath.Join(v.RootDir, config.DotRepo, copyLinkFilesList)
+	oldFiles := readCopyLinkFiles(listFile)
+	oldChecksums := make(map[string]string)
+	for _, f := range oldFiles {
+		oldChecksums[f.Dest] = f.Checksum
+	}
+
+	// Checksum is only updated when file is written by sync, otherwise
+	// the previous one is kept, so that user modification is not taken
+	// as the baseline.
+	addFile := func(typ, dest string) {
+		// Dest of linkfile is a symlink created by ourselves.
+		destAbs, err := project.ResolvePath(v.RootDir, dest, typ == linkFileType)
+		if err != nil {
+			return
+		}
+		rel, err := filepath.Rel(v.RootDir, destAbs)
+		if err != nil {
+			return
+		}
+		rel = filepath.ToSlash(rel)
+		if defined[rel] {
+			return
+		}
+		defined[rel] = true
+		checksum := project.WrittenFileChecksum(destAbs)
+		if checksum == "" {
+			checksum = oldChecksums[rel]
+		}
+		if checksum != "" {
+			newFiles = append(newFiles, copyLinkFile{
+				Type:     typ,
+				Dest:     rel,
+				Checksum: checksum,
+			})
+		}
+	}
+	for _, p := range allProjects {
+		for _, f := range p.CopyFiles {
+			addFile(copyFileType, f.Dest)
+		}
+		for _, f := range p.LinkFiles {
+			addFile(linkFileType, f.Dest)
+		}
+	}
+
+	for _, f := range oldFiles {
+		if defined[f.Dest] {
+			continue
+		}
+		destAbs, err := project.ResolvePath(v.RootDir, f.Dest, true)
+		if err != nil {
+			errMsgs = append(errMsgs,
+				fmt.Sprintf("bad %s '%s' in '%s', ignored", f.Type, f.Dest, listFile))
+			continue
+		}
+		checksum := project.FileChecksum(destAbs)
+		if checksum == "" {
+			// Already removed.
+			continue
+		}
+		if checksum != f.Checksum {
+			// Modified by user, keep it in the list.
+			remains = append(remains, f.Dest)
+			newFiles = append(newFiles, f)
+			continue
+		}
+		log.Debugf("removing stale %s '%s'", f.Type, f.Dest)
+		err = os.Remove(destAbs)
+		if err != nil {
+			remains = append(remains, f.Dest)
+			newFiles = append(newFiles, f)
+			errMsgs = append(errMsgs, fmt.Sprintf("fail to remove '%s': %s", f.Dest, err))
+			continue
+		}
+		v.removeEmptyDirs(filepath.Dir(destAbs))
+	}
+
+	err = writeCopyLinkFiles(listFile, newFiles)
+	if err != nil {
+		errMsgs = append(errMsgs, err.Error())
+	}
+	if len(errMsgs) > 0 {
+		return remains, fmt.Errorf(strings.Join(errMsgs, "\n"))
+	}
+	return remains, nil
+}
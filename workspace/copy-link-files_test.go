@@ -0,0 +1,46 @@
+package workspace
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyLinkFilesList(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	regular := filepath.Join(tmpdir, "VERSION")
+	link := filepath.Join(tmpdir, "Makefile")
+	assert.Nil(ioutil.WriteFile(regular, []byte("1.0\n"), 0644))
+	assert.Nil(os.Symlink("main/Makefile", link))
+
+	assert.Equal("sha1:61652cd1568dcf2614df833eba241755eee34e89", project.FileChecksum(regular))
+	assert.Equal("symlink:main/Makefile", project.FileChecksum(link))
+	assert.Equal("", project.FileChecksum(filepath.Join(tmpdir, "not-exist")))
+	assert.Equal("", project.FileChecksum(tmpdir))
+
+	listFile := filepath.Join(tmpdir, copyLinkFilesList)
+	assert.Equal([]copyLinkFile{}, readCopyLinkFiles(listFile))
+
+	files := []copyLinkFile{
+		{Type: copyFileType, Dest: "dir with space/VERSION", Checksum: project.FileChecksum(regular)},
+		{Type: linkFileType, Dest: "Makefile", Checksum: project.FileChecksum(link)},
+	}
+	assert.Nil(writeCopyLinkFiles(listFile, files))
+	data, err := ioutil.ReadFile(listFile)
+	assert.Nil(err)
+	assert.Equal("linkfile\tsymlink:main/Makefile\tMakefile\n"+
+		"copyfile\tsha1:61652cd1568dcf2614df833eba241755eee34e89\tdir with space/VERSION\n",
+		string(data))
+	assert.Equal(files, readCopyLinkFiles(listFile))
+}
@@ -0,0 +1,147 @@
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/file"
+	log "github.com/jiangxin/multi-log"
+)
+
+const (
+	// fetchTimesFile saves fetch time of projects in the admin dir.
+	fetchTimesFile = ".repo_fetchtimes.json"
+
+	// Fetch time of unknown project, so new projects are fetched first.
+	defaultFetchTime = 24 * time.Hour
+
+	// Weight of the latest fetch time when calculating average.
+	fetchTimeAlpha = 0.5
+
+	// Lock file is only held while writing, so lock file older than this
+	// is left by a crashed process, and can be removed.
+	staleLockTimeout = time.Minute
+)
+
+// FetchTimes holds fetch time (in seconds) of projects. The time saved is
+// an exponential moving average of previous fetches.
+type FetchTimes struct {
+	file  string
+	times map[string]float64
+}
+
+// Get returns fetch time of project.
+func (v *FetchTimes) Get(name string) time.Duration {
+	if t, ok := v.times[name]; ok {
+		return time.Duration(t * float64(time.Second))
+	}
+	return defaultFetchTime
+}
+
+// Set updates fetch time of project.
+func (v *FetchTimes) Set(name string, d time.Duration) {
+	t := d.Seconds()
+	if old, ok := v.times[name]; ok {
+		t = fetchTimeAlpha*t + (1-fetchTimeAlpha)*old
+	}
+	v.times[name] = t
+}
+
+// Prune removes fetch times of projects not in names, such as projects
+// removed from manifest.
+func (v *FetchTimes) Prune(names []string) {
+	known := make(map[string]bool)
+	for _, name := range names {
+		known[name] = true
+	}
+	for name := range v.times {
+		if !known[name] {
+			delete(v.times, name)
+		}
+	}
+}
+
+// Sort sorts names of projects, the slowest first.
+func (v *FetchTimes) Sort(names []string) {
+	sort.SliceStable(names, func(i, j int) bool {
+		return v.Get(names[i]) > v.Get(names[j])
+	})
+}
+
+// Save writes fetch times of projects to file.
+func (v *FetchTimes) Save() error {
+	data, err := json.MarshalIndent(v.times, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	lockFile := v.file + ".lock"
+	lockf, err := file.New(lockFile).OpenCreateRewriteExcl()
+	if err != nil && removeStaleLock(lockFile) {
+		lockf, err = file.New(lockFile).OpenCreateRewriteExcl()
+	}
+	if err != nil {
+		return fmt.Errorf("fail to create lockfile '%s': %s", lockFile, err)
+	}
+	defer lockf.Close()
+	_, err = lockf.Write(data)
+	if err != nil {
+		return fmt.Errorf("fail to save lockfile '%s': %s", lockFile, err)
+	}
+	lockf.Close()
+
+	err = os.Rename(lockFile, v.file)
+	if err != nil {
+		return fmt.Errorf("fail to rename lockfile to '%s': %s", v.file, err)
+	}
+	return nil
+}
+
+// removeStaleLock removes lock file left by a crashed process, and
+// returns true if removed.
+func removeStaleLock(lockFile string) bool {
+	fi, err := os.Stat(lockFile)
+	if err != nil || time.Since(fi.ModTime()) < staleLockTimeout {
+		return false
+	}
+	log.Warnf("remove stale lockfile '%s'", lockFile)
+	return os.Remove(lockFile) == nil
+}
+
+func newFetchTimes(file string) *FetchTimes {
+	v := FetchTimes{
+		file:  file,
+		times: make(map[string]float64),
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return &v
+	}
+	err = json.Unmarshal(data, &v.times)
+	if err != nil {
+		log.Warnf("ignore bad fetch times file '%s': %s", file, err)
+		v.times = make(map[string]float64)
+	}
+	return &v
+}
+
+// FetchTimes loads fetch time of projects from file in admin dir, and
+// drops projects which are not in manifest any more.
+func (v *RepoWorkSpace) FetchTimes() *FetchTimes {
+	fetchTimes := newFetchTimes(filepath.Join(v.RootDir, config.DotRepo, fetchTimesFile))
+	if v.Manifest != nil {
+		names := []string{}
+		for _, p := range v.Manifest.AllProjects() {
+			names = append(names, p.Name)
+		}
+		fetchTimes.Prune(names)
+	}
+	return fetchTimes
+}
@@ -0,0 +1,63 @@
+package workspace
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchTimes(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	filename := filepath.Join(tmpdir, fetchTimesFile)
+	fetchTimes := newFetchTimes(filename)
+	assert.Equal(defaultFetchTime, fetchTimes.Get("foo"))
+
+	fetchTimes.Set("foo", 10*time.Second)
+	fetchTimes.Set("bar", 30*time.Second)
+	fetchTimes.Set("baz", 20*time.Second)
+	assert.Equal(10*time.Second, fetchTimes.Get("foo"))
+	fetchTimes.Set("foo", 50*time.Second)
+	assert.Equal(30*time.Second, fetchTimes.Get("foo"))
+
+	names := []string{"foo", "bar", "new", "baz"}
+	fetchTimes.Sort(names)
+	assert.Equal([]string{"new", "foo", "bar", "baz"}, names)
+
+	assert.Nil(fetchTimes.Save())
+	fetchTimes = newFetchTimes(filename)
+	assert.Equal(30*time.Second, fetchTimes.Get("foo"))
+	assert.Equal(30*time.Second, fetchTimes.Get("bar"))
+	assert.Equal(20*time.Second, fetchTimes.Get("baz"))
+
+	fetchTimes.Prune([]string{"foo", "baz"})
+	assert.Equal(30*time.Second, fetchTimes.Get("foo"))
+	assert.Equal(defaultFetchTime, fetchTimes.Get("bar"))
+	assert.Equal(20*time.Second, fetchTimes.Get("baz"))
+
+	// Fresh lock blocks saving, and stale lock is removed.
+	lockFile := filename + ".lock"
+	assert.Nil(ioutil.WriteFile(lockFile, nil, 0644))
+	assert.NotNil(fetchTimes.Save())
+	old := time.Now().Add(-2 * staleLockTimeout)
+	assert.Nil(os.Chtimes(lockFile, old, old))
+	assert.Nil(fetchTimes.Save())
+	fetchTimes = newFetchTimes(filename)
+	assert.Equal(defaultFetchTime, fetchTimes.Get("bar"))
+	assert.Equal(20*time.Second, fetchTimes.Get("baz"))
+
+	// Ignore bad file
+	assert.Nil(ioutil.WriteFile(filename, []byte("bad"), 0644))
+	fetchTimes = newFetchTimes(filename)
+	assert.Equal(defaultFetchTime, fetchTimes.Get("foo"))
+}
@@ -0,0 +1,272 @@
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/file"
+	"github.com/alibaba/git-repo-go/project"
+	log "github.com/jiangxin/multi-log"
+)
+
+const (
+	// journalDir saves operations which change refs of projects in the
+	// admin dir, one file for each operation.
+	journalDir = "journal"
+
+	// Only keep the latest operations in journal.
+	maxJournalOperations = 50
+)
+
+// JournalProject holds refs of a project changed by an operation.
+type JournalProject struct {
+	Path string              `json:"path"`
+	Refs []project.RefChange `json:"refs"`
+}
+
+// Operation is a command saved in journal, such as `sync -d`, `prune`,
+// and `abandon`, with all refs it moved or deleted.
+type Operation struct {
+	ID       int              `json:"id"`
+	Command  string           `json:"command"`
+	Time     time.Time        `json:"time"`
+	Undone   bool             `json:"undone,omitempty"`
+	Projects []JournalProject `json:"projects"`
+}
+
+// Journal records refs of projects before an operation, and saves refs
+// changed by the operation when finished.
+type Journal struct {
+	ws        *RepoWorkSpace
+	command   string
+	projects  []*project.Project
+	snapshots map[string]map[string]string
+}
+
+func (v *RepoWorkSpace) journalDir() string {
+	return filepath.Join(v.RootDir, config.DotRepo, journalDir)
+}
+
+func (v *RepoWorkSpace) operationFile(id int) string {
+	return filepath.Join(v.journalDir(), strconv.Itoa(id)+".json")
+}
+
+// BeginOperation takes snapshot of refs of projects before running
+// command. Projects which are not checked out yet are ignored, so the
+// first checkout of a project cannot be undone.
+func (v *RepoWorkSpace) BeginOperation(command string, projects []*project.Project) *Journal {
+	j := Journal{
+		ws:        v,
+		command:   command,
+		snapshots: make(map[string]map[string]string),
+	}
+	for _, p := range projects {
+		snapshot := p.RefSnapshot()
+		if len(snapshot) == 0 {
+			continue
+		}
+		j.projects = append(j.projects, p)
+		j.snapshots[p.Path] = snapshot
+	}
+	return &j
+}
+
+// Finish compares refs of projects with snapshot, and saves changed refs
+// in journal. Returns nil if no ref is changed.
+func (v *Journal) Finish() (*Operation, error) {
+	op := Operation{
+		Command:  v.command,
+		Time:     time.Now(),
+		Projects: []JournalProject{},
+	}
+	for _, p := range v.projects {
+		changes := project.DiffRefSnapshot(v.snapshots[p.Path], p.RefSnapshot())
+		if len(changes) == 0 {
+			continue
+		}
+		op.Projects = append(op.Projects, JournalProject{
+			Path: p.Path,
+			Refs: changes,
+		})
+	}
+	if len(op.Projects) == 0 {
+		return nil, nil
+	}
+	sort.Slice(op.Projects, func(i, j int) bool {
+		return op.Projects[i].Path < op.Projects[j].Path
+	})
+
+	ids := v.ws.operationIDs()
+	if len(ids) > 0 {
+		op.ID = ids[len(ids)-1] + 1
+	} else {
+		op.ID = 1
+	}
+	err := v.ws.saveOperation(&op)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove old operations.
+	for i := 0; i < len(ids)+1-maxJournalOperations; i++ {
+		os.Remove(v.ws.operationFile(ids[i]))
+	}
+	return &op, nil
+}
+
+// operationIDs returns IDs of operations in journal in ascending order.
+func (v *RepoWorkSpace) operationIDs() []int {
+	ids := []int{}
+	files, err := ioutil.ReadDir(v.journalDir())
+	if err != nil {
+		return ids
+	}
+	for _, f := range files {
+		name := f.Name()
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSuffix(name, ".json"))
+		if err != nil || id <= 0 {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func (v *RepoWorkSpace) saveOperation(op *Operation) error {
+	data, err := json.MarshalIndent(op, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(v.journalDir(), 0755)
+	if err != nil {
+		return err
+	}
+
+	filename := v.operationFile(op.ID)
+	lockFile := filename + ".lock"
+	lockf, err := file.New(lockFile).OpenCreateRewriteExcl()
+	if err != nil {
+		return fmt.Errorf("fail to create lockfile '%s': %s", lockFile, err)
+	}
+	defer lockf.Close()
+	_, err = lockf.Write(data)
+	if err != nil {
+		return fmt.Errorf("fail to save lockfile '%s': %s", lockFile, err)
+	}
+	lockf.Close()
+
+	err = os.Rename(lockFile, filename)
+	if err != nil {
+		return fmt.Errorf("fail to rename lockfile to '%s': %s", filename, err)
+	}
+	return nil
+}
+
+// LoadOperation reads operation from journal.
+func (v *RepoWorkSpace) LoadOperation(id int) (*Operation, error) {
+	op := Operation{}
+	data, err := ioutil.ReadFile(v.operationFile(id))
+	if err != nil {
+		return nil, fmt.Errorf("operation %d is not found in journal", id)
+	}
+	err = json.Unmarshal(data, &op)
+	if err != nil {
+		return nil, fmt.Errorf("bad operation %d in journal: %s", id, err)
+	}
+	return &op, nil
+}
+
+// Operations returns operations in journal, the latest first.
+func (v *RepoWorkSpace) Operations() []*Operation {
+	result := []*Operation{}
+	ids := v.operationIDs()
+	for i := len(ids) - 1; i >= 0; i-- {
+		op, err := v.LoadOperation(ids[i])
+		if err != nil {
+			log.Warn(err)
+			continue
+		}
+		result = append(result, op)
+	}
+	return result
+}
+
+// UndoOperation restores refs of projects changed by operation. If id is
+// 0, undo the latest operation which is not undone yet.
+func (v *RepoWorkSpace) UndoOperation(id int, force bool) (*Operation, error) {
+	var (
+		op      *Operation
+		err     error
+		errMsgs = []string{}
+	)
+
+	if id == 0 {
+		for _, o := range v.Operations() {
+			if !o.Undone {
+				op = o
+				break
+			}
+		}
+		if op == nil {
+			return nil, fmt.Errorf("nothing to undo")
+		}
+	} else {
+		op, err = v.LoadOperation(id)
+		if err != nil {
+			return nil, err
+		}
+		if op.Undone && !force {
+			return nil, fmt.Errorf("operation %d is already undone", id)
+		}
+	}
+
+	allProjects, err := v.GetProjects(&GetProjectsOptions{
+		Groups:    project.GroupAll,
+		MissingOK: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	projectByPath := make(map[string]*project.Project)
+	for _, p := range allProjects {
+		projectByPath[p.Path] = p
+	}
+
+	for _, jp := range op.Projects {
+		p, ok := projectByPath[jp.Path]
+		if !ok || !p.Exists() {
+			errMsgs = append(errMsgs,
+				fmt.Sprintf("project %s> not found, cannot restore", jp.Path))
+			continue
+		}
+		err = p.RestoreRefs(jp.Refs, force)
+		if err != nil {
+			for _, line := range strings.Split(err.Error(), "\n") {
+				errMsgs = append(errMsgs, fmt.Sprintf("project %s> %s", jp.Path, line))
+			}
+		}
+	}
+
+	// Refs already restored are skipped if undo is run again.
+	if len(errMsgs) > 0 {
+		return op, fmt.Errorf("%s", strings.Join(errMsgs, "\n"))
+	}
+	op.Undone = true
+	err = v.saveOperation(op)
+	if err != nil {
+		return op, err
+	}
+	return op, nil
+}
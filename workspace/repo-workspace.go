@@ -246,6 +246,18 @@ type GetProjectsOptions struct {
 	SubmodulesOK bool
 }
 
+// EffectiveGroups returns groups to match projects, and groups in manifest
+// settings are used if groups is empty.
+func (v RepoWorkSpace) EffectiveGroups(groups string) string {
+	if groups == "" {
+		groups = v.ManifestProject.Config().Get(config.CfgManifestGroups)
+		if groups == "" {
+			groups = "default,platform-" + runtime.GOOS
+		}
+	}
+	return groups
+}
+
 // GetProjects returns all matching projects.
 func (v RepoWorkSpace) GetProjects(o *GetProjectsOptions, args ...string) ([]*project.Project, error) {
 	var (
@@ -262,12 +274,10 @@ func (v RepoWorkSpace) GetProjects(o *GetProjectsOptions, args ...string) ([]*pr
 	if o == nil {
 		o = &GetProjectsOptions{}
 	}
-	groups = o.Groups
-	if groups == "" {
-		groups = v.ManifestProject.Config().Get(config.CfgManifestGroups)
-		if groups == "" {
-			groups = "default,platform-" + runtime.GOOS
-		}
+	groups = v.EffectiveGroups(o.Groups)
+	groupMatcher, err := project.ParseGroups(groups)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(args) == 0 {
@@ -311,7 +321,7 @@ func (v RepoWorkSpace) GetProjects(o *GetProjectsOptions, args ...string) ([]*pr
 			}
 			continue
 		}
-		if p.MatchGroups(groups) {
+		if groupMatcher.Match(p.Groups) {
 			result = append(result, p)
 		} else if len(args) > 0 {
 			return nil, errors.ProjectNotBelongToGroupsError(p.Name, groups)